@@ -0,0 +1,297 @@
+package hotsheet
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// changesSheetName is the tab that lists what changed since the previous hotsheet.
+const changesSheetName = "Changes"
+
+// Change labels written to the Changes sheet. Sheet moves and MTO band crossings are labelled
+// with the sheets and bands involved.
+const (
+	changeAdded   = "Added"
+	changeRemoved = "Removed"
+	changeStatus  = "Status changed"
+	changeOnHand  = "QTY on Hand changed"
+	changeOnPO    = "QTY on PO changed"
+)
+
+// changesHeaders are the column titles of the Changes sheet. A consolidated workbook puts a
+// Product Line column first.
+var changesHeaders = []string{
+	"Item Code", "Description", "Sheet", "Change", "Previous Status", "Status",
+	"Previous QTY on Hand", "QTY on Hand", "QTY on Hand Change",
+	"Previous Total QTY on PO", "Total QTY on PO", "QTY on PO Change",
+	"Previous MTO YTD", "MTO YTD", "Previous MTO PY", "MTO PY",
+}
+
+// changesColumnWidths keeps the Changes sheet readable without manual resizing.
+var changesColumnWidths = []float64{20, 35, 12, 40, 16, 15, 20, 14, 18, 22, 16, 16, 16, 12, 16, 12}
+
+// changeKind orders the Changes sheet: added items first, then removed, then changed.
+type changeKind int
+
+const (
+	changeKindAdded changeKind = iota
+	changeKindRemoved
+	changeKindChanged
+)
+
+// hotsheetChange is one item's row on the Changes sheet.
+type hotsheetChange struct {
+	productLine string
+	kind        changeKind
+	labels      []string
+	// previous is nil for an added item and current for a removed one.
+	previous *hotsheetItem
+	current  *hotsheetItem
+	// ytdFill and pyFill shade an MTO column that crossed into another band.
+	ytdFill string
+	pyFill  string
+}
+
+// hotsheetChanges is the content of a Changes sheet.
+type hotsheetChanges struct {
+	// comparedWith is the earlier hotsheet the changes are measured from.
+	comparedWith string
+	rows         []hotsheetChange
+}
+
+// currentHotsheetItem returns the figures of e's row on the standard sheets of a hotsheet
+// generated now.
+func currentHotsheetItem(e *inventoryEntry, monthsThrough float64, settings LineSettings) hotsheetItem {
+	season := entrySeason(e)
+	figures := computeStandardRowFigures(e, monthsThrough, settings.SeasonMonths.forSeason(season))
+	return hotsheetItem{
+		SKU:         e.SKU,
+		Description: e.Description,
+		Sheet:       season,
+		Status:      strings.TrimSpace(e.Status),
+		OnHand:      e.OnHand,
+		OnPO:        e.OnPO,
+		MTOYTD:      figures.mtoYTD,
+		MTOPY:       figures.mtoPY,
+		HasMTOYTD:   true,
+		HasMTOPY:    true,
+	}
+}
+
+// compareWithPrevious lists the items of a product line that were added or removed since the
+// previous hotsheet, or whose sheet, status, QTY on Hand, or Total QTY on PO changed, or whose
+// MTO crossed into another red, yellow, or green band. Both runs' MTO values are banded with the
+// current settings, so a threshold change alone does not count as a crossing. A nil previous
+// lists every item as added.
+func compareWithPrevious(productLine string, entries []*inventoryEntry, previous map[string]hotsheetItem, settings LineSettings, now time.Time) []hotsheetChange {
+	monthsThrough := currentMonthsThrough(now)
+	var changes []hotsheetChange
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		current := currentHotsheetItem(e, monthsThrough, settings)
+		seen[current.SKU] = true
+		change := hotsheetChange{productLine: productLine, current: &current}
+		prev, ok := previous[current.SKU]
+		if !ok {
+			change.kind = changeKindAdded
+			change.labels = []string{changeAdded}
+			changes = append(changes, change)
+			continue
+		}
+
+		change.kind = changeKindChanged
+		change.previous = &prev
+		if prev.Sheet != current.Sheet {
+			change.labels = append(change.labels, fmt.Sprintf("Moved from %s", prev.Sheet))
+		}
+		if !strings.EqualFold(prev.Status, current.Status) {
+			change.labels = append(change.labels, changeStatus)
+		}
+		if prev.OnHand != current.OnHand {
+			change.labels = append(change.labels, changeOnHand)
+		}
+		if prev.OnPO != current.OnPO {
+			change.labels = append(change.labels, changeOnPO)
+		}
+		if prev.HasMTOYTD {
+			if from, to := settings.mtoBand(prev.MTOYTD), settings.mtoBand(current.MTOYTD); from != to {
+				change.labels = append(change.labels, fmt.Sprintf("MTO YTD %s to %s", from, to))
+				change.ytdFill = settings.mtoFill(settings.YTDFills, current.MTOYTD)
+			}
+		}
+		if prev.HasMTOPY {
+			if from, to := settings.mtoBand(prev.MTOPY), settings.mtoBand(current.MTOPY); from != to {
+				change.labels = append(change.labels, fmt.Sprintf("MTO PY %s to %s", from, to))
+				change.pyFill = settings.mtoFill(settings.PYFills, current.MTOPY)
+			}
+		}
+		if len(change.labels) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	for sku, prev := range previous {
+		if !seen[sku] {
+			changes = append(changes, hotsheetChange{productLine: productLine, kind: changeKindRemoved, labels: []string{changeRemoved}, previous: &prev})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].kind != changes[j].kind {
+			return changes[i].kind < changes[j].kind
+		}
+		return changes[i].item().SKU < changes[j].item().SKU
+	})
+	return changes
+}
+
+// item returns the change's current figures, or the previous ones for a removed item.
+func (c hotsheetChange) item() *hotsheetItem {
+	if c.current != nil {
+		return c.current
+	}
+	return c.previous
+}
+
+// values returns the change's Changes sheet cells, without the Product Line column.
+func (c hotsheetChange) values() []interface{} {
+	item := c.item()
+	values := []interface{}{item.SKU, item.Description, item.Sheet, strings.Join(c.labels, "; ")}
+	prevStatus, status := "", ""
+	if c.previous != nil {
+		prevStatus = c.previous.Status
+	}
+	if c.current != nil {
+		status = c.current.Status
+	}
+	values = append(values, prevStatus, status)
+
+	quantity := func(get func(*hotsheetItem) int) []interface{} {
+		var prev, cur, diff interface{} = "", "", ""
+		if c.previous != nil {
+			prev = get(c.previous)
+		}
+		if c.current != nil {
+			cur = get(c.current)
+		}
+		if c.previous != nil && c.current != nil {
+			diff = get(c.current) - get(c.previous)
+		}
+		return []interface{}{prev, cur, diff}
+	}
+	values = append(values, quantity(func(i *hotsheetItem) int { return i.OnHand })...)
+	values = append(values, quantity(func(i *hotsheetItem) int { return i.OnPO })...)
+
+	mto := func(i *hotsheetItem, ytd bool) interface{} {
+		switch {
+		case i == nil:
+			return ""
+		case ytd && i.HasMTOYTD:
+			return i.MTOYTD
+		case !ytd && i.HasMTOPY:
+			return i.MTOPY
+		}
+		return ""
+	}
+	values = append(values, mto(c.previous, true), mto(c.current, true), mto(c.previous, false), mto(c.current, false))
+	return values
+}
+
+// writeChangesSheet creates the Changes sheet in f and lists changes on it. productLineColumn adds
+// the Product Line column used by the consolidated workbook. The header comment names the
+// hotsheet the changes are measured from.
+func writeChangesSheet(f *excelize.File, changes *hotsheetChanges, productLineColumn bool) error {
+	sheetName := changesSheetName
+	if _, err := f.NewSheet(sheetName); err != nil {
+		return fmt.Errorf("failed to create %s sheet: %w", sheetName, err)
+	}
+
+	headers, widths := changesHeaders, changesColumnWidths
+	if productLineColumn {
+		headers = append([]string{"Product Line"}, headers...)
+		widths = append([]float64{14}, widths...)
+	}
+	styles := newStyleCache(f)
+	headerStyle, err := styles.id(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(standardHeaderFill),
+		Font:      boldFont(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s header style: %w", sheetName, err)
+	}
+	dataStyle, err := styles.id(&excelize.Style{Alignment: centeredAlignment(), Border: thinBlackBorder()})
+	if err != nil {
+		return fmt.Errorf("failed to create %s data style: %w", sheetName, err)
+	}
+
+	for c, h := range headers {
+		cell, _ := excelize.CoordinatesToCellName(c+1, 1)
+		if err := f.SetCellValue(sheetName, cell, h); err != nil {
+			return fmt.Errorf("failed to set %s header %s: %w", sheetName, cell, err)
+		}
+		col, _ := excelize.ColumnNumberToName(c + 1)
+		if err := f.SetColWidth(sheetName, col, col, widths[c]); err != nil {
+			return fmt.Errorf("failed to set width for %s column %s: %w", sheetName, col, err)
+		}
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(headers))
+	if err := f.SetCellStyle(sheetName, "A1", lastCol+"1", headerStyle); err != nil {
+		return fmt.Errorf("failed to style %s header row: %w", sheetName, err)
+	}
+	_ = f.AddComment(sheetName, excelize.Comment{
+		Cell:   "A1",
+		Author: "Shane DuPrey",
+		Text: fmt.Sprintf("Changes since %s. An MTO crossing is a move into another red, yellow, or green band under the current thresholds, and the new band's color is shown.",
+			filepath.Base(changes.comparedWith)),
+		Height: 120,
+		Width:  200,
+	})
+
+	// The MTO columns are the last four: previous and current YTD, then previous and current PY.
+	mtoYTDCol := len(headers) - 2
+	mtoPYCol := len(headers)
+	for i, change := range changes.rows {
+		rowNum := i + 2
+		values := change.values()
+		if productLineColumn {
+			values = append([]interface{}{change.productLine}, values...)
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
+			return fmt.Errorf("failed to write %s row %d: %w", sheetName, rowNum, err)
+		}
+		if err := f.SetCellStyle(sheetName, cell, fmt.Sprintf("%s%d", lastCol, rowNum), dataStyle); err != nil {
+			return fmt.Errorf("failed to style %s row %d: %w", sheetName, rowNum, err)
+		}
+		for _, crossing := range []struct {
+			col  int
+			fill string
+		}{{mtoYTDCol, change.ytdFill}, {mtoPYCol, change.pyFill}} {
+			if crossing.fill == "" {
+				continue
+			}
+			style, err := styles.id(&excelize.Style{Alignment: centeredAlignment(), Border: thinBlackBorder(), Fill: patternFill(crossing.fill)})
+			if err != nil {
+				return fmt.Errorf("failed to create %s MTO style: %w", sheetName, err)
+			}
+			mtoCell, _ := excelize.CoordinatesToCellName(crossing.col, rowNum)
+			if err := f.SetCellStyle(sheetName, mtoCell, mtoCell, style); err != nil {
+				return fmt.Errorf("failed to style %s cell %s: %w", sheetName, mtoCell, err)
+			}
+		}
+	}
+
+	if err := f.AutoFilter(sheetName, fmt.Sprintf("A1:%s1", lastCol), nil); err != nil {
+		return fmt.Errorf("failed to set autofilter for %s: %w", sheetName, err)
+	}
+	if err := f.SetPanes(sheetName, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return fmt.Errorf("failed to freeze %s header: %w", sheetName, err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package hotsheet
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestGenerateAddsChangesSheet verifies that a run finds the product line's previous hotsheet in
+// the output folder and lists added and removed items, status and quantity changes, and MTO band
+// crossings on a Changes sheet.
+func TestGenerateAddsChangesSheet(t *testing.T) {
+	t.Parallel()
+
+	_, input := writeGenerateTestInputs(t)
+	outputDir := t.TempDir()
+	run := func(day int, rows ...[]string) *GenerateResult {
+		t.Helper()
+		inventoryPath := writeChangesTestInventory(t, rows...)
+		result, err := GenerateWithOptions(context.Background(), GenerateOptions{
+			InventoryPath: inventoryPath,
+			OutputDir:     outputDir,
+			Input:         input,
+			Now:           func() time.Time { return time.Date(2026, time.March, day, 9, 0, 0, 0, time.Local) },
+			Logger:        slog.New(slog.DiscardHandler),
+		})
+		if err != nil {
+			t.Fatalf("GenerateWithOptions returned error: %v", err)
+		}
+		return result
+	}
+
+	first := run(2,
+		changesTestRow("SKU0", "Active", "500"),
+		changesTestRow("SKU1", "Active", "25"),
+		changesTestRow("SKU3", "Active", "25"),
+	)
+	if len(first.Files) != 1 || first.Files[0].ComparedWith != "" {
+		t.Fatalf("first run Files = %+v, want one hotsheet with nothing to compare with", first.Files)
+	}
+	second := run(9,
+		changesTestRow("SKU0", "Rundown", "1"),
+		changesTestRow("SKU2", "Active", "25"),
+		changesTestRow("SKU3", "Active", "25"),
+	)
+	file := second.Files[0]
+	if file.ComparedWith != first.Files[0].Path || file.Changes != 3 {
+		t.Fatalf("second run file = %+v, want 3 changes since %s", file, first.Files[0].Path)
+	}
+
+	f, err := excelize.OpenFile(file.Path)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	rows, err := f.GetRows(changesSheetName)
+	if err != nil {
+		t.Fatalf("GetRows returned error: %v", err)
+	}
+	if len(rows) != 4 || !slices.Equal(rows[0], changesHeaders) {
+		t.Fatalf("Changes rows = %q, want the header and three changes", rows)
+	}
+	if rows[1][0] != "SKU2" || rows[1][3] != changeAdded || rows[2][0] != "SKU1" || rows[2][3] != changeRemoved {
+		t.Fatalf("Changes rows = %q, want SKU2 added, then SKU1 removed", rows[1:3])
+	}
+	changed := rows[3]
+	wantLabels := "Status changed; QTY on Hand changed; MTO YTD green to red; MTO PY green to yellow"
+	if changed[0] != "SKU0" || changed[3] != wantLabels || changed[4] != "Active" || changed[5] != "Rundown" || changed[8] != "-499" {
+		t.Fatalf("SKU0 row = %q, want %q with the status and on-hand change", changed, wantLabels)
+	}
+	comments, err := f.GetComments(changesSheetName)
+	if err != nil || len(comments) != 1 || !strings.Contains(comments[0].Text, filepath.Base(first.Files[0].Path)) {
+		t.Fatalf("Changes comments = %+v, %v; want one naming the previous hotsheet", comments, err)
+	}
+}
+
+// TestGenerateChangesReadsConsolidatedWorkbook verifies that a consolidated run compares each
+// product line with its tabs in the previous consolidated workbook, and that an explicitly chosen
+// file is used in place of the output folder search.
+func TestGenerateChangesReadsConsolidatedWorkbook(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS", "OAT")
+	firstDir, secondDir := t.TempDir(), t.TempDir()
+	opts := GenerateOptions{
+		InventoryPath: inventoryPath,
+		OutputDir:     firstDir,
+		Input:         input,
+		Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:        slog.New(slog.DiscardHandler),
+		Layout:        LayoutConsolidated,
+	}
+	first, err := GenerateWithOptions(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("first GenerateWithOptions returned error: %v", err)
+	}
+
+	opts.OutputDir = secondDir
+	opts.CompareWith = first.Files[0].Path
+	opts.Now = func() time.Time { return time.Date(2026, time.March, 3, 9, 0, 0, 0, time.Local) }
+	second, err := GenerateWithOptions(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("second GenerateWithOptions returned error: %v", err)
+	}
+	if file := second.Files[0]; file.ComparedWith != first.Files[0].Path || file.Changes != 0 {
+		t.Fatalf("second run file = %+v, want no changes since %s", file, first.Files[0].Path)
+	}
+	f, err := excelize.OpenFile(second.Files[0].Path)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	header, err := f.GetRows(changesSheetName)
+	if err != nil || len(header) != 1 || header[0][0] != "Product Line" {
+		t.Fatalf("Changes rows = %q, %v; want only the header with a Product Line column", header, err)
+	}
+
+	opts.CompareWith = filepath.Join(firstDir, "missing.xlsx")
+	if _, err := GenerateWithOptions(context.Background(), opts); err == nil {
+		t.Fatal("GenerateWithOptions returned nil, want an error for a missing previous hotsheet")
+	}
+}
+
+// TestGenerateComparesChosenFileOnlyWithItsProductLine verifies that a chosen product line
+// hotsheet is only compared with the product line it is named for, and that the other lines of
+// the run are left without a Changes sheet and reported in a warning.
+func TestGenerateComparesChosenFileOnlyWithItsProductLine(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS", "OAT")
+	opts := GenerateOptions{
+		InventoryPath: inventoryPath,
+		OutputDir:     t.TempDir(),
+		Input:         input,
+		Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:        slog.New(slog.DiscardHandler),
+	}
+	first, err := GenerateWithOptions(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("first GenerateWithOptions returned error: %v", err)
+	}
+	basIndex := slices.IndexFunc(first.Files, func(file FileResult) bool { return file.ProductLine == "BAS" })
+	if len(first.Files) != 2 || basIndex < 0 {
+		t.Fatalf("first run Files = %+v, want BAS and OAT hotsheets", first.Files)
+	}
+
+	opts.OutputDir = t.TempDir()
+	opts.CompareWith = first.Files[basIndex].Path
+	opts.Now = func() time.Time { return time.Date(2026, time.March, 3, 9, 0, 0, 0, time.Local) }
+	second, err := GenerateWithOptions(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("second GenerateWithOptions returned error: %v", err)
+	}
+	for _, file := range second.Files {
+		want := ""
+		if file.ProductLine == "BAS" {
+			want = opts.CompareWith
+		}
+		if file.ComparedWith != want || file.Changes != 0 {
+			t.Errorf("%s file = %+v, want it compared with %q and no changes", file.ProductLine, file, want)
+		}
+	}
+	if len(second.Warnings) != 1 || !strings.HasPrefix(second.Warnings[0], "OAT was not compared") {
+		t.Fatalf("second run warnings = %q, want one for OAT", second.Warnings)
+	}
+}
+
+// TestFindPreviousHotsheet verifies that the newest hotsheet dated up to the run's date is found,
+// ignoring case and other product lines.
+func TestFindPreviousHotsheet(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"BAS_hotsheet_20260301.xlsx",
+		"bas_HOTSHEET_20260303.xlsx",
+		"BAS_hotsheet_20260305.xlsx",
+		"BAS_hotsheet_20260310.xlsx",
+		"BAS_hotsheet_2026030.xlsx",
+		"OAT_hotsheet_20260304.xlsx",
+		"BAS_hotsheet_latest.xlsx",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+	}
+	tests := []struct {
+		name      string
+		dateStamp string
+		want      string
+	}{
+		{name: "BAS_hotsheet_{date}.xlsx", dateStamp: "20260305", want: "BAS_hotsheet_20260305.xlsx"},
+		{name: "BAS_hotsheet_{date}.xlsx", dateStamp: "20260304", want: "bas_HOTSHEET_20260303.xlsx"},
+		{name: "BAS_hotsheet_{date}.xlsx", dateStamp: "20260228", want: ""},
+		{name: "BAS_hotsheet_latest.xlsx", dateStamp: "20260305", want: "BAS_hotsheet_latest.xlsx"},
+	}
+	for _, tt := range tests {
+		got, err := findPreviousHotsheet(dir, tt.name, tt.dateStamp)
+		if err != nil {
+			t.Fatalf("findPreviousHotsheet(%q, %q) returned error: %v", tt.name, tt.dateStamp, err)
+		}
+		if tt.want != "" {
+			tt.want = filepath.Join(dir, tt.want)
+		}
+		if got != tt.want {
+			t.Errorf("findPreviousHotsheet(%q, %q) = %q, want %q", tt.name, tt.dateStamp, got, tt.want)
+		}
+	}
+}
+
+// changesTestRow returns the item code row and value row of one BAS item.
+func changesTestRow(sku, status, onHand string) []string {
+	values := testinputs.InventoryValueRow("BAS", "Birthday", onHand)
+	values[4] = status
+	return []string{"," + sku, strings.Join(values, ",")}
+}
+
+// writeChangesTestInventory writes an inventory report of the given item rows.
+func writeChangesTestInventory(t *testing.T, rows ...[]string) string {
+	t.Helper()
+	lines := []string{strings.Join(testinputs.InventoryHeader, ",")}
+	for _, row := range rows {
+		lines = append(lines, row...)
+	}
+	path := filepath.Join(t.TempDir(), "inventory.csv")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	return path
+}
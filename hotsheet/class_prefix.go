@@ -0,0 +1,255 @@
+package hotsheet
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// classRulesConfigFileName is the class prefix rules file looked up in the app's config folder.
+const classRulesConfigFileName = "class_rules.json"
+
+// classPrefixRule adds Prefix to the Class column of every item it matches. Each condition that is
+// set must hold: the SKU starts with one of SKUPrefixes, ends with one of SKUSuffixes, matches
+// SKURegex, and the item belongs to one of ProductLines. SKUs are upper-cased before matching.
+type classPrefixRule struct {
+	Name         string   `json:"name,omitempty"`
+	Prefix       string   `json:"prefix"`
+	SKUPrefixes  []string `json:"skuPrefixes,omitempty"`
+	SKUSuffixes  []string `json:"skuSuffixes,omitempty"`
+	SKURegex     string   `json:"skuRegex,omitempty"`
+	ProductLines []string `json:"productLines,omitempty"`
+}
+
+// classRulesConfig is the layout of the class prefix rules file.
+type classRulesConfig struct {
+	Rules []classPrefixRule `json:"rules"`
+}
+
+// defaultClassPrefixRules are the packaging prefixes the hotsheet has always shown. More specific
+// suffixes come first, and the product-line rules for SKUs ending in "B" only apply when no
+// packaging suffix matched.
+func defaultClassPrefixRules() []classPrefixRule {
+	return []classPrefixRule{
+		{Name: "LLB suffix", Prefix: "LLB - ", SKUSuffixes: []string{"LLB"}},
+		{Name: "TB suffix", Prefix: "TB - ", SKUSuffixes: []string{"TB"}},
+		{Name: "TB prefix", Prefix: "TB - ", SKUPrefixes: []string{"TB"}},
+		{Name: "WM suffix", Prefix: "WM - ", SKUSuffixes: []string{"WM"}},
+		{Name: "AN suffix", Prefix: "AN - ", SKUSuffixes: []string{"AN"}},
+		{Name: "BN suffix", Prefix: "BN - ", SKUSuffixes: []string{"BN"}},
+		{Name: "BX suffix", Prefix: "BX - ", SKUSuffixes: []string{"BX"}},
+		{Name: "Custom", Prefix: "Custom - ", SKUSuffixes: []string{"C"}},
+		{Name: "2021 FC bulk", Prefix: "Bulk - ", ProductLines: []string{"2021"}, SKUPrefixes: []string{"FC"}, SKUSuffixes: []string{"B"}},
+		{Name: "2021 box", Prefix: "BX - ", ProductLines: []string{"2021"}, SKUSuffixes: []string{"B"}},
+		{Name: "BAS bulk", Prefix: "Bulk - ", ProductLines: []string{"BAS"}, SKUSuffixes: []string{"B"}},
+		{Name: "OAT box", Prefix: "BX - ", ProductLines: []string{"OAT"}, SKUSuffixes: []string{"B"}},
+	}
+}
+
+// classPrefixRuleSet is an ordered, validated list of class prefix rules; the first match wins.
+type classPrefixRuleSet struct {
+	rules []compiledClassPrefixRule
+}
+
+// compiledClassPrefixRule is a classPrefixRule with its conditions normalized for matching.
+type compiledClassPrefixRule struct {
+	classPrefixRule
+	regex *regexp.Regexp
+}
+
+// builtinClassPrefixRules is used when no class rules file exists.
+var builtinClassPrefixRules = mustClassPrefixRuleSet(defaultClassPrefixRules())
+
+// newClassPrefixRuleSet validates rules and upper-cases their SKU and product line conditions.
+// Unnamed rules are named by their position.
+func newClassPrefixRuleSet(rules []classPrefixRule) (*classPrefixRuleSet, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no class prefix rules defined")
+	}
+	set := &classPrefixRuleSet{rules: make([]compiledClassPrefixRule, 0, len(rules))}
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Name) == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		rule.SKUPrefixes = upperTrimmed(rule.SKUPrefixes)
+		rule.SKUSuffixes = upperTrimmed(rule.SKUSuffixes)
+		rule.ProductLines = upperTrimmed(rule.ProductLines)
+		compiled := compiledClassPrefixRule{classPrefixRule: rule}
+		if rule.SKURegex != "" {
+			re, err := regexp.Compile(rule.SKURegex)
+			if err != nil {
+				return nil, fmt.Errorf("class prefix rule %q: invalid skuRegex: %w", rule.Name, err)
+			}
+			compiled.regex = re
+		}
+		if len(rule.SKUPrefixes) == 0 && len(rule.SKUSuffixes) == 0 && compiled.regex == nil && len(rule.ProductLines) == 0 {
+			return nil, fmt.Errorf("class prefix rule %q has no match conditions", rule.Name)
+		}
+		set.rules = append(set.rules, compiled)
+	}
+	return set, nil
+}
+
+// upperTrimmed returns the non-blank values of list, trimmed and upper-cased.
+func upperTrimmed(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v = strings.ToUpper(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// mustClassPrefixRuleSet is newClassPrefixRuleSet for the built-in rules, which are known to be valid.
+func mustClassPrefixRuleSet(rules []classPrefixRule) *classPrefixRuleSet {
+	set, err := newClassPrefixRuleSet(rules)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// loadClassPrefixRules reads the class rules file at path. When optional is set, a missing file
+// is not an error: the built-in rules are returned with found=false.
+func loadClassPrefixRules(path string, optional bool) (set *classPrefixRuleSet, found bool, err error) {
+	var cfg classRulesConfig
+	found, err = readJSONConfig(path, "class rules", optional, &cfg)
+	if err != nil {
+		return nil, found, err
+	}
+	if !found {
+		return builtinClassPrefixRules, false, nil
+	}
+	set, err = newClassPrefixRuleSet(cfg.Rules)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid class rules %s: %w", path, err)
+	}
+	return set, true, nil
+}
+
+// resolveClassPrefixRules loads the class rules from path, or from the default location when
+// path is empty. Only the default file may be missing.
+func resolveClassPrefixRules(path string, logger *slog.Logger) (*classPrefixRuleSet, error) {
+	optional := path == ""
+	if optional {
+		var err error
+		if path, err = userConfigFilePath(classRulesConfigFileName); err != nil {
+			// Without a config directory there is no file to read, so the built-in rules apply.
+			if logger != nil {
+				logger.Warn("using built-in class rules", "err", err)
+			}
+			return builtinClassPrefixRules, nil
+		}
+	}
+	set, found, err := loadClassPrefixRules(path, optional)
+	if err != nil {
+		return nil, err
+	}
+	if logger != nil {
+		logger.Info("class rules loaded", "path", path, "fromFile", found, "rules", len(set.rules))
+	}
+	return set, nil
+}
+
+// match returns the 0-based position of the first rule that applies to sku in productLine. A nil
+// set uses the built-in rules.
+func (s *classPrefixRuleSet) match(sku, productLine string) (int, bool) {
+	if s == nil {
+		s = builtinClassPrefixRules
+	}
+	sku = strings.ToUpper(strings.TrimSpace(sku))
+	productLine = strings.ToUpper(strings.TrimSpace(productLine))
+	for i, rule := range s.rules {
+		if rule.matches(sku, productLine) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// matches reports whether every condition of the rule holds for an upper-cased SKU and product line.
+func (r compiledClassPrefixRule) matches(sku, productLine string) bool {
+	if len(r.SKUPrefixes) > 0 && !anyOf(r.SKUPrefixes, func(p string) bool { return strings.HasPrefix(sku, p) }) {
+		return false
+	}
+	if len(r.SKUSuffixes) > 0 && !anyOf(r.SKUSuffixes, func(s string) bool { return strings.HasSuffix(sku, s) }) {
+		return false
+	}
+	if r.regex != nil && !r.regex.MatchString(sku) {
+		return false
+	}
+	if len(r.ProductLines) > 0 && !anyOf(r.ProductLines, func(pl string) bool { return pl == productLine }) {
+		return false
+	}
+	return true
+}
+
+// anyOf reports whether ok holds for any value.
+func anyOf(values []string, ok func(string) bool) bool {
+	for _, v := range values {
+		if ok(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyStandardDisplayClassPrefix applies the first matching class prefix rule while keeping the
+// original inventory class available through RawClassDesc for downstream reuse.
+func applyStandardDisplayClassPrefix(rules *classPrefixRuleSet, e *inventoryEntry) string {
+	if rules == nil {
+		rules = builtinClassPrefixRules
+	}
+	classDesc := strings.TrimSpace(e.ClassDesc)
+	if i, ok := rules.match(e.SKU, e.ProductLine); ok {
+		if prefix := rules.rules[i].Prefix; prefix != "" && !strings.HasPrefix(classDesc, prefix) {
+			classDesc = prefix + classDesc
+		}
+	}
+	e.ClassDesc = classDesc
+	return classDesc
+}
+
+// ClassRuleMatch describes the class prefix rule that applies to a SKU.
+type ClassRuleMatch struct {
+	// Matched is false when no rule applies and the class is shown unchanged.
+	Matched bool
+	// Position is the rule's 1-based position in the rule list.
+	Position int
+	Name     string
+	Prefix   string
+	// Source is the rules file that was read, or "built-in" when it does not exist.
+	Source string
+}
+
+// MatchClassRule reports which class prefix rule applies to sku in productLine. The rules are read
+// from path, or from class_rules.json in the app's folder of the user config directory when path
+// is empty, so edits to the file are picked up on every call. Only the default file may be
+// missing, in which case the built-in rules are matched.
+func MatchClassRule(path, sku, productLine string) (ClassRuleMatch, error) {
+	optional := path == ""
+	if optional {
+		var err error
+		if path, err = userConfigFilePath(classRulesConfigFileName); err != nil {
+			return ClassRuleMatch{}, err
+		}
+	}
+	set, found, err := loadClassPrefixRules(path, optional)
+	if err != nil {
+		return ClassRuleMatch{}, err
+	}
+	result := ClassRuleMatch{Source: path}
+	if !found {
+		result.Source = "built-in"
+	}
+	if i, ok := set.match(sku, productLine); ok {
+		result.Matched = true
+		result.Position = i + 1
+		result.Name = set.rules[i].Name
+		result.Prefix = set.rules[i].Prefix
+	}
+	return result, nil
+}
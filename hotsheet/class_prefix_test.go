@@ -0,0 +1,93 @@
+package hotsheet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBuiltinClassPrefixRules verifies that the built-in rule table keeps the packaging prefixes
+// the hotsheet has always shown, including the product-line rules for SKUs ending in "B".
+func TestBuiltinClassPrefixRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sku, productLine, want string
+	}{
+		{"AB123-LLB", "BAS", "LLB - Cards"},
+		{"TB100", "BAS", "TB - Cards"},
+		{"ab100tb", "BAS", "TB - Cards"},
+		{"AB100WM", "BAS", "WM - Cards"},
+		{"AB100C", "BAS", "Custom - Cards"},
+		{"FC100B", "2021", "Bulk - Cards"},
+		{"AB100B", "2021", "BX - Cards"},
+		{"AB100B", "BAS", "Bulk - Cards"},
+		{"AB100B", "OAT", "BX - Cards"},
+		{"AB100B", "XYZ", "Cards"},
+		{"AB100", "BAS", "Cards"},
+	}
+	for _, tt := range tests {
+		e := &inventoryEntry{SKU: tt.sku, ProductLine: tt.productLine, ClassDesc: " Cards "}
+		if got := applyStandardDisplayClassPrefix(nil, e); got != tt.want {
+			t.Errorf("%s in %s: class = %q, want %q", tt.sku, tt.productLine, got, tt.want)
+		}
+	}
+
+	// Applying the rules twice must not stack the prefix.
+	e := &inventoryEntry{SKU: "AB100BX", ClassDesc: "Cards"}
+	applyStandardDisplayClassPrefix(nil, e)
+	if got := applyStandardDisplayClassPrefix(nil, e); got != "BX - Cards" {
+		t.Fatalf("second application gave %q, want %q", got, "BX - Cards")
+	}
+}
+
+// TestMatchClassRuleUsesFileRulesInOrder verifies that a rules file replaces the built-in table, that
+// every condition of a rule must hold, and that MatchClassRule reports the first matching rule.
+func TestMatchClassRuleUsesFileRulesInOrder(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), classRulesConfigFileName)
+	config := `{"rules": [
+		{"name": "Gift wrap", "prefix": "GW - ", "skuRegex": "^GW-\\d+$", "productLines": ["bas"]},
+		{"prefix": "Mini - ", "skuSuffixes": ["-m", "-mini"]}
+	]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	match, err := MatchClassRule(path, "gw-12", "BAS")
+	if err != nil {
+		t.Fatalf("MatchClassRule returned error: %v", err)
+	}
+	if want := (ClassRuleMatch{Matched: true, Position: 1, Name: "Gift wrap", Prefix: "GW - ", Source: path}); match != want {
+		t.Fatalf("MatchClassRule = %+v, want %+v", match, want)
+	}
+	if match, _ := MatchClassRule(path, "GW-12", "OAT"); match.Matched {
+		t.Fatalf("expected the product line condition to reject OAT, got %+v", match)
+	}
+	if match, _ := MatchClassRule(path, "AB100-MINI", "OAT"); match.Position != 2 || match.Name != "rule 2" {
+		t.Fatalf("expected the unnamed suffix rule to match, got %+v", match)
+	}
+	if match, _ := MatchClassRule(path, "AB100BX", "OAT"); match.Matched {
+		t.Fatalf("expected the built-in rules to be replaced, got %+v", match)
+	}
+
+	if _, err := MatchClassRule(filepath.Join(t.TempDir(), classRulesConfigFileName), "AB100BX", "OAT"); err == nil {
+		t.Fatal("MatchClassRule with a missing rules file returned nil, want an error")
+	}
+
+	for _, bad := range []string{
+		`{"rules": []}`,
+		`{"rules": [{"prefix": "X - "}]}`,
+		`{"rules": [{"prefix": "X - ", "skuRegex": "("}]}`,
+		`{"rules": [{"prefix": "X - ", "skuSufixes": ["X"]}]}`,
+	} {
+		if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+		if _, _, err := loadClassPrefixRules(path, false); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("loadClassPrefixRules(%s) error = %v, want one naming the file", bad, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package hotsheet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// appConfigDirName is the app's folder inside the user config directory, where the optional
+// mapping and calendar files live.
+const appConfigDirName = "bsc-hotsheet"
+
+// userConfigFilePath returns the location of a config file inside the app's folder of the user
+// config directory (for example %AppData%\bsc-hotsheet\occasions.json on Windows).
+func userConfigFilePath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, appConfigDirName, name), nil
+}
+
+// readJSONConfig decodes the JSON config file at path into v. Unknown fields are rejected so a
+// misspelled key is not silently ignored. what names the file in error messages.
+//
+// optional is set when path is the default location from userConfigFilePath: a missing file is
+// then not an error and reports found=false, so the built-in values apply. A file the user chose
+// must exist, so a mistyped path is not silently replaced by the built-in values.
+func readJSONConfig(path, what string, optional bool, v any) (found bool, err error) {
+	data, err := os.ReadFile(path)
+	if optional && errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s %s: %w", what, path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return true, fmt.Errorf("failed to parse %s %s: %w", what, path, err)
+	}
+	return true, nil
+}
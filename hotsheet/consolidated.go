@@ -0,0 +1,351 @@
+package hotsheet
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// consolidatedSummarySheetName is the first tab of the consolidated workbook.
+const consolidatedSummarySheetName = "Summary"
+
+// defaultConsolidatedFileNameTemplate is the consolidated workbook's file name.
+const defaultConsolidatedFileNameTemplate = "hotsheet_{date}.xlsx"
+
+// consolidatedProductLineName replaces {productLine} in a consolidated workbook's file name.
+const consolidatedProductLineName = "ALL"
+
+// maxSheetNameLength is Excel's limit on worksheet names.
+const maxSheetNameLength = 31
+
+// consolidatedSummaryHeaders are the column titles of the Summary sheet. The link columns after
+// the totals jump to the product line's tabs.
+var consolidatedSummaryHeaders = []string{
+	"Product Line", "Items", "QTY on Hand", "Total QTY on PO", "QTY on SO+BO", "QTY Available",
+	"QTY Sold+Issued YTD", "QTY Sold+Issued PY", "Dollar Sold YTD", "Dollar Sold PY",
+	"Everyday", "Winter", "Spring", "Data Insights",
+}
+
+// consolidatedSummaryColumnWidths keeps the Summary sheet readable without manual resizing.
+var consolidatedSummaryColumnWidths = []float64{15, 10, 12, 15, 15, 15, 20, 20, 18, 18, 16, 16, 16, 16}
+
+// consolidatedLinkColor is the font color of the Summary sheet's links.
+const consolidatedLinkColor = "0563C1"
+
+// productLineTotals are the Summary sheet's figures for one product line.
+type productLineTotals struct {
+	items         int
+	onHand        int
+	onPO          int
+	onSOBO        int
+	available     int
+	soldYTD       int
+	soldPY        int
+	dollarSoldYTD float64
+	dollarSoldPY  float64
+	// seasonItems counts the items on each standard tab.
+	seasonItems map[string]int
+}
+
+// add adds e to the totals, deriving the columns the way the standard sheets do.
+func (t *productLineTotals) add(e *inventoryEntry) {
+	onSOBO := e.OnSO + e.OnBO
+	t.items++
+	t.onHand += e.OnHand
+	t.onPO += e.OnPO
+	t.onSOBO += onSOBO
+	t.available += e.OnHand + e.OnPO - onSOBO
+	t.soldYTD += e.YTDSold + max(e.YTDIssued, 0)
+	t.soldPY += e.SoldPY + max(e.IssuedPY, 0)
+	t.dollarSoldYTD += e.DollarSoldYTD
+	t.dollarSoldPY += e.DollarSoldPY
+	if t.seasonItems == nil {
+		t.seasonItems = make(map[string]int)
+	}
+	t.seasonItems[entrySeason(e)]++
+}
+
+// addTotals adds other's figures to t.
+func (t *productLineTotals) addTotals(other productLineTotals) {
+	t.items += other.items
+	t.onHand += other.onHand
+	t.onPO += other.onPO
+	t.onSOBO += other.onSOBO
+	t.available += other.available
+	t.soldYTD += other.soldYTD
+	t.soldPY += other.soldPY
+	t.dollarSoldYTD += other.dollarSoldYTD
+	t.dollarSoldPY += other.dollarSoldPY
+}
+
+// values returns the Summary sheet's total columns.
+func (t productLineTotals) values() []interface{} {
+	return []interface{}{t.items, t.onHand, t.onPO, t.onSOBO, t.available, t.soldYTD, t.soldPY, t.dollarSoldYTD, t.dollarSoldPY}
+}
+
+// consolidatedTabPrefixes returns the prefix of each product line's tabs, such as "BAS ". Codes
+// are stripped of characters Excel does not allow in sheet names and shortened so the longest
+// tab name fits, and two product lines that end up with the same prefix are an error.
+func consolidatedTabPrefixes(productLines []string) (map[string]string, error) {
+	maxCodeLength := maxSheetNameLength - len(" "+dataInsightsSheetName)
+	prefixes := make(map[string]string, len(productLines))
+	owners := make(map[string]string, len(productLines))
+	for _, productLine := range productLines {
+		code := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`:\/?*[]'`, r) {
+				return -1
+			}
+			return r
+		}, strings.TrimSpace(productLine))
+		if code == "" {
+			code = "unknown"
+		}
+		if runes := []rune(code); len(runes) > maxCodeLength {
+			code = string(runes[:maxCodeLength])
+		}
+		prefix := code + " "
+		if owner, taken := owners[strings.ToLower(prefix)]; taken {
+			return nil, fmt.Errorf("product lines %q and %q would share the %q tabs", owner, productLine, strings.TrimSpace(prefix))
+		}
+		owners[strings.ToLower(prefix)] = productLine
+		prefixes[productLine] = prefix
+	}
+	return prefixes, nil
+}
+
+// buildConsolidatedWorkbook writes every product line into one workbook at outPath: a Summary
+// sheet with each line's totals and links to its tabs, the Everyday, Winter, Spring, and Data
+// Insights tabs of each line, named after the line, and company-wide Changes, Open POs, PO
+// Reconciliation, and Import Issues sheets. opts.issues holds every issue of the run, and
+// sheetOptions returns each line's standard sheet options. Progress follows the product lines
+// written, and building stops with ctx's error once ctx is cancelled.
+func buildConsolidatedWorkbook(ctx context.Context, entriesByProductLine map[string][]*inventoryEntry, outPath string, opts productLineWorkbookOptions, sheetOptions func(productLine string) standardSheetOptions, report ProgressCallback, logger *slog.Logger) error {
+	productLines := slices.Sorted(maps.Keys(entriesByProductLine))
+	prefixes, err := consolidatedTabPrefixes(productLines)
+	if err != nil {
+		return err
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		_ = f.Close()
+	}()
+	if err := f.SetSheetName("Sheet1", consolidatedSummarySheetName); err != nil {
+		return fmt.Errorf("failed to create %s sheet: %w", consolidatedSummarySheetName, err)
+	}
+
+	var allEntries []*inventoryEntry
+	totals := make([]productLineTotals, len(productLines))
+	for i, productLine := range productLines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		reportGenerationProgress(report, workbookProgress(i, len(productLines)), fmt.Sprintf("Writing %s tabs...", productLine))
+
+		entries := entriesByProductLine[productLine]
+		sortEntriesForProductLine(entries)
+		allEntries = append(allEntries, entries...)
+		for _, e := range entries {
+			totals[i].add(e)
+		}
+
+		prefix := prefixes[productLine]
+		for _, season := range standardSheetNames {
+			if _, err := f.NewSheet(prefix + season); err != nil {
+				return fmt.Errorf("failed to create %s%s sheet: %w", prefix, season, err)
+			}
+		}
+		sheetOpts := sheetOptions(productLine)
+		sheetOpts.TabPrefix = prefix
+		if err := writeStandardSheets(ctx, f, entries, opts.hasPO, sheetOpts); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if logger != nil {
+				logger.Error("failed to write standard sheets", "productLine", productLine, "err", err)
+			}
+			return fmt.Errorf("failed to write standard sheets for %s: %w", productLine, err)
+		}
+		if !opts.features.NoDataInsights {
+			if err := writeDataInsightsSheet(f, prefix+dataInsightsSheetName, entries, opts.calendar, sheetOpts.Trends, opts.now); err != nil {
+				if logger != nil {
+					logger.Error("failed to create Data Insights sheet", "productLine", productLine, "err", err)
+				}
+				return fmt.Errorf("failed to create Data Insights sheet for %s: %w", productLine, err)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.changes != nil {
+		if err := writeChangesSheet(f, opts.changes, true); err != nil {
+			return fmt.Errorf("failed to create Changes sheet: %w", err)
+		}
+	}
+	if opts.hasPO && !opts.features.NoPOSheets {
+		if err := writeOpenPOsSheet(f, allEntries); err != nil {
+			return fmt.Errorf("failed to create Open POs sheet: %w", err)
+		}
+		if err := writePOReconciliationSheet(f, allEntries, opts.poOnly); err != nil {
+			return fmt.Errorf("failed to create PO Reconciliation sheet: %w", err)
+		}
+	}
+	if len(opts.issues) > 0 && !opts.features.NoImportIssues {
+		if err := writeImportIssuesSheet(f, opts.issues); err != nil {
+			return fmt.Errorf("failed to create Import Issues sheet: %w", err)
+		}
+	}
+	if err := writeConsolidatedSummarySheet(f, productLines, prefixes, totals, !opts.features.NoDataInsights); err != nil {
+		return err
+	}
+	f.SetActiveSheet(0)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := saveWorkbook(f, outPath); err != nil {
+		if logger != nil {
+			logger.Error("failed to save consolidated hotsheet", "err", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// writeConsolidatedSummarySheet fills the Summary sheet with one row of totals per product line,
+// links to the line's tabs, and a company-wide total row. The Data Insights link column is
+// left out when that sheet was not written.
+func writeConsolidatedSummarySheet(f *excelize.File, productLines []string, prefixes map[string]string, totals []productLineTotals, dataInsights bool) error {
+	sheetName := consolidatedSummarySheetName
+	headers := consolidatedSummaryHeaders
+	if !dataInsights {
+		headers = headers[:len(headers)-1]
+	}
+	styles := newStyleCache(f)
+	headerStyle, err := styles.id(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(standardHeaderFill),
+		Font:      boldFont(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create summary header style: %w", err)
+	}
+	dataStyle, err := styles.id(&excelize.Style{Alignment: centeredAlignment(), Border: thinBlackBorder()})
+	if err != nil {
+		return fmt.Errorf("failed to create summary data style: %w", err)
+	}
+	currencyStyle, err := styles.id(&excelize.Style{Alignment: centeredAlignment(), Border: thinBlackBorder(), CustomNumFmt: currencyNumFmt()})
+	if err != nil {
+		return fmt.Errorf("failed to create summary currency style: %w", err)
+	}
+	linkStyle, err := styles.id(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Font:      &excelize.Font{Color: consolidatedLinkColor, Underline: "single"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create summary link style: %w", err)
+	}
+	totalStyle, err := styles.id(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(dataInsightsTotalFill),
+		Font:      boldFont(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create summary total style: %w", err)
+	}
+	currencyTotalStyle, err := styles.id(&excelize.Style{
+		Alignment:    centeredAlignment(),
+		Border:       thinBlackBorder(),
+		Fill:         patternFill(dataInsightsTotalFill),
+		Font:         boldFont(),
+		CustomNumFmt: currencyNumFmt(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create summary currency total style: %w", err)
+	}
+
+	for c, h := range headers {
+		cell, _ := excelize.CoordinatesToCellName(c+1, 1)
+		if err := f.SetCellValue(sheetName, cell, h); err != nil {
+			return fmt.Errorf("failed to write %s header: %w", sheetName, err)
+		}
+		col, _ := excelize.ColumnNumberToName(c + 1)
+		if err := f.SetColWidth(sheetName, col, col, consolidatedSummaryColumnWidths[c]); err != nil {
+			return fmt.Errorf("failed to set %s column width: %w", sheetName, err)
+		}
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(headers))
+	if err := f.SetCellStyle(sheetName, "A1", lastCol+"1", headerStyle); err != nil {
+		return fmt.Errorf("failed to style %s header: %w", sheetName, err)
+	}
+
+	// The total columns end with the two dollar columns; the link columns follow them.
+	totalColumns := len(productLineTotals{}.values())
+	writeRow := func(row int, label string, t productLineTotals, valueStyle, moneyStyle int) error {
+		values := append([]interface{}{label}, t.values()...)
+		for c, v := range values {
+			cell, _ := excelize.CoordinatesToCellName(c+1, row)
+			if err := f.SetCellValue(sheetName, cell, v); err != nil {
+				return fmt.Errorf("failed to write %s row %d: %w", sheetName, row, err)
+			}
+		}
+		firstMoney, _ := excelize.CoordinatesToCellName(totalColumns, row)
+		lastMoney, _ := excelize.CoordinatesToCellName(totalColumns+1, row)
+		lastValue, _ := excelize.CoordinatesToCellName(totalColumns-1, row)
+		if err := f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), lastValue, valueStyle); err != nil {
+			return fmt.Errorf("failed to style %s row %d: %w", sheetName, row, err)
+		}
+		if err := f.SetCellStyle(sheetName, firstMoney, lastMoney, moneyStyle); err != nil {
+			return fmt.Errorf("failed to style %s row %d: %w", sheetName, row, err)
+		}
+		return nil
+	}
+
+	var company productLineTotals
+	for i, productLine := range productLines {
+		row := i + 2
+		if err := writeRow(row, productLine, totals[i], dataStyle, currencyStyle); err != nil {
+			return err
+		}
+		company.addTotals(totals[i])
+
+		links := make([][2]string, 0, len(standardSheetNames)+1)
+		for _, season := range standardSheetNames {
+			links = append(links, [2]string{prefixes[productLine] + season, fmt.Sprintf("%s (%d)", season, totals[i].seasonItems[season])})
+		}
+		if dataInsights {
+			links = append(links, [2]string{prefixes[productLine] + dataInsightsSheetName, dataInsightsSheetName})
+		}
+		for l, link := range links {
+			cell, _ := excelize.CoordinatesToCellName(totalColumns+2+l, row)
+			if err := f.SetCellValue(sheetName, cell, link[1]); err != nil {
+				return fmt.Errorf("failed to write %s link: %w", sheetName, err)
+			}
+			if err := f.SetCellHyperLink(sheetName, cell, fmt.Sprintf("'%s'!A1", link[0]), "Location"); err != nil {
+				return fmt.Errorf("failed to link %s to %s: %w", cell, link[0], err)
+			}
+			if err := f.SetCellStyle(sheetName, cell, cell, linkStyle); err != nil {
+				return fmt.Errorf("failed to style %s link: %w", sheetName, err)
+			}
+		}
+	}
+	totalRow := len(productLines) + 2
+	if err := writeRow(totalRow, "Total", company, totalStyle, currencyTotalStyle); err != nil {
+		return err
+	}
+
+	if err := f.SetPanes(sheetName, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return fmt.Errorf("failed to freeze %s header: %w", sheetName, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package hotsheet
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// TestGenerateConsolidatedWorkbook verifies that the consolidated layout writes one workbook with
+// a Summary sheet of totals and links followed by each product line's tabs.
+func TestGenerateConsolidatedWorkbook(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "OAT", "BAS")
+	outputDir := t.TempDir()
+	result, err := GenerateWithOptions(context.Background(), GenerateOptions{
+		InventoryPath: inventoryPath,
+		OutputDir:     outputDir,
+		Input:         input,
+		Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:        slog.New(slog.DiscardHandler),
+		Layout:        LayoutConsolidated,
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions returned error: %v", err)
+	}
+	want := filepath.Join(outputDir, "hotsheet_20260302.xlsx")
+	if len(result.Files) != 1 || result.Files[0].Path != want || result.Files[0].Kind != FileKindConsolidated || result.Files[0].Items != 2 {
+		t.Fatalf("Files = %+v, want only the consolidated workbook with two items", result.Files)
+	}
+
+	f, err := excelize.OpenFile(want)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	wantSheets := []string{
+		"Summary",
+		"BAS Everyday", "BAS Winter", "BAS Spring", "BAS Data Insights",
+		"OAT Everyday", "OAT Winter", "OAT Spring", "OAT Data Insights",
+	}
+	if got := f.GetSheetList(); !slices.Equal(got, wantSheets) {
+		t.Fatalf("sheets = %q, want %q", got, wantSheets)
+	}
+
+	rows, err := f.GetRows("Summary")
+	if err != nil {
+		t.Fatalf("GetRows returned error: %v", err)
+	}
+	if len(rows) != 4 || rows[1][0] != "BAS" || rows[2][0] != "OAT" || rows[3][0] != "Total" {
+		t.Fatalf("Summary rows = %q, want BAS, OAT, and Total", rows)
+	}
+	// Each test item has 25 on hand and 10 on PO.
+	if rows[3][1] != "2" || rows[3][2] != "50" || rows[3][3] != "20" {
+		t.Fatalf("Total row = %q, want 2 items, 50 on hand, and 20 on PO", rows[3])
+	}
+	if rows[1][10] != "Everyday (1)" {
+		t.Fatalf("BAS Everyday link text = %q", rows[1][10])
+	}
+	linked, target, err := f.GetCellHyperLink("Summary", "K2")
+	if err != nil || !linked || target != "'BAS Everyday'!A1" {
+		t.Fatalf("K2 link = %v %q %v, want a link to BAS Everyday", linked, target, err)
+	}
+
+	sku, err := f.GetCellValue("OAT Everyday", "A2")
+	if err != nil || sku != "SKU0" {
+		t.Fatalf("OAT Everyday A2 = %q, %v; want SKU0", sku, err)
+	}
+}
+
+// TestConsolidatedTabPrefixes verifies that product line codes become valid, distinct tab
+// prefixes.
+func TestConsolidatedTabPrefixes(t *testing.T) {
+	t.Parallel()
+
+	prefixes, err := consolidatedTabPrefixes([]string{"BAS", "A/B:C", strings.Repeat("X", 40)})
+	if err != nil {
+		t.Fatalf("consolidatedTabPrefixes returned error: %v", err)
+	}
+	if prefixes["BAS"] != "BAS " || prefixes["A/B:C"] != "ABC " {
+		t.Fatalf("prefixes = %q", prefixes)
+	}
+	if long := prefixes[strings.Repeat("X", 40)]; len(long+dataInsightsSheetName) > maxSheetNameLength {
+		t.Fatalf("prefix %q makes a tab name longer than %d characters", long, maxSheetNameLength)
+	}
+
+	if _, err := consolidatedTabPrefixes([]string{"A:B", "AB"}); err == nil {
+		t.Fatal("consolidatedTabPrefixes returned nil for product lines sharing a prefix")
+	}
+}
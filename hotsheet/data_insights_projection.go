@@ -8,37 +8,18 @@ import (
 )
 
 // occasionDateInfo captures how an occasion should be displayed, ordered, and projected.
+//
+// CurrentSelling and TotalSelling are the elapsed and full length of the occasion's selling
+// windows, in the unit its calendar entry is measured in.
 type occasionDateInfo struct {
-	Display             string
-	Month               time.Month
-	Day                 int
-	SortKey             int
-	Complete            bool
-	TargetMonthsThrough float64
-}
-
-// dataInsightsDateMap maps normalized occasion names to their display text and calendar metadata.
-var dataInsightsDateMap = map[string]occasionDateInfo{
-	// Valentine's Day cards sell in two separate windows, but we still anchor the occasion to Feb 14
-	// so the event keeps its normal calendar identity while projection logic handles the split season.
-	"VALENTINE'S DAY":   {Display: "February 14", Month: time.February, Day: 14, SortKey: 214},
-	"VALENTINES DAY":    {Display: "February 14", Month: time.February, Day: 14, SortKey: 214},
-	"ST PATRICKS DAY":   {Display: "March 17", Month: time.March, Day: 17, SortKey: 317},
-	"ST. PATRICK'S DAY": {Display: "March 17", Month: time.March, Day: 17, SortKey: 317},
-	"EASTER":            {Display: "April 5", Month: time.April, Day: 4, SortKey: 405},
-	"MOTHER'S DAY":      {Display: "May 10", Month: time.May, Day: 10, SortKey: 510},
-	"MOTHERS DAY":       {Display: "May 10", Month: time.May, Day: 10, SortKey: 510},
-	"GRADUATION":        {Display: "mid-June", Month: time.June, Day: 15, SortKey: 615},
-	"FATHER'S DAY":      {Display: "June 21", Month: time.June, Day: 21, SortKey: 621},
-	"FATHERS DAY":       {Display: "June 21", Month: time.June, Day: 21, SortKey: 621},
-	"INDEPENDENCE DAY":  {Display: "July 4", Month: time.July, Day: 4, SortKey: 704},
-	"HALLOWEEN":         {Display: "October 31", Month: time.October, Day: 31, SortKey: 1031},
-	"VETERAN'S DAY":     {Display: "November 11", Month: time.November, Day: 11, SortKey: 1111},
-	"VETERANS DAY":      {Display: "November 11", Month: time.November, Day: 11, SortKey: 1111},
-	"THANKSGIVING":      {Display: "November 26", Month: time.November, Day: 26, SortKey: 1126},
-	"HANUKKAH":          {Display: "December 5", Month: time.December, Day: 5, SortKey: 1205},
-	"HOLIDAY":           {Display: "December 25", Month: time.December, Day: 25, SortKey: 1225},
-	"CHRISTMAS":         {Display: "December 25", Month: time.December, Day: 25, SortKey: 1225},
+	Display        string
+	Month          time.Month
+	Day            int
+	SortKey        int
+	Started        bool
+	Complete       bool
+	CurrentSelling float64
+	TotalSelling   float64
 }
 
 // dataInsightsSeasonTotalYoYDisplay derives the section-total text for the rightmost
@@ -90,41 +71,19 @@ func otherProductsClassTotalYoYDisplay(totalProjected, totalPY float64, rows []d
 
 // projectDataInsightsRow centralizes the seasonal projection rules so both card and
 // non-card Data Insights rows use the same date metadata, projected sales, and rightmost-column
-// year-over-year display text.
-func projectDataInsightsRow(section, occasion string, dollarSoldYTD, dollarSoldPY, currentMonthsThrough float64, now time.Time) (occasionDateInfo, float64, string) {
-	dateInfo := dataInsightsDateInfo(section, occasion, now)
+// year-over-year display text. Seasonal occasions are projected across their selling windows
+// from calendar; a nil calendar uses the built-in one.
+func projectDataInsightsRow(calendar *holidayCalendar, section, occasion string, dollarSoldYTD, dollarSoldPY, currentMonthsThrough float64, now time.Time) (occasionDateInfo, float64, string) {
+	dateInfo := dataInsightsDateInfo(calendar, section, occasion, now)
 
 	if section == "Spring" || section == "Winter" {
-		// Seasonal items are projected only up to their typical selling window.
-		if isValentinesOccasion(occasion) {
-			// Valentine's Day is the one split-season exception: it sells in an early-year
-			// window and again near the end of the year, so we project against both windows.
-			currentSellingDays, totalSellingDays, complete := valentinesProjectionWindow(now)
-			projected := dollarSoldYTD * (totalSellingDays / currentSellingDays)
-			return dateInfo, projected, formatSeasonStatusYoY(projected, dollarSoldPY, true, complete)
-		}
-		if section == "Winter" {
-			// Winter items are treated as selling from June 15 forward, which matches the
-			// existing card logic and avoids projecting winter holidays from the off-season.
-			seasonStart := time.Date(now.Year(), time.June, 15, 0, 0, 0, 0, now.Location())
-			if now.Before(seasonStart) {
-				projected := dollarSoldYTD
-				return dateInfo, projected, formatSeasonStatusYoY(projected, dollarSoldPY, false, dateInfo.Complete)
-			}
-			if dateInfo.Complete {
-				projected := dollarSoldYTD
-				return dateInfo, projected, formatSeasonStatusYoY(projected, dollarSoldPY, true, dateInfo.Complete)
-			}
-			currentSellingMonths := monthsThroughSinceDate(now.Year(), time.June, 15, now.Month(), now.Day(), now.Location())
-			projected := dollarSoldYTD * (dateInfo.TargetMonthsThrough / currentSellingMonths)
-			return dateInfo, projected, formatSeasonStatusYoY(projected, dollarSoldPY, true, dateInfo.Complete)
-		}
-		if dateInfo.Complete {
-			// Once the holiday has passed, stop extrapolating and keep the row at actual YTD.
+		// Seasonal items are projected only across their selling windows. Before the season
+		// opens and after it closes the row stays at actual YTD instead of extrapolating.
+		if !dateInfo.Started || dateInfo.Complete {
 			projected := dollarSoldYTD
-			return dateInfo, projected, formatSeasonStatusYoY(projected, dollarSoldPY, true, dateInfo.Complete)
+			return dateInfo, projected, formatSeasonStatusYoY(projected, dollarSoldPY, dateInfo.Started, dateInfo.Complete)
 		}
-		projected := dollarSoldYTD * (dateInfo.TargetMonthsThrough / currentMonthsThrough)
+		projected := dollarSoldYTD * (dateInfo.TotalSelling / dateInfo.CurrentSelling)
 		return dateInfo, projected, formatSeasonStatusYoY(projected, dollarSoldPY, true, dateInfo.Complete)
 	}
 
@@ -135,87 +94,30 @@ func projectDataInsightsRow(section, occasion string, dollarSoldYTD, dollarSoldP
 }
 
 // dataInsightsDateInfo returns the display and projection metadata for a data-insight occasion.
-func dataInsightsDateInfo(section, occasion string, now time.Time) occasionDateInfo {
+func dataInsightsDateInfo(calendar *holidayCalendar, section, occasion string, now time.Time) occasionDateInfo {
 	if section == "Everyday" {
-		return occasionDateInfo{Display: "N/A", SortKey: 999999, TargetMonthsThrough: 12.0}
+		return occasionDateInfo{Display: "N/A", SortKey: 999999}
 	}
 
-	// Unknown seasonal occasions fall back to a neutral display and sort after known holidays.
-	info, ok := dataInsightsDateMap[strings.ToUpper(strings.TrimSpace(occasion))]
-	if !ok {
-		return occasionDateInfo{Display: "N/A", SortKey: 999999, TargetMonthsThrough: 12.0}
+	schedule := calendar.schedule(section, occasion, now)
+	current, total, started, complete := schedule.progress(now)
+	info := occasionDateInfo{
+		Display:        schedule.Display,
+		Started:        started,
+		Complete:       complete,
+		CurrentSelling: current,
+		TotalSelling:   total,
 	}
-
-	if isValentinesOccasion(occasion) {
-		// Valentine's Day is the one seasonal occasion that is sold in two merchandising waves:
-		// an early-year run through Feb 14, then a late-year run from Nov 16 through Dec 31.
-		// We show that split directly in the sheet and keep it marked incomplete until year end,
-		// because December inventory is still part of the same selling season.
-		info.Display = "Jan 1 - Feb 14, Nov 16 - Dec 31"
-		info.Complete = !now.Before(time.Date(now.Year()+1, time.January, 1, 0, 0, 0, 0, now.Location()))
-		info.TargetMonthsThrough = monthsThroughForDate(now.Year(), info.Month, info.Day, now.Location())
+	if !schedule.Known {
+		// Occasions missing from the calendar sort after known holidays.
+		info.SortKey = 999999
 		return info
 	}
-
-	if section == "Winter" {
-		// Winter holidays don't really start selling until June 15, so treat that as the
-		// beginning of the season when calculating the projection window.
-		info.TargetMonthsThrough = monthsThroughSinceDate(now.Year(), time.June, 15, info.Month, info.Day, now.Location())
-	} else {
-		info.TargetMonthsThrough = monthsThroughForDate(now.Year(), info.Month, info.Day, now.Location())
-	}
-
-	// Treat the occasion as complete for the whole event day, not just after midnight.
-	eventDate := time.Date(now.Year(), info.Month, info.Day, 23, 59, 59, 0, now.Location())
-	info.Complete = !now.Before(eventDate)
+	info.Month, info.Day = schedule.Date.Month(), schedule.Date.Day()
+	info.SortKey = int(info.Month)*100 + info.Day
 	return info
 }
 
-// isValentinesOccasion centralizes the occasion matching so both spelling variants
-// take the same split-window projection path.
-func isValentinesOccasion(occasion string) bool {
-	switch strings.ToUpper(strings.TrimSpace(occasion)) {
-	case "VALENTINE'S DAY", "VALENTINES DAY":
-		return true
-	default:
-		return false
-	}
-}
-
-// valentinesProjectionWindow returns the active and total selling-day counts for the
-// split Valentine's season so projections can scale against the portion of the year
-// that is actually on sale instead of assuming the occasion sells all year.
-func valentinesProjectionWindow(now time.Time) (currentSellingDays float64, totalSellingDays float64, complete bool) {
-	year := now.Year()
-	loc := now.Location()
-
-	firstStart := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
-	firstEnd := time.Date(year, time.February, 14, 23, 59, 59, 0, loc)
-	secondStart := time.Date(year, time.November, 16, 0, 0, 0, 0, loc)
-	yearEnd := time.Date(year+1, time.January, 1, 0, 0, 0, 0, loc)
-
-	firstWindowDays := float64(firstEnd.YearDay() - firstStart.YearDay() + 1)
-	secondWindowDays := float64(time.Date(year, time.December, 31, 0, 0, 0, 0, loc).YearDay() - secondStart.YearDay() + 1)
-	totalSellingDays = firstWindowDays + secondWindowDays
-	complete = !now.Before(yearEnd)
-
-	switch {
-	case now.Before(firstStart):
-		currentSellingDays = 1
-	case !now.After(firstEnd):
-		currentSellingDays = float64(now.YearDay() - firstStart.YearDay() + 1)
-	case now.Before(secondStart):
-		currentSellingDays = firstWindowDays
-	default:
-		currentSellingDays = firstWindowDays + float64(now.YearDay()-secondStart.YearDay()+1)
-	}
-
-	if currentSellingDays <= 0 {
-		currentSellingDays = 1
-	}
-	return currentSellingDays, totalSellingDays, complete
-}
-
 // monthsThroughSinceDate returns the month progress between two calendar dates.
 func monthsThroughSinceDate(year int, startMonth time.Month, startDay int, endMonth time.Month, endDay int, loc *time.Location) float64 {
 	monthsThrough := monthsThroughForDate(year, endMonth, endDay, loc) - monthsThroughForDate(year, startMonth, startDay, loc)
@@ -34,14 +34,45 @@ type dataInsightsGroup struct {
 	Date                string
 	occasionDateSortKey int
 	complete            bool
-	TargetMonthsThrough float64
 	DollarSoldYTD       float64
 	DollarSoldPY        float64
+	// observedProjected sums the group's everyday items projected at their observed weekly pace,
+	// or annualized for items without one, and observed is set when any item had a pace.
+	observedProjected float64
+	observed          bool
+}
+
+// add adds e's sales to the group. With trends, an everyday item's observed projection is added
+// as well.
+func (g *dataInsightsGroup) add(e *inventoryEntry, trends *productLineTrends, currentMonthsThrough float64, now time.Time) {
+	g.DollarSoldYTD += e.DollarSoldYTD
+	g.DollarSoldPY += e.DollarSoldPY
+	if trends == nil || g.Section != "Everyday" {
+		return
+	}
+	projected, ok := trends.projectedDollarSales(e, now)
+	if !ok {
+		projected = e.DollarSoldYTD * (12.0 / currentMonthsThrough)
+	}
+	g.observedProjected += projected
+	g.observed = g.observed || ok
+}
+
+// project returns the group's date metadata, projected sales, and rightmost-column text. Everyday
+// groups with an observed pace use it in place of the annualized projection.
+func (g *dataInsightsGroup) project(calendar *holidayCalendar, currentMonthsThrough float64, now time.Time) (occasionDateInfo, float64, string) {
+	dateInfo, projected, yoyDisplay := projectDataInsightsRow(calendar, g.Section, g.Occasion, g.DollarSoldYTD, g.DollarSoldPY, currentMonthsThrough, now)
+	if g.observed {
+		projected = g.observedProjected
+		yoyDisplay = formatYoYFromProjectedSales(projected, g.DollarSoldPY)
+	}
+	return dateInfo, projected, yoyDisplay
 }
 
 // buildDataInsightsRows groups Counter Cards into the seasonal sections used by the
-// Data Insights sheet, preserving the existing holiday, date, and projection rules.
-func buildDataInsightsRows(entries []*inventoryEntry, currentMonthsThrough float64, now time.Time) map[string][]dataInsightsRow {
+// Data Insights sheet, preserving the existing holiday, date, and projection rules. Everyday rows
+// use the observed weekly pace from trends when they have one.
+func buildDataInsightsRows(entries []*inventoryEntry, calendar *holidayCalendar, trends *productLineTrends, currentMonthsThrough float64, now time.Time) map[string][]dataInsightsRow {
 	groups := make(map[string]*dataInsightsGroup)
 
 	for _, e := range entries {
@@ -52,8 +83,8 @@ func buildDataInsightsRows(entries []*inventoryEntry, currentMonthsThrough float
 
 		// Normalize occasion names so common variants collapse into the same grouped row.
 		occasion := normalizeDataInsightsOccasion(e.Occasion)
-		section := mapOccasion(occasion)
-		dateInfo := dataInsightsDateInfo(section, occasion, now)
+		section := entrySeason(e)
+		dateInfo := dataInsightsDateInfo(calendar, section, occasion, now)
 		// Use the normalized occasion plus section so variants collapse into one rollup bucket.
 		groupKey := section + "|" + strings.ToUpper(occasion)
 
@@ -65,18 +96,16 @@ func buildDataInsightsRows(entries []*inventoryEntry, currentMonthsThrough float
 				Date:                dateInfo.Display,
 				occasionDateSortKey: dateInfo.SortKey,
 				complete:            dateInfo.Complete,
-				TargetMonthsThrough: dateInfo.TargetMonthsThrough,
 			}
 			groups[groupKey] = group
 		}
 
-		group.DollarSoldYTD += e.DollarSoldYTD
-		group.DollarSoldPY += e.DollarSoldPY
+		group.add(e, trends, currentMonthsThrough, now)
 	}
 
 	rowsBySection := newDataInsightsRowsBySection()
 	for _, group := range groups {
-		dateInfo, projected, yoyDisplay := projectDataInsightsRow(group.Section, group.Occasion, group.DollarSoldYTD, group.DollarSoldPY, currentMonthsThrough, now)
+		dateInfo, projected, yoyDisplay := group.project(calendar, currentMonthsThrough, now)
 		row := dataInsightsRow{
 			Section:             group.Section,
 			Occasion:            group.Occasion,
@@ -110,7 +139,7 @@ func buildDataInsightsRows(entries []*inventoryEntry, currentMonthsThrough float
 // buildOtherProductsDataInsightsRows groups non-card products by class and occasion, then
 // returns the rows keyed by class so the sheet can render one table per class while reusing
 // the same holiday, date, and projection rules used by the card section.
-func buildOtherProductsDataInsightsRows(entries []*inventoryEntry, currentMonthsThrough float64, now time.Time) map[string][]dataInsightsRow {
+func buildOtherProductsDataInsightsRows(entries []*inventoryEntry, calendar *holidayCalendar, trends *productLineTrends, currentMonthsThrough float64, now time.Time) map[string][]dataInsightsRow {
 	groups := make(map[string]*dataInsightsGroup)
 
 	for _, e := range entries {
@@ -120,8 +149,8 @@ func buildOtherProductsDataInsightsRows(entries []*inventoryEntry, currentMonths
 
 		classDesc := normalizeDataInsightsClassDescription(e)
 		occasion := normalizeDataInsightsOccasion(e.Occasion)
-		section := mapOccasion(occasion)
-		dateInfo := dataInsightsDateInfo(section, occasion, now)
+		section := entrySeason(e)
+		dateInfo := dataInsightsDateInfo(calendar, section, occasion, now)
 		// Keep class and occasion in the grouping key so each class can keep one row per
 		// holiday date instead of collapsing every seasonal occasion into a single total.
 		groupKey := strings.ToUpper(classDesc) + "|" + section + "|" + strings.ToUpper(occasion)
@@ -135,18 +164,16 @@ func buildOtherProductsDataInsightsRows(entries []*inventoryEntry, currentMonths
 				Date:                dateInfo.Display,
 				occasionDateSortKey: dateInfo.SortKey,
 				complete:            dateInfo.Complete,
-				TargetMonthsThrough: dateInfo.TargetMonthsThrough,
 			}
 			groups[groupKey] = group
 		}
 
-		group.DollarSoldYTD += e.DollarSoldYTD
-		group.DollarSoldPY += e.DollarSoldPY
+		group.add(e, trends, currentMonthsThrough, now)
 	}
 
 	rowsByClass := make(map[string][]dataInsightsRow)
 	for _, group := range groups {
-		dateInfo, projected, yoyDisplay := projectDataInsightsRow(group.Section, group.Occasion, group.DollarSoldYTD, group.DollarSoldPY, currentMonthsThrough, now)
+		dateInfo, projected, yoyDisplay := group.project(calendar, currentMonthsThrough, now)
 		row := dataInsightsRow{
 			Section:             group.Section,
 			Class:               group.Class,
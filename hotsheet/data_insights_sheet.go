@@ -45,16 +45,17 @@ func dataInsightsTableEndCol(startCol string, columnCount int) (string, error) {
 	return cols[columnCount-1], nil
 }
 
-// writeDataInsightsSheet creates the "Data Insights" worksheet, with Counter Cards grouped by
-// seasonal section on the left and Other Products grouped into one table per class on the right.
-func writeDataInsightsSheet(f *excelize.File, entries []*inventoryEntry) error {
-	now := time.Now()
+// writeDataInsightsSheet creates the Data Insights worksheet named sheetName, with Counter Cards
+// grouped by seasonal section on the left and Other Products grouped into one table per class on
+// the right. Seasonal rows take their dates and selling windows from calendar, and sales pace and
+// stockout projections are measured from now. With trends, everyday rows are projected at the
+// weekly pace observed since the trend baseline instead of annualizing YTD sales.
+func writeDataInsightsSheet(f *excelize.File, sheetName string, entries []*inventoryEntry, calendar *holidayCalendar, trends *productLineTrends, now time.Time) error {
 	currentMonthsThrough := currentMonthsThrough(now)
 	// Use the current month progress to annualize in-progress rows.
-	rowsBySection := buildDataInsightsRows(entries, currentMonthsThrough, now)
-	otherRowsByClass := buildOtherProductsDataInsightsRows(entries, currentMonthsThrough, now)
+	rowsBySection := buildDataInsightsRows(entries, calendar, trends, currentMonthsThrough, now)
+	otherRowsByClass := buildOtherProductsDataInsightsRows(entries, calendar, trends, currentMonthsThrough, now)
 
-	sheetName := dataInsightsSheetName
 	if _, err := f.NewSheet(sheetName); err != nil {
 		return fmt.Errorf("failed to create %s sheet: %w", sheetName, err)
 	}
@@ -10,12 +10,17 @@ import (
 )
 
 // TestValentinesProjectionWindow verifies the split-window selling-day math used for
-// Valentine's Day projections.
+// Valentine's Day projections by the built-in calendar.
 func TestValentinesProjectionWindow(t *testing.T) {
 	t.Parallel()
 
+	valentinesProgress := func(now time.Time) (float64, float64, bool) {
+		current, total, _, complete := builtinHolidayCalendar.schedule("Spring", "Valentine's Day", now).progress(now)
+		return current, total, complete
+	}
+
 	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
-	current, total, complete := valentinesProjectionWindow(now)
+	current, total, complete := valentinesProgress(now)
 
 	if current != 45 {
 		t.Fatalf("expected 45 current selling days before the November window, got %v", current)
@@ -27,7 +32,7 @@ func TestValentinesProjectionWindow(t *testing.T) {
 		t.Fatal("expected Valentine's Day to remain incomplete before year end")
 	}
 
-	current, total, complete = valentinesProjectionWindow(time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC))
+	current, total, complete = valentinesProgress(time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC))
 	if current != total {
 		t.Fatalf("expected current selling days to equal the full window on Dec 31, got %v vs %v", current, total)
 	}
@@ -49,7 +54,7 @@ func TestBuildDataInsightsRowsValentinesProjection(t *testing.T) {
 	}}
 
 	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
-	rowsBySection := buildDataInsightsRows(entries, currentMonthsThrough(now), now)
+	rowsBySection := buildDataInsightsRows(entries, nil, nil, currentMonthsThrough(now), now)
 	rows := rowsBySection["Spring"]
 	if len(rows) != 1 {
 		t.Fatalf("expected one Spring row, got %d", len(rows))
@@ -68,7 +73,7 @@ func TestBuildDataInsightsRowsValentinesProjection(t *testing.T) {
 		t.Fatalf("expected in-progress status before year end, got %q", row.YoYDisplay)
 	}
 
-	rowsBySection = buildDataInsightsRows(entries, currentMonthsThrough(time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC)), time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC))
+	rowsBySection = buildDataInsightsRows(entries, nil, nil, currentMonthsThrough(time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC)), time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC))
 	row = rowsBySection["Spring"][0]
 	if diff := math.Abs(row.ProjectedDollar - 100.0); diff > 1e-9 {
 		t.Fatalf("expected projected sales to match YTD at the end of the season, got %.9f", row.ProjectedDollar)
@@ -78,8 +83,8 @@ func TestBuildDataInsightsRowsValentinesProjection(t *testing.T) {
 	}
 }
 
-// TestGraduationProjectionWindow confirms a normal spring holiday uses its holiday date
-// and flips to complete after the event day.
+// TestGraduationProjectionWindow confirms a normal spring holiday uses its holiday date, projects
+// from the start of the year, and flips to complete after the event day.
 func TestGraduationProjectionWindow(t *testing.T) {
 	t.Parallel()
 
@@ -91,7 +96,7 @@ func TestGraduationProjectionWindow(t *testing.T) {
 	}}
 
 	beforeCutoff := time.Date(2026, time.June, 14, 12, 0, 0, 0, time.UTC)
-	rowsBySection := buildDataInsightsRows(entries, currentMonthsThrough(beforeCutoff), beforeCutoff)
+	rowsBySection := buildDataInsightsRows(entries, nil, nil, currentMonthsThrough(beforeCutoff), beforeCutoff)
 	rows := rowsBySection["Spring"]
 	if len(rows) != 1 {
 		t.Fatalf("expected one Spring row, got %d", len(rows))
@@ -104,9 +109,15 @@ func TestGraduationProjectionWindow(t *testing.T) {
 	if !strings.HasPrefix(row.YoYDisplay, "IN PROGRESS:") {
 		t.Fatalf("expected Graduation to be in progress before June 15, got %q", row.YoYDisplay)
 	}
+	// Spring occasions are measured from the start of the year: 5 15/30 months through the
+	// holiday over 5 14/30 months through today.
+	expectedProjected := 100 * (5 + 15.0/30) / (5 + 14.0/30)
+	if diff := math.Abs(row.ProjectedDollar - expectedProjected); diff > 1e-9 {
+		t.Fatalf("expected Graduation projection %.6f, got %.6f", expectedProjected, row.ProjectedDollar)
+	}
 
 	afterCutoff := time.Date(2026, time.June, 16, 12, 0, 0, 0, time.UTC)
-	rowsBySection = buildDataInsightsRows(entries, currentMonthsThrough(afterCutoff), afterCutoff)
+	rowsBySection = buildDataInsightsRows(entries, nil, nil, currentMonthsThrough(afterCutoff), afterCutoff)
 	row = rowsBySection["Spring"][0]
 	if row.Date != "mid-June" {
 		t.Fatalf("expected Graduation to still display as mid-June, got %q", row.Date)
@@ -129,7 +140,7 @@ func TestBuildDataInsightsRowsWinterProjectionStartsJune15(t *testing.T) {
 	}}
 
 	beforeSeason := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
-	rowsBySection := buildDataInsightsRows(entries, currentMonthsThrough(beforeSeason), beforeSeason)
+	rowsBySection := buildDataInsightsRows(entries, nil, nil, currentMonthsThrough(beforeSeason), beforeSeason)
 	rows := rowsBySection["Winter"]
 	if len(rows) != 1 {
 		t.Fatalf("expected one Winter row, got %d", len(rows))
@@ -150,7 +161,7 @@ func TestBuildDataInsightsRowsWinterProjectionStartsJune15(t *testing.T) {
 	}
 
 	inSeason := time.Date(2026, time.September, 1, 12, 0, 0, 0, time.UTC)
-	rowsBySection = buildDataInsightsRows(entries, currentMonthsThrough(inSeason), inSeason)
+	rowsBySection = buildDataInsightsRows(entries, nil, nil, currentMonthsThrough(inSeason), inSeason)
 	row = rowsBySection["Winter"][0]
 	expectedProjected := 100.0 * (monthsThroughSinceDate(2026, time.June, 15, time.December, 25, time.UTC) / monthsThroughSinceDate(2026, time.June, 15, time.September, 1, time.UTC))
 	if diff := math.Abs(row.ProjectedDollar - expectedProjected); diff > 1e-9 {
@@ -178,7 +189,7 @@ func TestBuildOtherProductsDataInsightsRowsGroupedByClass(t *testing.T) {
 	}
 
 	now := time.Date(2026, time.September, 1, 12, 0, 0, 0, time.UTC)
-	rowsByClass := buildOtherProductsDataInsightsRows(entries, currentMonthsThrough(now), now)
+	rowsByClass := buildOtherProductsDataInsightsRows(entries, nil, nil, currentMonthsThrough(now), now)
 
 	if got := len(rowsByClass); got != 6 {
 		t.Fatalf("expected six class buckets, got %d", got)
@@ -259,7 +270,7 @@ func TestWriteDataInsightsSheetGroupsOtherProductsByClass(t *testing.T) {
 		{RawClassDesc: "Alpha Everyday", DollarSoldYTD: 60, DollarSoldPY: 50},
 	}
 
-	if err := writeDataInsightsSheet(f, entries); err != nil {
+	if err := writeDataInsightsSheet(f, dataInsightsSheetName, entries, nil, nil, time.Now()); err != nil {
 		t.Fatalf("writeDataInsightsSheet returned error: %v", err)
 	}
 
@@ -298,3 +309,37 @@ func TestWriteDataInsightsSheetGroupsOtherProductsByClass(t *testing.T) {
 		t.Fatalf("expected G18 to contain the second Napkins occasion, got %q (err=%v)", got, err)
 	}
 }
+
+// TestBuildDataInsightsRowsUsesObservedWeeklySales verifies that everyday rows are projected at
+// the weekly pace observed since the trend baseline, with items missing from the baseline still
+// annualized.
+func TestBuildDataInsightsRowsUsesObservedWeeklySales(t *testing.T) {
+	t.Parallel()
+
+	entries := []*inventoryEntry{
+		{SKU: "SKU0", RawClassDesc: "Counter Cards", Occasion: "Birthday", Season: "Everyday", DollarSoldYTD: 160, DollarSoldPY: 400},
+		{SKU: "SKU1", RawClassDesc: "Counter Cards", Occasion: "Birthday", Season: "Everyday", DollarSoldYTD: 50, DollarSoldPY: 100},
+	}
+	baseline := &historySnapshot{Date: "20261109", Items: []historyItem{{SKU: "SKU0", DollarSoldYTD: 100}}}
+	now := time.Date(2026, time.November, 23, 12, 0, 0, 0, time.UTC)
+	monthsThrough := currentMonthsThrough(now)
+
+	rows := buildDataInsightsRows(entries, nil, newProductLineTrends(baseline, now), monthsThrough, now)["Everyday"]
+	if len(rows) != 1 {
+		t.Fatalf("expected one Everyday row, got %d", len(rows))
+	}
+	// SKU0 sold $60 in two weeks; SKU1 has no baseline and keeps the annualized projection.
+	weeksLeft := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC).Sub(now).Hours() / 24 / 7
+	expected := 160 + 30*weeksLeft + 50*12/monthsThrough
+	if diff := math.Abs(rows[0].ProjectedDollar - expected); diff > 1e-9 {
+		t.Fatalf("expected projected sales %.9f, got %.9f", expected, rows[0].ProjectedDollar)
+	}
+	if want := formatYoYFromProjectedSales(expected, 500); rows[0].YoYDisplay != want {
+		t.Fatalf("expected YoY %q, got %q", want, rows[0].YoYDisplay)
+	}
+
+	annualized := buildDataInsightsRows(entries, nil, nil, monthsThrough, now)["Everyday"][0]
+	if diff := math.Abs(annualized.ProjectedDollar - 210*12/monthsThrough); diff > 1e-9 {
+		t.Fatalf("expected annualized sales without trends, got %.9f", annualized.ProjectedDollar)
+	}
+}
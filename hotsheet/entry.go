@@ -1,5 +1,11 @@
 package hotsheet
 
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
 // inventoryEntry represents a single inventory item.
 type inventoryEntry struct {
 	SKU         string
@@ -9,11 +15,8 @@ type inventoryEntry struct {
 	RawClassDesc string
 	Status       string
 	OnHand       int
-	// Per-PO details from the PO report
-	PONum1         string
-	OnPO1          int
-	PONum2         string
-	OnPO2          int
+	// POs holds every open PO line found for the SKU in the PO report, in report order.
+	POs            []poLine
 	OnPO           int
 	OnSO           int
 	OnBO           int
@@ -24,10 +27,50 @@ type inventoryEntry struct {
 	IssuedPY       int
 	Foil           string
 	Occasion       string
-	Description    string
-	UPC            string
+	// Season is the standard sheet (Everyday, Winter, or Spring) the occasion mapped to at import.
+	Season      string
+	Description string
+	UPC         string
 	// Additional fields: royalty and dollar sales (added for new report columns)
 	RoyaltyCode   string
 	DollarSoldYTD float64
 	DollarSoldPY  float64
 }
+
+// poLine is one open purchase-order line for an item in the PO report.
+type poLine struct {
+	Number string
+	Status string
+	Qty    int
+	// ExpectedDate is the required/expected date from the PO report, or the zero time when the
+	// report did not give one.
+	ExpectedDate time.Time
+}
+
+// sortedPOLines returns the entry's PO lines ordered by expected date, with undated lines last
+// and PO number breaking ties. The entry's own slice keeps report order.
+func sortedPOLines(e *inventoryEntry) []poLine {
+	lines := append([]poLine(nil), e.POs...)
+	slices.SortStableFunc(lines, func(a, b poLine) int {
+		return comparePOLines(e.SKU, a, e.SKU, b)
+	})
+	return lines
+}
+
+// comparePOLines orders PO line a of item skuA against line b of item skuB: by expected date,
+// with undated lines last, then by item code and PO number.
+func comparePOLines(skuA string, a poLine, skuB string, b poLine) int {
+	if a.ExpectedDate.IsZero() != b.ExpectedDate.IsZero() {
+		if a.ExpectedDate.IsZero() {
+			return 1
+		}
+		return -1
+	}
+	if c := a.ExpectedDate.Compare(b.ExpectedDate); c != 0 {
+		return c
+	}
+	if c := strings.Compare(skuA, skuB); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Number, b.Number)
+}
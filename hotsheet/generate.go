@@ -1,8 +1,16 @@
 package hotsheet
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Fepozopo/bsc-hotsheet-update/helpers"
@@ -10,11 +18,11 @@ import (
 
 // Progress describes a user-visible generation milestone.
 //
-// Progress is intentionally coarse-grained: generation does not currently know
-// the exact amount of work required for every Excel row and cell ahead of time,
-// so the reported percentage is based on major pipeline phases plus completed
-// product-line workbooks. That gives the GUI a determinate bar that reflects
-// real completed work without coupling the UI to every low-level writer loop.
+// Progress is based on major pipeline phases. While the source reports are
+// read, the percentage follows the share of report rows read so far; while
+// hotsheets are written it follows completed product-line workbooks. That gives
+// the GUI a determinate bar that reflects real completed work without coupling
+// the UI to every low-level writer loop.
 type Progress struct {
 	// Percent is the overall completion percentage, clamped by the generator to
 	// the inclusive range 0..100 before it is reported to callers.
@@ -27,72 +35,604 @@ type Progress struct {
 
 // ProgressCallback receives generation progress updates.
 //
-// Callbacks are invoked synchronously from the generation goroutine. Callers
-// that update UI state should marshal the Progress value onto their UI thread
-// instead of mutating UI-owned state directly.
+// Callbacks are invoked synchronously from the generation goroutine, never from
+// the workers that build workbooks, so updates arrive in order and Percent never
+// goes down. Callers that update UI state should marshal the Progress value onto
+// their UI thread instead of mutating UI-owned state directly.
 type ProgressCallback func(Progress)
 
+// InputOptions selects how the source reports are read.
+//
+// The inventory and PO reports may be XLSX workbooks or CSV/TSV exports; the format is chosen
+// from the file extension. The sheet fields only apply to workbooks and are ignored for
+// delimited files.
+type InputOptions struct {
+	// InventorySheet selects the inventory worksheet by name or 1-based index. Empty uses
+	// Sheet1 when present and the first sheet otherwise.
+	InventorySheet string
+	// POSheet selects the PO worksheet the same way.
+	POSheet string
+	// OccasionConfig is the occasion mapping file. Empty uses occasions.json in the app's folder
+	// of the user config directory; when that file does not exist the built-in mapping is used.
+	OccasionConfig string
+	// CalendarConfig is the holiday and selling-window calendar file, found the same way as
+	// OccasionConfig (calendar.json).
+	CalendarConfig string
+	// SettingsConfig is the MTO threshold, fill, and season-length settings file, found the same
+	// way (settings.json).
+	SettingsConfig string
+	// ClassRulesConfig is the Class column prefix rules file, found the same way
+	// (class_rules.json).
+	ClassRulesConfig string
+	// HistoryDir is the snapshot history folder: every run saves each product line's inventory
+	// data there and measures the trend columns from it. Empty uses the history folder in the
+	// app's folder of the user config directory.
+	HistoryDir string
+}
+
 // Generate orchestrates the hotsheet report pipeline.
 //
 // It loads the source inventory data, merges optional PO information, groups
-// entries by product line, and writes one workbook per product line. If report is
-// non-nil, Generate reports determinate progress at major pipeline milestones
-// and after each product-line workbook is written. Passing nil disables progress
-// reporting.
-func Generate(inventoryPath, poPath, outputDir string, report ProgressCallback) ([]string, error) {
-	reportGenerationProgress(report, 0, "Starting generation...")
+// entries by product line, and writes one workbook per product line. The
+// workbooks are built concurrently by as many workers as the settings' Workers
+// value, one per CPU by default. If report is non-nil, Generate reports
+// determinate progress at major pipeline milestones and after each product-line
+// workbook is written. Passing nil disables progress reporting.
+//
+// A product line that fails does not stop the others. The returned paths list
+// every workbook that was saved, and the error joins every product line's
+// failure.
+//
+// Problems found in the source reports are returned as import issues. Each
+// hotsheet lists its own product line's issues on an Import Issues sheet, and
+// when any issues exist the full list is also saved as a standalone
+// import_issues_YYYYMMDD.xlsx workbook that is included in the returned paths.
+//
+// Deprecated: Use GenerateWithOptions, which also reports warnings and per-file stats.
+func Generate(inventoryPath, poPath, outputDir string, report ProgressCallback) ([]string, []ImportIssue, error) {
+	return GenerateWithInput(inventoryPath, poPath, outputDir, InputOptions{}, report)
+}
 
-	logger, logCloser, err := newReportLogger()
-	if err != nil {
-		return nil, err
-	}
+// GenerateWithInput runs the same pipeline as Generate, reading the source reports as described
+// by input. It takes no further positional arguments: new run options, such as the PO worksheet
+// in InputOptions.POSheet, are fields of GenerateOptions and its Input.
+//
+// Deprecated: Use GenerateWithOptions with GenerateOptions.Input set to input.
+func GenerateWithInput(inventoryPath, poPath, outputDir string, input InputOptions, report ProgressCallback) ([]string, []ImportIssue, error) {
+	return GenerateContext(context.Background(), inventoryPath, poPath, outputDir, input, report)
+}
+
+// GenerateContext runs the same pipeline as GenerateWithInput and stops once ctx is cancelled.
+// Cancellation is checked between pipeline phases, every few report rows while reading, before
+// each product-line workbook, and between a workbook's sheets. A cancelled run removes every file
+// it had already written and returns an error wrapping ctx's error, so callers can test it with
+// errors.Is(err, context.Canceled).
+//
+// Deprecated: Use GenerateWithOptions, which is cancelled the same way.
+func GenerateContext(ctx context.Context, inventoryPath, poPath, outputDir string, input InputOptions, report ProgressCallback) ([]string, []ImportIssue, error) {
+	result, err := GenerateWithOptions(ctx, GenerateOptions{
+		InventoryPath: inventoryPath,
+		POPath:        poPath,
+		OutputDir:     outputDir,
+		Input:         input,
+		Progress:      report,
+	})
+	return result.Paths(), result.Issues, err
+}
+
+// GenerateWithOptions runs the pipeline described by opts and stops once ctx is cancelled, as
+// GenerateContext does. The result is never nil: a failed run still reports the files it wrote,
+// the import issues, the warnings, and the timings up to the failure, and a cancelled run reports
+// no files because it removes them.
+func GenerateWithOptions(ctx context.Context, opts GenerateOptions) (*GenerateResult, error) {
+	return runGeneration(ctx, opts, false)
+}
+
+// Validate checks a run described by opts without writing anything. It loads the config files
+// and, when opts.InventoryPath is set, reads the reports. The result's Files lists the files the
+// run would write, without sizes, and the error reports config or report problems that would
+// stop the run, including existing files OverwriteFail would refuse to replace. Import issues
+// and warnings are returned in the result as GenerateWithOptions returns them.
+func Validate(ctx context.Context, opts GenerateOptions) (*GenerateResult, error) {
+	return runGeneration(ctx, opts, true)
+}
+
+// runGeneration is the pipeline behind GenerateWithOptions and Validate. A dry run stops after
+// grouping the product lines and plans the output files instead of writing them.
+func runGeneration(ctx context.Context, opts GenerateOptions, dryRun bool) (*GenerateResult, error) {
+	started := time.Now()
+	now := opts.now()
+	result := &GenerateResult{Date: now}
 	defer func() {
-		_ = logCloser.Close()
+		result.Timings.Total = time.Since(started)
 	}()
+	if err := opts.validate(); err != nil {
+		return result, err
+	}
+	if strings.TrimSpace(opts.InventoryPath) == "" && !dryRun {
+		return result, errors.New("an inventory report is required")
+	}
+	if opts.CompareWith != "" && !opts.Features.NoChanges {
+		if _, err := os.Stat(opts.CompareWith); err != nil {
+			return result, fmt.Errorf("failed to open the previous hotsheet: %w", err)
+		}
+	}
+	report := opts.Progress
+	reportGenerationProgress(report, 0, "Starting generation...")
 
-	logger.Info("hotsheet generation started", "inventoryPath", inventoryPath, "poPath", poPath, "outputDir", outputDir)
+	logger := opts.Logger
+	if logger == nil {
+		fileLogger, logCloser, err := newReportLogger(opts.LogLevel)
+		if err != nil {
+			return result, err
+		}
+		defer func() {
+			_ = logCloser.Close()
+		}()
+		logger = fileLogger
+	}
+	input := opts.Input
+	outputDir := opts.outputDir()
+
+	logger.Info("hotsheet generation started", "inventoryPath", opts.InventoryPath, "poPath", opts.POPath, "outputDir", outputDir,
+		"inventorySheet", input.InventorySheet, "poSheet", input.POSheet, "productLines", opts.ProductLines)
+
+	phaseStarted := time.Now()
+	occasions, err := resolveOccasionMapping(input.OccasionConfig, logger)
+	if err != nil {
+		logger.Error("failed to load occasion mapping", "err", err)
+		return result, err
+	}
+	calendar, err := resolveHolidayCalendar(input.CalendarConfig, logger)
+	if err != nil {
+		logger.Error("failed to load calendar", "err", err)
+		return result, err
+	}
+	settings, err := resolveSettings(input.SettingsConfig, logger)
+	if err != nil {
+		logger.Error("failed to load settings", "err", err)
+		return result, err
+	}
+	classRules, err := resolveClassPrefixRules(input.ClassRulesConfig, logger)
+	if err != nil {
+		logger.Error("failed to load class rules", "err", err)
+		return result, err
+	}
+	result.Timings.Config = time.Since(phaseStarted)
+	if ctx.Err() != nil {
+		return result, generationCancelled(ctx, nil, logger)
+	}
+	if strings.TrimSpace(opts.InventoryPath) == "" {
+		reportGenerationProgress(report, 100, "Config files are valid.")
+		logger.Info("hotsheet config files validated")
+		return result, nil
+	}
 	reportGenerationProgress(report, 5, "Loading inventory report...")
 
-	inventoryBySKU, err := loadInventoryEntries(inventoryPath, logger)
+	phaseStarted = time.Now()
+	inventoryBySKU, issues, err := loadInventoryEntries(ctx, opts.InventoryPath, input.InventorySheet, occasions, settings.Statuses, logger,
+		readStageProgress(report, 5, 30, "Loading inventory report"))
+	result.Timings.Inventory = time.Since(phaseStarted)
+	if ctx.Err() != nil {
+		return result, generationCancelled(ctx, nil, logger)
+	}
 	if err != nil {
-		return nil, err
+		return result, err
 	}
 	reportGenerationProgress(report, 30, "Inventory report loaded.")
 
-	hasPO := poPath != ""
+	hasPO := opts.POPath != ""
+	var poOnly []poOnlyItem
 	if hasPO {
 		reportGenerationProgress(report, 35, "Merging PO report...")
-		if err := mergePOData(poPath, inventoryBySKU, logger); err != nil {
+		phaseStarted = time.Now()
+		poResult, err := mergePOData(ctx, opts.POPath, input.POSheet, inventoryBySKU, logger,
+			readStageProgress(report, 35, 45, "Merging PO report"))
+		result.Timings.PO = time.Since(phaseStarted)
+		issues = append(issues, poResult.Issues...)
+		poOnly = poResult.POOnly
+		if err != nil && ctx.Err() == nil {
+			// A partly read report would flag every item past the failure as a mismatch, so the
+			// hotsheets are written as if no PO report was given.
 			logger.Error("failed to merge PO report", "err", err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("The PO report could not be merged, so the hotsheets have no PO columns or sheets: %v", err))
+			hasPO, poOnly = false, nil
+		}
+		if ctx.Err() != nil {
+			result.Issues = issues
+			return result, generationCancelled(ctx, nil, logger)
 		}
 	}
+	sortImportIssues(issues)
+	result.Issues = issues
+	if len(issues) > 0 {
+		logger.Warn("import issues found", "count", len(issues))
+	}
 	reportGenerationProgress(report, 45, "Grouping product lines...")
 
-	entriesByProductLine := groupEntriesByProductLine(inventoryBySKU, logger)
-	outputs := make([]string, 0, len(entriesByProductLine))
-	dateStamp := currentDateStamp()
+	entriesByProductLine, missing := opts.filterProductLines(groupEntriesByProductLine(inventoryBySKU, logger))
+	for _, code := range missing {
+		logger.Warn("requested product line has no items", "productLine", code)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Product line %s has no items in the inventory report.", code))
+	}
+	dateStamp := now.Format(dateStampLayout)
 	totalProductLines := len(entriesByProductLine)
+	if dryRun {
+		files, err := opts.planOutputFiles(entriesByProductLine, issues, dateStamp)
+		for _, file := range files {
+			result.addFile(file, logger)
+		}
+		reportGenerationProgress(report, 100, "Validation complete.")
+		logger.Info("hotsheet run validated", "files", len(files), "err", err)
+		return result, err
+	}
+
+	phaseStarted = time.Now()
+	defer func() {
+		result.Timings.Workbooks = time.Since(phaseStarted)
+	}()
+	if len(issues) > 0 && !opts.Features.NoImportIssues {
+		// PO-only SKUs belong to no product line's hotsheet, so without the company-wide workbook
+		// the issues workbook lists them on its own PO Reconciliation sheet.
+		var issuesPOOnly []poOnlyItem
+		if opts.Layout != LayoutConsolidated && !opts.Features.NoPOSheets {
+			issuesPOOnly = poOnly
+		}
+		file, err := saveImportIssuesFile(importIssuesWorkbookPath(outputDir, dateStamp), issues, issuesPOOnly, opts.Overwrite)
+		if err != nil {
+			logger.Error("failed to save import issues workbook", "err", err)
+			return result, err
+		}
+		result.addFile(file, logger)
+	}
 	if totalProductLines == 0 {
 		reportGenerationProgress(report, 100, "Generation complete.")
-		logger.Info("hotsheet generation completed", "filesCreated", len(outputs), "outputDir", outputDir)
-		return outputs, nil
+		logger.Info("hotsheet generation completed", "filesCreated", len(result.Paths()), "outputDir", outputDir)
+		return result, nil
 	}
 
-	for productLine, entries := range entriesByProductLine {
-		reportGenerationProgress(report, workbookProgress(len(outputs), totalProductLines), fmt.Sprintf("Writing %s hotsheet...", productLine))
-		sortEntriesForProductLine(entries)
+	// The trends are read before this run's snapshot is saved, and the snapshot is only saved
+	// once the workbooks are done, so a cancelled run leaves the history as it was. Only the
+	// product lines whose hotsheet was written are saved, so the history matches the hotsheets.
+	var trends map[string]*productLineTrends
+	historyDir := ""
+	if !opts.Features.NoHistory {
+		historyDir, err = resolveHistoryDir(input.HistoryDir)
+		if err != nil {
+			logger.Warn("snapshot history is unavailable", "err", err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("The snapshot history is unavailable: %v", err))
+		} else {
+			var historyWarnings []string
+			trends, historyWarnings = loadProductLineTrends(historyDir, entriesByProductLine, now)
+			logger.Info("read snapshot history", "dir", historyDir, "productLinesWithTrends", len(trends))
+			result.Warnings = append(result.Warnings, historyWarnings...)
+		}
+	}
+	saveHistory := func(productLines []string) {
+		if historyDir == "" || ctx.Err() != nil {
+			return
+		}
+		for _, warning := range saveHistorySnapshots(historyDir, entriesByProductLine, productLines, now) {
+			logger.Warn("failed to save snapshot", "warning", warning)
+			result.Warnings = append(result.Warnings, warning)
+		}
+	}
 
-		outPath, err := buildProductLineWorkbook(productLine, entries, outputDir, dateStamp, hasPO, logger)
+	sheetOptions := func(productLine string) standardSheetOptions {
+		return standardSheetOptions{
+			Settings:   settings.ForProductLine(productLine),
+			ClassRules: classRules,
+			Formulas:   settings.Formulas,
+			Now:        now,
+			Trends:     trends[productLine],
+		}
+	}
+	workbookOpts := productLineWorkbookOptions{
+		hasPO:    hasPO,
+		calendar: calendar,
+		features: opts.Features,
+		now:      now,
+	}
+	// loadChanges compares a new hotsheet with its previous one. Workers call it at the same time,
+	// and a hotsheet that cannot be compared is still written, without its Changes sheet.
+	var compareMu sync.Mutex
+	var compareWarnings []string
+	loadChanges := func(defaultTemplate, fileProductLine string, entriesByProductLine map[string][]*inventoryEntry, tabPrefixes func(string) []string) *hotsheetChanges {
+		if opts.Features.NoChanges {
+			return nil
+		}
+		changes, warnings, err := opts.changesSincePrevious(defaultTemplate, fileProductLine, dateStamp, entriesByProductLine, tabPrefixes,
+			func(productLine string) LineSettings { return settings.ForProductLine(productLine) }, now)
+		name := opts.fileName(defaultTemplate, fileProductLine, dateStamp)
+		for _, warning := range warnings {
+			logger.Warn("skipped a product line when comparing with the previous hotsheet", "file", name, "warning", warning)
+		}
 		if err != nil {
-			return outputs, err
+			logger.Warn("failed to compare with the previous hotsheet", "file", name, "err", err)
+			warnings = append(warnings, fmt.Sprintf("%s has no Changes sheet because the previous hotsheet could not be read: %v", name, err))
 		}
-		outputs = append(outputs, outPath)
-		reportGenerationProgress(report, workbookProgress(len(outputs), totalProductLines), fmt.Sprintf("Created %d of %d hotsheets.", len(outputs), totalProductLines))
+		compareMu.Lock()
+		compareWarnings = append(compareWarnings, warnings...)
+		compareMu.Unlock()
+		return changes
+	}
+	if opts.Layout == LayoutConsolidated {
+		// PO-only SKUs have no product line, so only the company-wide workbook lists them.
+		workbookOpts.poOnly = poOnly
+		file, err := buildConsolidatedFile(ctx, opts, entriesByProductLine, dateStamp, workbookOpts, issues, sheetOptions, loadChanges, logger)
+		result.Warnings = append(result.Warnings, compareWarnings...)
+		if file.Path != "" {
+			result.addFile(file, logger)
+		}
+		if err == nil && !file.Skipped {
+			saveHistory(slices.Sorted(maps.Keys(entriesByProductLine)))
+		}
+		if ctx.Err() != nil {
+			cancelled := generationCancelled(ctx, result.Paths(), logger)
+			result.Files = nil
+			return result, cancelled
+		}
+		if err != nil {
+			logger.Error("failed to write consolidated hotsheet", "err", err)
+			return result, err
+		}
+		reportGenerationProgress(report, 100, "Generation complete.")
+		logger.Info("hotsheet generation completed", "filesCreated", len(result.Paths()), "outputDir", outputDir)
+		return result, nil
+	}
+
+	workers := settings.WorkerCount()
+	logger.Info("writing hotsheets", "productLines", totalProductLines, "workers", workers)
+	files, err := buildProductLineWorkbooks(ctx, entriesByProductLine, workers, report, func(productLine string, entries []*inventoryEntry) (FileResult, error) {
+		file := FileResult{
+			Path:        opts.hotsheetPath(productLine, dateStamp),
+			Kind:        FileKindHotsheet,
+			ProductLine: productLine,
+			Items:       len(entries),
+		}
+		skip, err := checkOverwrite(file.Path, opts.Overwrite)
+		if err != nil || skip {
+			file.Skipped = skip
+			return file, err
+		}
+		fileStarted := time.Now()
+		sortEntriesForProductLine(entries)
+		lineOpts := workbookOpts
+		lineOpts.issues = importIssuesForProductLine(issues, productLine)
+		lineOpts.sheets = sheetOptions(productLine)
+		// The previous hotsheet is read before this one is saved, because on a second run the
+		// same day it is the file being replaced.
+		lineOpts.changes = loadChanges(defaultFileNameTemplate, productLine, map[string][]*inventoryEntry{productLine: entries}, productLineTabPrefixes)
+		if lineOpts.changes != nil {
+			file.ComparedWith, file.Changes = lineOpts.changes.comparedWith, len(lineOpts.changes.rows)
+		}
+		if err := buildProductLineWorkbook(ctx, productLine, entries, file.Path, lineOpts, logger); err != nil {
+			return file, err
+		}
+		return savedFileResult(file, fileStarted), nil
+	})
+	slices.Sort(compareWarnings)
+	result.Warnings = append(result.Warnings, compareWarnings...)
+	var written []string
+	for _, file := range files {
+		result.addFile(file, logger)
+		if !file.Skipped {
+			written = append(written, file.ProductLine)
+		}
+	}
+	saveHistory(written)
+	if ctx.Err() != nil {
+		cancelled := generationCancelled(ctx, result.Paths(), logger)
+		result.Files = nil
+		return result, cancelled
+	}
+	if err != nil {
+		logger.Error("hotsheet generation finished with failures", "filesCreated", len(result.Paths()), "err", err)
+		return result, err
 	}
 
 	reportGenerationProgress(report, 100, "Generation complete.")
-	logger.Info("hotsheet generation completed", "filesCreated", len(outputs), "outputDir", outputDir)
-	return outputs, nil
+	logger.Info("hotsheet generation completed", "filesCreated", len(result.Paths()), "outputDir", outputDir)
+	return result, nil
+}
+
+// buildConsolidatedFile writes the consolidated workbook unless the overwrite policy keeps an
+// existing one. The returned file has no path when the policy refused to replace it.
+func buildConsolidatedFile(ctx context.Context, opts GenerateOptions, entriesByProductLine map[string][]*inventoryEntry, dateStamp string, workbookOpts productLineWorkbookOptions, issues []ImportIssue, sheetOptions func(string) standardSheetOptions, loadChanges func(string, string, map[string][]*inventoryEntry, func(string) []string) *hotsheetChanges, logger *slog.Logger) (FileResult, error) {
+	file := FileResult{Path: opts.consolidatedPath(dateStamp), Kind: FileKindConsolidated, Items: countEntries(entriesByProductLine)}
+	skip, err := checkOverwrite(file.Path, opts.Overwrite)
+	if err != nil {
+		return FileResult{}, err
+	}
+	if skip {
+		file.Skipped = true
+		return file, nil
+	}
+	started := time.Now()
+	workbookOpts.issues = issues
+	prefixes, err := consolidatedTabPrefixes(slices.Sorted(maps.Keys(entriesByProductLine)))
+	if err != nil {
+		return FileResult{}, err
+	}
+	workbookOpts.changes = loadChanges(defaultConsolidatedFileNameTemplate, consolidatedProductLineName, entriesByProductLine, func(productLine string) []string {
+		return []string{prefixes[productLine], ""}
+	})
+	if workbookOpts.changes != nil {
+		file.ComparedWith, file.Changes = workbookOpts.changes.comparedWith, len(workbookOpts.changes.rows)
+	}
+	logger.Info("writing consolidated hotsheet", "productLines", len(entriesByProductLine), "path", file.Path)
+	if err := buildConsolidatedWorkbook(ctx, entriesByProductLine, file.Path, workbookOpts, sheetOptions, opts.Progress, logger); err != nil {
+		return FileResult{}, err
+	}
+	reportGenerationProgress(opts.Progress, workbookProgress(1, 1), "Created the consolidated hotsheet.")
+	return savedFileResult(file, started), nil
+}
+
+// countEntries returns the number of items across every product line.
+func countEntries(entriesByProductLine map[string][]*inventoryEntry) int {
+	count := 0
+	for _, entries := range entriesByProductLine {
+		count += len(entries)
+	}
+	return count
+}
+
+// planOutputFiles lists the files a run would write, applying the overwrite policy to files that
+// already exist. The error joins every file OverwriteFail would refuse to replace.
+func (o GenerateOptions) planOutputFiles(entriesByProductLine map[string][]*inventoryEntry, issues []ImportIssue, dateStamp string) ([]FileResult, error) {
+	var files []FileResult
+	if len(issues) > 0 && !o.Features.NoImportIssues {
+		files = append(files, FileResult{Path: importIssuesWorkbookPath(o.outputDir(), dateStamp), Kind: FileKindImportIssues, Items: len(issues)})
+	}
+	if o.Layout == LayoutConsolidated && len(entriesByProductLine) > 0 {
+		files = append(files, FileResult{Path: o.consolidatedPath(dateStamp), Kind: FileKindConsolidated, Items: countEntries(entriesByProductLine)})
+		entriesByProductLine = nil
+	}
+	for _, productLine := range slices.Sorted(maps.Keys(entriesByProductLine)) {
+		files = append(files, FileResult{
+			Path:        o.hotsheetPath(productLine, dateStamp),
+			Kind:        FileKindHotsheet,
+			ProductLine: productLine,
+			Items:       len(entriesByProductLine[productLine]),
+		})
+	}
+	var errs []error
+	for i := range files {
+		skip, err := checkOverwrite(files[i].Path, o.Overwrite)
+		files[i].Skipped = skip
+		errs = append(errs, err)
+		if o.Features.NoChanges || skip {
+			continue
+		}
+		switch files[i].Kind {
+		case FileKindHotsheet:
+			files[i].ComparedWith, err = o.previousHotsheetPath(defaultFileNameTemplate, files[i].ProductLine, dateStamp)
+		case FileKindConsolidated:
+			files[i].ComparedWith, err = o.previousHotsheetPath(defaultConsolidatedFileNameTemplate, consolidatedProductLineName, dateStamp)
+		}
+		errs = append(errs, err)
+	}
+	return files, errors.Join(errs...)
+}
+
+// addFile records a written or skipped file, warning about skipped ones.
+func (r *GenerateResult) addFile(file FileResult, logger *slog.Logger) {
+	if file.Skipped {
+		logger.Info("output file already exists; left unchanged", "path", file.Path)
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%s already exists and was left unchanged.", file.Path))
+	}
+	r.Files = append(r.Files, file)
+}
+
+// saveImportIssuesFile writes the standalone issues workbook to path, with a PO Reconciliation
+// sheet for poOnly when it is not empty, unless the overwrite policy keeps an existing one.
+func saveImportIssuesFile(path string, issues []ImportIssue, poOnly []poOnlyItem, policy OverwritePolicy) (FileResult, error) {
+	file := FileResult{Path: path, Kind: FileKindImportIssues, Items: len(issues)}
+	skip, err := checkOverwrite(path, policy)
+	if err != nil || skip {
+		file.Skipped = skip
+		return file, err
+	}
+	started := time.Now()
+	if err := writeImportIssuesWorkbook(path, issues, poOnly); err != nil {
+		return file, err
+	}
+	return savedFileResult(file, started), nil
+}
+
+// productLineEvent tells the goroutine reporting progress that a worker started or finished the
+// product line at index.
+type productLineEvent struct {
+	index int
+	done  bool
+	file  FileResult
+	err   error
+}
+
+// generationCancelled removes the files a cancelled run already wrote and returns the error
+// GenerateContext reports for the cancellation.
+func generationCancelled(ctx context.Context, outputs []string, logger *slog.Logger) error {
+	for _, path := range outputs {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("failed to remove output of cancelled generation", "path", path, "err", err)
+		}
+	}
+	logger.Info("hotsheet generation cancelled", "filesRemoved", len(outputs))
+	return fmt.Errorf("hotsheet generation cancelled: %w", ctx.Err())
+}
+
+// buildProductLineWorkbooks runs build for every product line with up to workers running at once,
+// handing out product lines in code order. Progress is reported from the calling goroutine as
+// workbooks start and finish, so it stays ordered and only moves forward. No further product
+// lines are started once ctx is cancelled.
+//
+// A failed product line does not stop the others. The returned files are the workbooks that were
+// saved or skipped, in product line order, and the error joins every failure in the same order.
+func buildProductLineWorkbooks(ctx context.Context, entriesByProductLine map[string][]*inventoryEntry, workers int, report ProgressCallback, build func(productLine string, entries []*inventoryEntry) (FileResult, error)) ([]FileResult, error) {
+	productLines := slices.Sorted(maps.Keys(entriesByProductLine))
+	total := len(productLines)
+	workers = min(max(workers, 1), total)
+
+	jobs := make(chan int)
+	events := make(chan productLineEvent)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				productLine := productLines[i]
+				events <- productLineEvent{index: i}
+				file, err := build(productLine, entriesByProductLine[productLine])
+				events <- productLineEvent{index: i, done: true, file: file, err: err}
+			}
+		}()
+	}
+	go func() {
+	feed:
+		for i := range productLines {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(events)
+	}()
+
+	files := make([]*FileResult, total)
+	errs := make([]error, total)
+	finished, created := 0, 0
+	for event := range events {
+		productLine := productLines[event.index]
+		switch {
+		case !event.done:
+			reportGenerationProgress(report, workbookProgress(finished, total), fmt.Sprintf("Writing %s hotsheet...", productLine))
+		case event.err != nil:
+			finished++
+			errs[event.index] = event.err
+			reportGenerationProgress(report, workbookProgress(finished, total), fmt.Sprintf("Failed to write %s hotsheet.", productLine))
+		case event.file.Skipped:
+			finished++
+			files[event.index] = &event.file
+			reportGenerationProgress(report, workbookProgress(finished, total), fmt.Sprintf("Kept the existing %s hotsheet.", productLine))
+		default:
+			finished++
+			created++
+			files[event.index] = &event.file
+			reportGenerationProgress(report, workbookProgress(finished, total), fmt.Sprintf("Created %d of %d hotsheets.", created, total))
+		}
+	}
+
+	outputs := make([]FileResult, 0, total)
+	for _, file := range files {
+		if file != nil {
+			outputs = append(outputs, *file)
+		}
+	}
+	return outputs, errors.Join(errs...)
 }
 
 // reportGenerationProgress normalizes and emits a Progress update.
@@ -113,6 +653,27 @@ func reportGenerationProgress(report ProgressCallback, percent int, message stri
 	report(Progress{Percent: percent, Message: message})
 }
 
+// readStageProgress maps the fraction of a source report read into the percentage range
+// start..end and reports it with message.
+//
+// The readers call back once per row, so updates are only sent when the overall percentage or the
+// share of rows read moves by a whole percent.
+func readStageProgress(report ProgressCallback, start, end int, message string) readProgressFunc {
+	if report == nil {
+		return nil
+	}
+	lastRead := -1
+	return func(fraction float64) {
+		read := int(fraction * 100)
+		if read == lastRead {
+			return
+		}
+		lastRead = read
+		percent := start + int(float64(end-start)*fraction)
+		reportGenerationProgress(report, percent, fmt.Sprintf("%s... %d%% of rows read", message, read))
+	}
+}
+
 // workbookProgress maps completed product-line workbooks into the percentage
 // range reserved for workbook generation.
 //
@@ -131,17 +692,16 @@ func workbookProgress(completed, total int) int {
 	return workbookStart + (workbookEnd-workbookStart)*completed/total
 }
 
-// newReportLogger constructs the logger used by Generate so the orchestration layer
-// stays focused on the report pipeline itself.
-func newReportLogger() (*slog.Logger, interface{ Close() error }, error) {
-	logger, logCloser, err := helpers.CreateSlogLogger("create", "DEBUG")
+// newReportLogger constructs the log-file logger Generate uses when the caller does not supply
+// one, so the orchestration layer stays focused on the report pipeline itself. An empty level
+// logs everything.
+func newReportLogger(level string) (*slog.Logger, interface{ Close() error }, error) {
+	if strings.TrimSpace(level) == "" {
+		level = "DEBUG"
+	}
+	logger, logCloser, err := helpers.CreateSlogLogger("create", level)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create logger: %w", err)
 	}
 	return logger, logCloser, nil
 }
-
-// currentDateStamp returns the date stamp used in output filenames.
-func currentDateStamp() string {
-	return time.Now().Format("20060102")
-}
@@ -0,0 +1,285 @@
+package hotsheet
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultFileNameTemplate is the historical hotsheet file name.
+const defaultFileNameTemplate = "{productLine}_hotsheet_{date}.xlsx"
+
+// GenerateOptions describes one hotsheet generation run. Only InventoryPath is required, and
+// Validate does not need it either; every other zero value keeps the historical behavior.
+type GenerateOptions struct {
+	// InventoryPath is the Sage inventory report.
+	InventoryPath string
+	// POPath is the optional PO report.
+	POPath string
+	// OutputDir is where the files are written; empty uses the current directory.
+	OutputDir string
+	// Input selects the report worksheets and the config files.
+	Input InputOptions
+	// Progress receives progress updates; nil disables them.
+	Progress ProgressCallback
+
+	// Now returns the run's current time, which dates the output files and is the "today" the
+	// sales pace and stockout projections are measured from. Nil uses time.Now. Timings always
+	// use the real clock.
+	Now func() time.Time
+	// Logger receives the run's log. Nil writes a log file to the temp folder at LogLevel.
+	Logger *slog.Logger
+	// LogLevel is the level of that log file: DEBUG (the default), INFO, WARN, or ERROR.
+	LogLevel string
+
+	// ProductLines limits the run to these product line codes, ignoring case. Empty builds every
+	// product line.
+	ProductLines []string
+	// Layout decides whether each product line gets its own workbook or all of them share one.
+	Layout OutputLayout
+	// FileNameTemplate names each hotsheet. {productLine} and {date} (YYYYMMDD) are replaced, and
+	// .xlsx is added when missing. Empty uses "{productLine}_hotsheet_{date}.xlsx", or
+	// "hotsheet_{date}.xlsx" for a consolidated workbook, where {productLine} becomes ALL.
+	FileNameTemplate string
+	// Overwrite decides what happens to output files that already exist.
+	Overwrite OverwritePolicy
+	// Features turns optional sheets and files off.
+	Features GenerateFeatures
+	// CompareWith is the earlier hotsheet each new one is compared with on its Changes sheet. A
+	// consolidated workbook file is compared with every product line it has tabs for. A product
+	// line's hotsheet is only compared with the line its file name is for; the run's other lines
+	// are left out with a warning. A folder is searched for each hotsheet's latest earlier
+	// version: the newest file named by the same template with a date up to the run's. Empty
+	// searches the output folder.
+	CompareWith string
+}
+
+// OutputLayout decides how the product lines are spread over output workbooks.
+type OutputLayout int
+
+const (
+	// LayoutPerProductLine writes one workbook per product line, as the hotsheet always has.
+	LayoutPerProductLine OutputLayout = iota
+	// LayoutConsolidated writes one workbook for every product line: a Summary sheet with each
+	// line's totals and links, each line's Everyday, Winter, Spring, and Data Insights tabs named
+	// after the line, and company-wide Open POs, PO Reconciliation, and Import Issues sheets.
+	LayoutConsolidated
+)
+
+// OverwritePolicy decides what happens when an output file already exists.
+type OverwritePolicy int
+
+const (
+	// OverwriteReplace replaces the existing file, as the hotsheet always has.
+	OverwriteReplace OverwritePolicy = iota
+	// OverwriteSkip leaves the existing file alone and records it as skipped.
+	OverwriteSkip
+	// OverwriteFail fails the file's product line, or the import issues workbook.
+	OverwriteFail
+)
+
+// GenerateFeatures turns optional output off. The zero value writes everything.
+type GenerateFeatures struct {
+	// NoDataInsights leaves the Data Insights sheet out of every hotsheet.
+	NoDataInsights bool `json:"noDataInsights,omitempty"`
+	// NoPOSheets leaves out the Open POs and PO Reconciliation sheets, even with a PO report.
+	NoPOSheets bool `json:"noPOSheets,omitempty"`
+	// NoImportIssues leaves out the Import Issues sheets and the import issues workbook. The
+	// issues are still returned in the result.
+	NoImportIssues bool `json:"noImportIssues,omitempty"`
+	// NoChanges leaves out the Changes sheet that compares each hotsheet with its previous one.
+	NoChanges bool `json:"noChanges,omitempty"`
+	// NoHistory neither saves the run's snapshot to the history nor reads trends from it, which
+	// leaves the trend columns out and keeps the annualized Data Insights projections.
+	NoHistory bool `json:"noHistory,omitempty"`
+}
+
+// FileKind tells a hotsheet from the import issues workbook in a GenerateResult.
+type FileKind string
+
+const (
+	FileKindHotsheet     FileKind = "hotsheet"
+	FileKindConsolidated FileKind = "consolidated"
+	FileKindImportIssues FileKind = "importIssues"
+)
+
+// FileResult describes one file a run wrote or skipped.
+type FileResult struct {
+	Path string   `json:"path"`
+	Kind FileKind `json:"kind"`
+	// ProductLine is the hotsheet's product line; it is empty for the consolidated and import
+	// issues workbooks.
+	ProductLine string `json:"productLine,omitempty"`
+	// Items is the number of inventory items on a hotsheet, or of issues in the issues workbook.
+	Items int `json:"items"`
+	// Bytes is the saved file's size.
+	Bytes int64 `json:"bytes"`
+	// Duration is how long building and saving the file took.
+	Duration time.Duration `json:"duration"`
+	// Skipped is set when the file already existed and OverwriteSkip left it alone.
+	Skipped bool `json:"skipped,omitempty"`
+	// ComparedWith is the earlier hotsheet the Changes sheet compares against, and Changes is the
+	// number of items listed there. ComparedWith is empty when there was nothing to compare with.
+	ComparedWith string `json:"comparedWith,omitempty"`
+	Changes      int    `json:"changes,omitempty"`
+}
+
+// GenerateTimings records how long each phase of a run took.
+type GenerateTimings struct {
+	// Config covers reading the occasion, calendar, settings, and class rules files.
+	Config    time.Duration `json:"config"`
+	Inventory time.Duration `json:"inventory"`
+	PO        time.Duration `json:"po"`
+	// Workbooks covers the import issues workbook and every hotsheet.
+	Workbooks time.Duration `json:"workbooks"`
+	Total     time.Duration `json:"total"`
+}
+
+// GenerateResult is the outcome of a generation run. It is returned even when the run fails, with
+// whatever was written before the failure.
+type GenerateResult struct {
+	// Date is the run's date from the options' clock.
+	Date time.Time `json:"date"`
+	// Files lists the import issues workbook first, when there is one, then the hotsheets in
+	// product line order or the consolidated workbook.
+	Files []FileResult `json:"files"`
+	// Issues are the problems found in the source reports.
+	Issues []ImportIssue `json:"issues"`
+	// Warnings describe problems that did not stop the run, such as a PO report that could not be
+	// read or a requested product line without items.
+	Warnings []string        `json:"warnings"`
+	Timings  GenerateTimings `json:"timings"`
+}
+
+// Paths returns the paths of the files the run wrote, leaving out skipped files.
+func (r *GenerateResult) Paths() []string {
+	if r == nil {
+		return nil
+	}
+	var paths []string
+	for _, file := range r.Files {
+		if !file.Skipped {
+			paths = append(paths, file.Path)
+		}
+	}
+	return paths
+}
+
+// now returns the current time from the options' clock.
+func (o GenerateOptions) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// validate reports options that cannot produce a run.
+func (o GenerateOptions) validate() error {
+	if o.Layout < LayoutPerProductLine || o.Layout > LayoutConsolidated {
+		return fmt.Errorf("unknown output layout %d", o.Layout)
+	}
+	if o.FileNameTemplate != "" {
+		if o.Layout == LayoutPerProductLine && !strings.Contains(o.FileNameTemplate, "{productLine}") {
+			return fmt.Errorf("file name template %q must contain {productLine}", o.FileNameTemplate)
+		}
+		if strings.ContainsAny(o.FileNameTemplate, `/\:`) {
+			return fmt.Errorf("file name template %q must be a file name, not a path", o.FileNameTemplate)
+		}
+	}
+	if o.Overwrite < OverwriteReplace || o.Overwrite > OverwriteFail {
+		return fmt.Errorf("unknown overwrite policy %d", o.Overwrite)
+	}
+	return nil
+}
+
+// outputDir returns the folder output files are written to.
+func (o GenerateOptions) outputDir() string {
+	if strings.TrimSpace(o.OutputDir) == "" {
+		return "."
+	}
+	return o.OutputDir
+}
+
+// hotsheetPath returns the output path of a product line's hotsheet.
+func (o GenerateOptions) hotsheetPath(productLine, dateStamp string) string {
+	return o.outputPath(defaultFileNameTemplate, productLine, dateStamp)
+}
+
+// consolidatedPath returns the output path of the consolidated workbook.
+func (o GenerateOptions) consolidatedPath(dateStamp string) string {
+	return o.outputPath(defaultConsolidatedFileNameTemplate, consolidatedProductLineName, dateStamp)
+}
+
+// outputPath names a workbook from the file name template, or from defaultTemplate without one.
+func (o GenerateOptions) outputPath(defaultTemplate, productLine, dateStamp string) string {
+	return filepath.Join(o.outputDir(), o.fileName(defaultTemplate, productLine, dateStamp))
+}
+
+// fileName fills in the file name template, or defaultTemplate without one.
+func (o GenerateOptions) fileName(defaultTemplate, productLine, dateStamp string) string {
+	template := o.FileNameTemplate
+	if template == "" {
+		template = defaultTemplate
+	}
+	name := strings.NewReplacer("{productLine}", sanitizeFileName(productLine), "{date}", dateStamp).Replace(template)
+	if !strings.EqualFold(filepath.Ext(name), ".xlsx") {
+		name += ".xlsx"
+	}
+	return name
+}
+
+// filterProductLines keeps the requested product lines and returns the requested codes that have
+// no items. Without a filter every product line is kept.
+func (o GenerateOptions) filterProductLines(entriesByProductLine map[string][]*inventoryEntry) (map[string][]*inventoryEntry, []string) {
+	if len(o.ProductLines) == 0 {
+		return entriesByProductLine, nil
+	}
+	kept := make(map[string][]*inventoryEntry)
+	var missing []string
+	for _, code := range o.ProductLines {
+		code = strings.TrimSpace(code)
+		found := false
+		for productLine, entries := range entriesByProductLine {
+			if strings.EqualFold(strings.TrimSpace(productLine), code) {
+				kept[productLine] = entries
+				found = true
+			}
+		}
+		if !found && code != "" {
+			missing = append(missing, code)
+		}
+	}
+	return kept, missing
+}
+
+// checkOverwrite applies the overwrite policy to path, reporting whether the file should be
+// skipped.
+func checkOverwrite(path string, policy OverwritePolicy) (skip bool, err error) {
+	if policy == OverwriteReplace {
+		return false, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	if policy == OverwriteSkip {
+		return true, nil
+	}
+	return false, fmt.Errorf("%s already exists", path)
+}
+
+// savedFileResult fills in the size of a file that was just written.
+func savedFileResult(file FileResult, started time.Time) FileResult {
+	file.Duration = time.Since(started)
+	if info, err := os.Stat(file.Path); err == nil {
+		file.Bytes = info.Size()
+	}
+	return file
+}
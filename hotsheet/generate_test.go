@@ -0,0 +1,384 @@
+package hotsheet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestMain runs the package's tests with a config folder of their own.
+func TestMain(m *testing.M) {
+	os.Exit(testinputs.Main(m))
+}
+
+// TestBuildProductLineWorkbooksCollectsFailures verifies that the worker pool builds every product
+// line, keeps going past failures, returns the saved paths in product line order, and reports
+// progress that only moves forward.
+func TestBuildProductLineWorkbooksCollectsFailures(t *testing.T) {
+	t.Parallel()
+
+	entriesByProductLine := make(map[string][]*inventoryEntry)
+	for i := range 12 {
+		entriesByProductLine[fmt.Sprintf("PL%02d", i)] = nil
+	}
+	errBAD := errors.New("disk full")
+	var mu sync.Mutex
+	running, peak := 0, 0
+	build := func(productLine string, _ []*inventoryEntry) (FileResult, error) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		time.Sleep(time.Millisecond)
+		if productLine == "PL03" || productLine == "PL07" {
+			return FileResult{}, fmt.Errorf("failed to save %s: %w", productLine, errBAD)
+		}
+		return FileResult{Path: productLine + ".xlsx", ProductLine: productLine}, nil
+	}
+
+	var progress []Progress
+	files, err := buildProductLineWorkbooks(context.Background(), entriesByProductLine, 4, func(p Progress) {
+		progress = append(progress, p)
+	}, build)
+	outputs := (&GenerateResult{Files: files}).Paths()
+
+	want := []string{"PL00.xlsx", "PL01.xlsx", "PL02.xlsx", "PL04.xlsx", "PL05.xlsx", "PL06.xlsx", "PL08.xlsx", "PL09.xlsx", "PL10.xlsx", "PL11.xlsx"}
+	if !slices.Equal(outputs, want) {
+		t.Fatalf("outputs = %q, want %q", outputs, want)
+	}
+	if !errors.Is(err, errBAD) || !strings.Contains(err.Error(), "PL03") || !strings.Contains(err.Error(), "PL07") {
+		t.Fatalf("err = %v, want both failures", err)
+	}
+	if peak > 4 {
+		t.Fatalf("%d workbooks were built at once, want at most 4", peak)
+	}
+
+	if len(progress) != 24 {
+		t.Fatalf("got %d progress updates, want a start and a finish per product line", len(progress))
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i].Percent < progress[i-1].Percent {
+			t.Fatalf("progress went from %d%% to %d%%", progress[i-1].Percent, progress[i].Percent)
+		}
+	}
+	if last := progress[len(progress)-1]; last.Percent != workbookProgress(12, 12) {
+		t.Fatalf("last progress = %+v, want %d%%", last, workbookProgress(12, 12))
+	}
+}
+
+// TestGenerateContextCancelRemovesOutputs verifies that cancelling a run stops it before the
+// remaining product lines and removes the hotsheets it had already written.
+func TestGenerateContextCancelRemovesOutputs(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS", "OAT", "XYZ", "ZZZ")
+	outputDir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var started []string
+	outputs, _, err := GenerateContext(ctx, inventoryPath, "", outputDir, input, func(p Progress) {
+		if strings.HasPrefix(p.Message, "Writing ") {
+			started = append(started, p.Message)
+		}
+		if strings.HasPrefix(p.Message, "Created 1 of") {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) || outputs != nil {
+		t.Fatalf("GenerateContext() = %q, %v; want no outputs and a cancellation error", outputs, err)
+	}
+	if len(started) > 2 {
+		t.Fatalf("product lines kept starting after the cancel: %q", started)
+	}
+	left, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	if len(left) != 0 {
+		t.Fatalf("cancelled run left %d file(s) in the output folder", len(left))
+	}
+}
+
+// TestGenerateWithOptions verifies the clock, file name template, product line filter, and feature
+// toggles, and the per-file stats in the result.
+func TestGenerateWithOptions(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS", "OAT", "XYZ")
+	outputDir := t.TempDir()
+	result, err := GenerateWithOptions(context.Background(), GenerateOptions{
+		InventoryPath:    inventoryPath,
+		OutputDir:        outputDir,
+		Input:            input,
+		Now:              func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:           slog.New(slog.DiscardHandler),
+		ProductLines:     []string{"oat", "BAS", "NOPE"},
+		FileNameTemplate: "{date}-{productLine}",
+		Features:         GenerateFeatures{NoDataInsights: true},
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions returned error: %v", err)
+	}
+
+	want := []string{filepath.Join(outputDir, "20260302-BAS.xlsx"), filepath.Join(outputDir, "20260302-OAT.xlsx")}
+	if got := result.Paths(); !slices.Equal(got, want) {
+		t.Fatalf("Paths() = %q, want %q", got, want)
+	}
+	for _, file := range result.Files {
+		if file.Kind != FileKindHotsheet || file.Items != 1 || file.Bytes == 0 || file.Skipped {
+			t.Fatalf("file = %+v, want a written hotsheet with one item", file)
+		}
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "NOPE") {
+		t.Fatalf("Warnings = %q, want one for the missing product line", result.Warnings)
+	}
+	if !result.Date.Equal(time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local)) || result.Timings.Total <= 0 {
+		t.Fatalf("result date %v and total %v do not reflect the run", result.Date, result.Timings.Total)
+	}
+
+	f, err := excelize.OpenFile(want[0])
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if idx, _ := f.GetSheetIndex(dataInsightsSheetName); idx != -1 {
+		t.Fatal("Data Insights sheet was written although it was turned off")
+	}
+}
+
+// TestGenerateWithOptionsOverwrite verifies that existing hotsheets are replaced, kept, or fail
+// their product line according to the overwrite policy.
+func TestGenerateWithOptionsOverwrite(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS", "OAT")
+	opts := GenerateOptions{
+		InventoryPath: inventoryPath,
+		Input:         input,
+		Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:        slog.New(slog.DiscardHandler),
+	}
+
+	tests := []struct {
+		name      string
+		policy    OverwritePolicy
+		wantErr   bool
+		wantPaths int
+		wantKept  bool
+	}{
+		{name: "replace", policy: OverwriteReplace, wantPaths: 2},
+		{name: "skip", policy: OverwriteSkip, wantPaths: 1, wantKept: true},
+		{name: "fail", policy: OverwriteFail, wantErr: true, wantPaths: 1, wantKept: true},
+	}
+	for _, tt := range tests {
+		opts.OutputDir = t.TempDir()
+		existing := filepath.Join(opts.OutputDir, "BAS_hotsheet_20260302.xlsx")
+		if err := os.WriteFile(existing, []byte("old"), 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+		opts.Overwrite = tt.policy
+		result, err := GenerateWithOptions(context.Background(), opts)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if got := len(result.Paths()); got != tt.wantPaths {
+			t.Fatalf("%s: wrote %d file(s), want %d", tt.name, got, tt.wantPaths)
+		}
+		data, err := os.ReadFile(existing)
+		if err != nil {
+			t.Fatalf("ReadFile returned error: %v", err)
+		}
+		if kept := string(data) == "old"; kept != tt.wantKept {
+			t.Fatalf("%s: existing file kept = %v, want %v", tt.name, kept, tt.wantKept)
+		}
+	}
+}
+
+// TestGenerateWithoutPOWhenMergeFails verifies that a PO report that cannot be read leaves the
+// hotsheets without PO columns or sheets, instead of flagging every item's QTY on PO.
+func TestGenerateWithoutPOWhenMergeFails(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS")
+	poPath := filepath.Join(t.TempDir(), "po.xlsx")
+	if err := os.WriteFile(poPath, []byte("not a workbook"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	result, err := GenerateWithOptions(context.Background(), GenerateOptions{
+		InventoryPath: inventoryPath,
+		POPath:        poPath,
+		OutputDir:     t.TempDir(),
+		Input:         input,
+		Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:        slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions returned error: %v", err)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "could not be merged") {
+		t.Fatalf("warnings = %q, want one for the PO report", result.Warnings)
+	}
+	if headers := everydayRows(t, result.Files[0].Path)[0]; slices.Contains(headers, "PO Num 1") {
+		t.Fatalf("Everyday headers = %q, want no PO columns", headers)
+	}
+	f, err := excelize.OpenFile(result.Files[0].Path)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if sheets := f.GetSheetList(); slices.Contains(sheets, "PO Reconciliation") || slices.Contains(sheets, "Open POs") {
+		t.Fatalf("sheets = %q, want no PO sheets", sheets)
+	}
+}
+
+// TestGenerateListsPOOnlySKUsInIssuesWorkbook verifies that in the default per-line layout the
+// PO-only SKUs are listed on a PO Reconciliation sheet of the issues workbook, and not on the
+// product line's own sheet.
+func TestGenerateListsPOOnlySKUsInIssuesWorkbook(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS")
+	poPath := writeTestPOReport(t, [][]interface{}{
+		{"Item Code", "", "", "", "", "Required Date", "Status", "", "Qty On PO"},
+		{"SKU0"},
+		{"0001001", "", "", "", "", "06/15/2026", "Open", "", 10},
+		{"Item Total"},
+		{"NOTINV1"},
+		{"0003001", "", "", "", "", "07/04/2026", "Open", "", 6},
+		{"Item Total"},
+	})
+	result, err := GenerateWithOptions(context.Background(), GenerateOptions{
+		InventoryPath: inventoryPath,
+		POPath:        poPath,
+		OutputDir:     t.TempDir(),
+		Input:         input,
+		Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:        slog.New(slog.DiscardHandler),
+		Features:      GenerateFeatures{NoChanges: true},
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions returned error: %v", err)
+	}
+
+	reconciliationItems := func(path string) []string {
+		t.Helper()
+		f, err := excelize.OpenFile(path)
+		if err != nil {
+			t.Fatalf("OpenFile returned error: %v", err)
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		rows, err := f.GetRows(poReconciliationSheetName)
+		if err != nil {
+			t.Fatalf("GetRows(%s) in %s returned error: %v", poReconciliationSheetName, filepath.Base(path), err)
+		}
+		var items []string
+		for _, row := range rows[1:] {
+			items = append(items, getCell(row, 0))
+		}
+		return items
+	}
+	var checked int
+	for _, file := range result.Files {
+		switch file.Kind {
+		case FileKindImportIssues:
+			if items := reconciliationItems(file.Path); !slices.Equal(items, []string{"NOTINV1"}) {
+				t.Fatalf("issues workbook reconciliation items = %q, want NOTINV1", items)
+			}
+			checked++
+		case FileKindHotsheet:
+			if items := reconciliationItems(file.Path); slices.Contains(items, "NOTINV1") {
+				t.Fatalf("BAS reconciliation items = %q, want no PO-only SKU", items)
+			}
+			checked++
+		}
+	}
+	if checked != 2 {
+		t.Fatalf("files = %+v, want a hotsheet and an issues workbook", result.Files)
+	}
+}
+
+// TestGenerateOptionsValidate verifies that unusable options are rejected before anything is read.
+func TestGenerateOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []GenerateOptions{
+		{InventoryPath: "inventory.csv", FileNameTemplate: "hotsheet_{date}"},
+		{InventoryPath: "inventory.csv", FileNameTemplate: "out/{productLine}"},
+		{InventoryPath: "inventory.csv", Overwrite: OverwritePolicy(7)},
+	}
+	for _, opts := range tests {
+		if err := opts.validate(); err == nil {
+			t.Fatalf("validate(%+v) returned nil, want an error", opts)
+		}
+	}
+	if err := (GenerateOptions{InventoryPath: "inventory.csv", FileNameTemplate: "{productLine}"}).validate(); err != nil {
+		t.Fatalf("validate returned error for a valid template: %v", err)
+	}
+}
+
+// writeGenerateTestInputs writes an inventory report with one item per product line and returns
+// its path and input options with a snapshot history of the test's own. The config files come
+// from the test binary's config folder set up by TestMain.
+func writeGenerateTestInputs(t *testing.T, productLines ...string) (string, InputOptions) {
+	t.Helper()
+	inputs := testinputs.Write(t, productLines...)
+	return inputs.InventoryPath, InputOptions{HistoryDir: inputs.HistoryDir}
+}
+
+// TestValidatePlansFilesWithoutWriting verifies that Validate lists the hotsheets a run would write,
+// writes none of them, and reports the existing files OverwriteFail would refuse to replace.
+func TestValidatePlansFilesWithoutWriting(t *testing.T) {
+	t.Parallel()
+
+	inventoryPath, input := writeGenerateTestInputs(t, "BAS", "OAT")
+	outputDir := t.TempDir()
+	existing := filepath.Join(outputDir, "OAT_hotsheet_20260302.xlsx")
+	if err := os.WriteFile(existing, []byte("old"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	result, err := Validate(context.Background(), GenerateOptions{
+		InventoryPath: inventoryPath,
+		OutputDir:     outputDir,
+		Input:         input,
+		Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+		Logger:        slog.New(slog.DiscardHandler),
+		Overwrite:     OverwriteFail,
+	})
+	if err == nil || !strings.Contains(err.Error(), existing) {
+		t.Fatalf("Validate error = %v, want one naming %s", err, existing)
+	}
+	want := []string{filepath.Join(outputDir, "BAS_hotsheet_20260302.xlsx"), existing}
+	if got := result.Paths(); !slices.Equal(got, want) {
+		t.Fatalf("Paths() = %q, want %q", got, want)
+	}
+	left, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	if len(left) != 1 {
+		t.Fatalf("Validate left %d file(s) in the output folder, want only the existing one", len(left))
+	}
+}
@@ -0,0 +1,354 @@
+package hotsheet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// historyDirName is the snapshot history folder inside the app's folder of the user config
+// directory.
+const historyDirName = "history"
+
+// Trends compare a run with the oldest snapshot saved from the last trendWindowDays days that is
+// at least trendMinDays old, so a weekly pace is measured over one to four weeks.
+const (
+	trendWindowDays = 28
+	trendMinDays    = 7
+)
+
+// historySnapshot is one run's inventory data for one product line. The history keeps one folder
+// per product line and one file per run date holding that day's snapshot as one JSON line, so a
+// second run the same day replaces the first.
+type historySnapshot struct {
+	ProductLine string        `json:"productLine"`
+	Date        string        `json:"date"`
+	SavedAt     time.Time     `json:"savedAt"`
+	Items       []historyItem `json:"items"`
+}
+
+// historyItem is the inventory data of one item in a snapshot.
+type historyItem struct {
+	SKU           string  `json:"sku"`
+	Class         string  `json:"class,omitempty"`
+	Status        string  `json:"status,omitempty"`
+	Occasion      string  `json:"occasion,omitempty"`
+	Season        string  `json:"season,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	UPC           string  `json:"upc,omitempty"`
+	Foil          string  `json:"foil,omitempty"`
+	RoyaltyCode   string  `json:"royaltyCode,omitempty"`
+	OnHand        int     `json:"onHand"`
+	OnPO          int     `json:"onPO"`
+	OnSO          int     `json:"onSO"`
+	OnBO          int     `json:"onBO"`
+	YTDSold       int     `json:"ytdSold"`
+	YTDIssued     int     `json:"ytdIssued"`
+	SoldPY        int     `json:"soldPY"`
+	IssuedPY      int     `json:"issuedPY"`
+	DollarSoldYTD float64 `json:"dollarSoldYTD"`
+	DollarSoldPY  float64 `json:"dollarSoldPY"`
+}
+
+// newHistoryItem returns e's snapshot data.
+func newHistoryItem(e *inventoryEntry) historyItem {
+	class := e.RawClassDesc
+	if class == "" {
+		class = e.ClassDesc
+	}
+	return historyItem{
+		SKU:           e.SKU,
+		Class:         class,
+		Status:        e.Status,
+		Occasion:      e.Occasion,
+		Season:        e.Season,
+		Description:   e.Description,
+		UPC:           e.UPC,
+		Foil:          e.Foil,
+		RoyaltyCode:   e.RoyaltyCode,
+		OnHand:        e.OnHand,
+		OnPO:          e.OnPO,
+		OnSO:          e.OnSO,
+		OnBO:          e.OnBO,
+		YTDSold:       e.YTDSold,
+		YTDIssued:     e.YTDIssued,
+		SoldPY:        e.SoldPY,
+		IssuedPY:      e.IssuedPY,
+		DollarSoldYTD: e.DollarSoldYTD,
+		DollarSoldPY:  e.DollarSoldPY,
+	}
+}
+
+// available returns the item's QTY Available, as the standard sheets compute it.
+func (i historyItem) available() int {
+	return i.OnHand + i.OnPO - i.OnSO - i.OnBO
+}
+
+// soldYTD returns the item's QTY Sold+Issued YTD, as the standard sheets compute it.
+func (i historyItem) soldYTD() int {
+	return i.YTDSold + max(i.YTDIssued, 0)
+}
+
+// resolveHistoryDir returns the snapshot history folder: dir when set, or the history folder in
+// the app's folder of the user config directory.
+func resolveHistoryDir(dir string) (string, error) {
+	if strings.TrimSpace(dir) != "" {
+		return dir, nil
+	}
+	return userConfigFilePath(historyDirName)
+}
+
+// historyProductLineDir returns the folder of a product line's snapshots.
+func historyProductLineDir(dir, productLine string) string {
+	return filepath.Join(dir, sanitizeFileName(productLine))
+}
+
+// saveHistorySnapshot saves a product line's snapshot of entries, taken at now, as that day's file
+// in the history in dir. Older snapshots are kept.
+func saveHistorySnapshot(dir, productLine string, entries []*inventoryEntry, now time.Time) error {
+	snapshot := historySnapshot{
+		ProductLine: productLine,
+		Date:        now.Format(dateStampLayout),
+		SavedAt:     now,
+		Items:       make([]historyItem, 0, len(entries)),
+	}
+	for _, e := range entries {
+		snapshot.Items = append(snapshot.Items, newHistoryItem(e))
+	}
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s snapshot: %w", productLine, err)
+	}
+
+	lineDir := historyProductLineDir(dir, productLine)
+	if err := os.MkdirAll(lineDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create history folder: %w", err)
+	}
+	// The day's file is replaced by renaming a finished temporary file over it, so a crash never
+	// leaves a file with only part of a snapshot.
+	path := filepath.Join(lineDir, snapshot.Date+".jsonl")
+	f, err := os.CreateTemp(lineDir, snapshot.Date+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create history file: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to write history file %s: %w", path, err)
+	}
+	return removeLeftoverHistoryFiles(lineDir, now)
+}
+
+// removeLeftoverHistoryFiles removes the temporary files in lineDir left behind by a save that
+// was interrupted on a day before now.
+func removeLeftoverHistoryFiles(lineDir string, now time.Time) error {
+	dirEntries, err := os.ReadDir(lineDir)
+	if err != nil {
+		return fmt.Errorf("failed to read history folder %s: %w", lineDir, err)
+	}
+	today := now.Format(dateStampLayout)
+	var errs []error
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || len(name) < len(dateStampLayout) || !strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		date := name[:len(dateStampLayout)]
+		if _, err := time.Parse(dateStampLayout, date); err != nil || date >= today {
+			continue
+		}
+		if err := os.Remove(filepath.Join(lineDir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove leftover history file: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// trendBaseline returns the snapshot a product line's trends are measured from: the snapshot of
+// the oldest run date in the trend window before now. It returns nil when the window has no
+// readable snapshot.
+func trendBaseline(dir, productLine string, now time.Time) (*historySnapshot, error) {
+	lineDir := historyProductLineDir(dir, productLine)
+	dirEntries, err := os.ReadDir(lineDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history folder %s: %w", lineDir, err)
+	}
+
+	today := historyDay(now)
+	first := today.AddDate(0, 0, -trendWindowDays).Format(dateStampLayout)
+	last := today.AddDate(0, 0, -trendMinDays).Format(dateStampLayout)
+	// ReadDir sorts by name, so the dated files come oldest first.
+	for _, dirEntry := range dirEntries {
+		date, ok := strings.CutSuffix(dirEntry.Name(), ".jsonl")
+		if dirEntry.IsDir() || !ok || len(date) != len(dateStampLayout) || date < first || date > last {
+			continue
+		}
+		if _, err := time.Parse(dateStampLayout, date); err != nil {
+			continue
+		}
+		snapshot, err := readHistorySnapshot(filepath.Join(lineDir, dirEntry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			snapshot.Date = date
+			return snapshot, nil
+		}
+	}
+	return nil, nil
+}
+
+// readHistorySnapshot returns the snapshot in a history file, or nil when the file holds no
+// readable snapshot, such as one damaged by hand.
+func readHistorySnapshot(path string) (*historySnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file %s: %w", path, err)
+	}
+	var snapshot historySnapshot
+	if err := json.Unmarshal(bytes.TrimSpace(data), &snapshot); err != nil {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+// historyDay returns midnight of t's date, the time a run date stands for.
+func historyDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// productLineTrends is what a product line's history shows about its items since the trend
+// baseline.
+type productLineTrends struct {
+	// since is the baseline's run date.
+	since time.Time
+	// weeks is the time between the baseline and now.
+	weeks float64
+	// salesKnown is set when the baseline is from the same year as now, so the YTD sales
+	// counters have not been reset in between.
+	salesKnown bool
+	items      map[string]historyItem
+}
+
+// newProductLineTrends returns the trends of a run at now measured from baseline, or nil without
+// a baseline.
+func newProductLineTrends(baseline *historySnapshot, now time.Time) *productLineTrends {
+	if baseline == nil {
+		return nil
+	}
+	since, err := time.ParseInLocation(dateStampLayout, baseline.Date, now.Location())
+	if err != nil {
+		return nil
+	}
+	days := math.Round(historyDay(now).Sub(since).Hours() / 24)
+	if days <= 0 {
+		return nil
+	}
+	trends := &productLineTrends{
+		since:      since,
+		weeks:      days / 7,
+		salesKnown: since.Year() == now.Year(),
+		items:      make(map[string]historyItem, len(baseline.Items)),
+	}
+	for _, item := range baseline.Items {
+		trends.items[item.SKU] = item
+	}
+	return trends
+}
+
+// itemTrend is one item's trend figures. The has fields are false when the figure is unknown.
+type itemTrend struct {
+	onHandVelocity     float64
+	hasOnHandVelocity  bool
+	weeksOfSupply      float64
+	weeksOfSupplyTrend float64
+	hasWeeksOfSupply   bool
+}
+
+// item returns the trend figures of e, whose QTY Available is available now.
+func (t *productLineTrends) item(e *inventoryEntry, available int) itemTrend {
+	var trend itemTrend
+	base, ok := t.items[e.SKU]
+	if !ok {
+		return trend
+	}
+	trend.onHandVelocity = float64(e.OnHand-base.OnHand) / t.weeks
+	trend.hasOnHandVelocity = true
+	if soldPerWeek, ok := t.soldPerWeek(e); ok && soldPerWeek > 0 {
+		trend.weeksOfSupply = float64(available) / soldPerWeek
+		trend.weeksOfSupplyTrend = float64(available-base.available()) / soldPerWeek
+		trend.hasWeeksOfSupply = true
+	}
+	return trend
+}
+
+// soldPerWeek returns the units of e sold and issued per week since the baseline.
+func (t *productLineTrends) soldPerWeek(e *inventoryEntry) (float64, bool) {
+	base, ok := t.items[e.SKU]
+	if !ok || !t.salesKnown {
+		return 0, false
+	}
+	current := historyItem{YTDSold: e.YTDSold, YTDIssued: e.YTDIssued}
+	return float64(max(current.soldYTD()-base.soldYTD(), 0)) / t.weeks, true
+}
+
+// projectedDollarSales returns e's projected full-year dollar sales at its observed weekly pace:
+// its YTD sales plus the pace since the baseline over the weeks left in the year.
+func (t *productLineTrends) projectedDollarSales(e *inventoryEntry, now time.Time) (float64, bool) {
+	base, ok := t.items[e.SKU]
+	if !ok || !t.salesKnown {
+		return 0, false
+	}
+	perWeek := max(e.DollarSoldYTD-base.DollarSoldYTD, 0) / t.weeks
+	yearEnd := time.Date(now.Year()+1, time.January, 1, 0, 0, 0, 0, now.Location())
+	weeksLeft := yearEnd.Sub(now).Hours() / 24 / 7
+	return e.DollarSoldYTD + perWeek*weeksLeft, true
+}
+
+// loadProductLineTrends returns the trends of each product line with a baseline in the history in
+// dir. A product line whose history cannot be read gets no trends and a warning.
+func loadProductLineTrends(dir string, entriesByProductLine map[string][]*inventoryEntry, now time.Time) (map[string]*productLineTrends, []string) {
+	trends := make(map[string]*productLineTrends)
+	var warnings []string
+	for _, productLine := range slices.Sorted(maps.Keys(entriesByProductLine)) {
+		baseline, err := trendBaseline(dir, productLine, now)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("The %s hotsheet has no trend columns because its history could not be read: %v", productLine, err))
+			continue
+		}
+		if lineTrends := newProductLineTrends(baseline, now); lineTrends != nil {
+			trends[productLine] = lineTrends
+		}
+	}
+	return trends, warnings
+}
+
+// saveHistorySnapshots saves the snapshot of each of productLines to the history in dir, returning
+// a warning for each one that could not be saved.
+func saveHistorySnapshots(dir string, entriesByProductLine map[string][]*inventoryEntry, productLines []string, now time.Time) []string {
+	var warnings []string
+	for _, productLine := range productLines {
+		if err := saveHistorySnapshot(dir, productLine, entriesByProductLine[productLine], now); err != nil {
+			warnings = append(warnings, fmt.Sprintf("The %s snapshot could not be saved to the history: %v", productLine, err))
+		}
+	}
+	return warnings
+}
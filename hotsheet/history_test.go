@@ -0,0 +1,216 @@
+package hotsheet
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestGenerateAddsTrendColumns verifies that every run saves its snapshot to the history and that
+// a run two weeks later adds the trend columns measured from it.
+func TestGenerateAddsTrendColumns(t *testing.T) {
+	t.Parallel()
+
+	_, input := writeGenerateTestInputs(t)
+	outputDir := t.TempDir()
+	run := func(day int, onHand, soldYTD string) *GenerateResult {
+		t.Helper()
+		values := testinputs.InventoryValueRow("BAS", "Birthday", onHand)
+		values[9] = soldYTD
+		inventoryPath := writeChangesTestInventory(t, []string{",SKU0", strings.Join(values, ",")})
+		result, err := GenerateWithOptions(context.Background(), GenerateOptions{
+			InventoryPath: inventoryPath,
+			OutputDir:     outputDir,
+			Input:         input,
+			Now:           func() time.Time { return time.Date(2026, time.March, day, 9, 0, 0, 0, time.Local) },
+			Logger:        slog.New(slog.DiscardHandler),
+			Features:      GenerateFeatures{NoChanges: true},
+		})
+		if err != nil {
+			t.Fatalf("GenerateWithOptions returned error: %v", err)
+		}
+		if len(result.Warnings) != 0 {
+			t.Fatalf("GenerateWithOptions warnings = %q, want none", result.Warnings)
+		}
+		return result
+	}
+
+	first := run(2, "500", "30")
+	if headers := everydayRows(t, first.Files[0].Path)[0]; slices.Contains(headers, standardSheetTrendHeaders[0]) {
+		t.Fatalf("first run headers = %q, want no trend columns without history", headers)
+	}
+	second := run(16, "300", "70")
+	for _, date := range []string{"20260302", "20260316"} {
+		if _, err := os.Stat(filepath.Join(input.HistoryDir, "BAS", date+".jsonl")); err != nil {
+			t.Fatalf("snapshot for %s: %v", date, err)
+		}
+	}
+
+	rows := everydayRows(t, second.Files[0].Path)
+	col := slices.Index(rows[0], standardSheetTrendHeaders[0])
+	if col < 0 || !slices.Equal(rows[0][col:col+len(standardSheetTrendHeaders)], standardSheetTrendHeaders) {
+		t.Fatalf("second run headers = %q, want the trend columns", rows[0])
+	}
+	// On hand fell by 200 and sales grew by 40 over two weeks. QTY Available fell from 507 to 307.
+	want := []float64{-100, 307.0 / 20, -10}
+	for i, w := range want {
+		got, err := strconv.ParseFloat(rows[1][col+i], 64)
+		if err != nil || math.Abs(got-w) > 1e-9 {
+			t.Errorf("%s = %q, want %v", standardSheetTrendHeaders[i], rows[1][col+i], w)
+		}
+	}
+}
+
+// TestTrendBaseline verifies that the baseline is the snapshot of the oldest readable run date
+// between four weeks and one week before the run.
+func TestTrendBaseline(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	save := func(date time.Time, onHand int) {
+		t.Helper()
+		entries := []*inventoryEntry{{SKU: "SKU0", OnHand: onHand}}
+		if err := saveHistorySnapshot(dir, "BAS", entries, date); err != nil {
+			t.Fatalf("saveHistorySnapshot returned error: %v", err)
+		}
+	}
+	save(time.Date(2026, time.February, 1, 9, 0, 0, 0, time.UTC), 1)
+	save(time.Date(2026, time.February, 12, 9, 0, 0, 0, time.UTC), 2)
+	save(time.Date(2026, time.February, 12, 15, 0, 0, 0, time.UTC), 3)
+	save(time.Date(2026, time.March, 5, 9, 0, 0, 0, time.UTC), 4)
+	data, err := os.ReadFile(filepath.Join(dir, "BAS", "20260212.jsonl"))
+	if err != nil || strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("Feb 12 history file = %q, %v; want only the later snapshot", data, err)
+	}
+	// A file that cannot be decoded holds no snapshot, so an older damaged file is skipped.
+	if err := os.WriteFile(filepath.Join(dir, "BAS", "20260210.jsonl"), []byte(`{"productLine":"BAS","date":"20260210","items":[{"sku"`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	now := time.Date(2026, time.March, 9, 9, 0, 0, 0, time.UTC)
+	baseline, err := trendBaseline(dir, "BAS", now)
+	if err != nil {
+		t.Fatalf("trendBaseline returned error: %v", err)
+	}
+	if baseline == nil || baseline.Date != "20260212" || len(baseline.Items) != 1 || baseline.Items[0].OnHand != 3 {
+		t.Fatalf("trendBaseline = %+v, want the later Feb 12 snapshot", baseline)
+	}
+	trends := newProductLineTrends(baseline, now)
+	if trends == nil || trends.weeks != 25.0/7 || !trends.salesKnown {
+		t.Fatalf("newProductLineTrends = %+v, want 25 days of known sales", trends)
+	}
+
+	if baseline, err := trendBaseline(dir, "OAT", now); err != nil || baseline != nil {
+		t.Fatalf("trendBaseline for a line without history = %+v, %v; want nil", baseline, err)
+	}
+	if baseline, err := trendBaseline(dir, "BAS", time.Date(2026, time.March, 4, 9, 0, 0, 0, time.UTC)); err != nil || baseline == nil || baseline.Date != "20260212" {
+		t.Fatalf("trendBaseline on Mar 4 = %+v, %v; want Feb 12, the only snapshot at least a week old", baseline, err)
+	}
+}
+
+// TestSaveHistorySnapshotKeepsOldFiles verifies that saving a snapshot keeps the product line's
+// older snapshots and removes only the temporary files left by an interrupted save.
+func TestSaveHistorySnapshotKeepsOldFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	lineDir := filepath.Join(dir, "BAS")
+	if err := os.MkdirAll(lineDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll returned error: %v", err)
+	}
+	for _, name := range []string{"20260101.jsonl", "20260201.jsonl", "20260301.123.tmp", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(lineDir, name), []byte("{}\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+	}
+
+	now := time.Date(2026, time.March, 9, 9, 0, 0, 0, time.UTC)
+	if err := saveHistorySnapshot(dir, "BAS", []*inventoryEntry{{SKU: "SKU0"}}, now); err != nil {
+		t.Fatalf("saveHistorySnapshot returned error: %v", err)
+	}
+	dirEntries, err := os.ReadDir(lineDir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	var names []string
+	for _, dirEntry := range dirEntries {
+		names = append(names, dirEntry.Name())
+	}
+	if want := []string{"20260101.jsonl", "20260201.jsonl", "20260309.jsonl", "notes.txt"}; !slices.Equal(names, want) {
+		t.Fatalf("history files = %q, want %q", names, want)
+	}
+}
+
+// TestGenerateSavesHistoryOnlyForWrittenHotsheets verifies that a product line whose hotsheet was
+// kept by OverwriteSkip or failed to be written gets no snapshot, while the others do.
+func TestGenerateSavesHistoryOnlyForWrittenHotsheets(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []OverwritePolicy{OverwriteSkip, OverwriteFail} {
+		inventoryPath, input := writeGenerateTestInputs(t, "BAS", "OAT")
+		outputDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(outputDir, "BAS_hotsheet_20260302.xlsx"), []byte("old"), 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+		_, _ = GenerateWithOptions(context.Background(), GenerateOptions{
+			InventoryPath: inventoryPath,
+			OutputDir:     outputDir,
+			Input:         input,
+			Overwrite:     policy,
+			Now:           func() time.Time { return time.Date(2026, time.March, 2, 9, 0, 0, 0, time.Local) },
+			Logger:        slog.New(slog.DiscardHandler),
+			Features:      GenerateFeatures{NoChanges: true},
+		})
+		if _, err := os.Stat(filepath.Join(input.HistoryDir, "BAS")); !os.IsNotExist(err) {
+			t.Fatalf("policy %v: BAS history stat = %v, want no snapshot", policy, err)
+		}
+		if _, err := os.Stat(filepath.Join(input.HistoryDir, "OAT", "20260302.jsonl")); err != nil {
+			t.Fatalf("policy %v: OAT snapshot: %v", policy, err)
+		}
+	}
+}
+
+// TestProductLineTrendsAcrossYearEnd verifies that a baseline from the previous year still gives
+// the on-hand velocity but no sales pace, because the YTD counters were reset in between.
+func TestProductLineTrendsAcrossYearEnd(t *testing.T) {
+	t.Parallel()
+
+	baseline := &historySnapshot{Date: "20251222", Items: []historyItem{{SKU: "SKU0", OnHand: 100, YTDSold: 900}}}
+	now := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	trends := newProductLineTrends(baseline, now)
+	e := &inventoryEntry{SKU: "SKU0", OnHand: 72, YTDSold: 10}
+	trend := trends.item(e, 72)
+	if !trend.hasOnHandVelocity || trend.onHandVelocity != -14 || trend.hasWeeksOfSupply {
+		t.Fatalf("item trend = %+v, want a velocity of -14 and no weeks of supply", trend)
+	}
+	if _, ok := trends.projectedDollarSales(e, now); ok {
+		t.Fatal("projectedDollarSales reported a pace across the year end")
+	}
+}
+
+// everydayRows returns the raw cell values of the Everyday sheet of the workbook at path.
+func everydayRows(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	rows, err := f.GetRows("Everyday", excelize.Options{RawCellValue: true})
+	if err != nil || len(rows) < 2 {
+		t.Fatalf("GetRows = %q, %v; want a header and an item row", rows, err)
+	}
+	return rows
+}
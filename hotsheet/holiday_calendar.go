@@ -0,0 +1,202 @@
+package hotsheet
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// holidayDateRule returns the day an occasion falls on in a given Gregorian year.
+type holidayDateRule func(year int) (time.Month, int)
+
+// holidayDateRuleSyntax describes the accepted date rule forms for error messages.
+const holidayDateRuleSyntax = `"MM-DD", "easter", "hanukkah", or "<nth> <weekday> of <month>" such as "second Sunday of May"`
+
+// holidayOrdinals maps the ordinal words accepted in nth-weekday rules to n; "last" is -1.
+var holidayOrdinals = map[string]int{
+	"first": 1, "1st": 1,
+	"second": 2, "2nd": 2,
+	"third": 3, "3rd": 3,
+	"fourth": 4, "4th": 4,
+	"fifth": 5, "5th": 5,
+	"last": -1,
+}
+
+// parseHolidayDateRule parses a calendar date rule (see holidayDateRuleSyntax).
+func parseHolidayDateRule(text string) (holidayDateRule, error) {
+	rule := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	switch rule {
+	case "easter":
+		return westernEaster, nil
+	case "hanukkah":
+		return hanukkahFirstDay, nil
+	}
+	if month, day, ok := parseMonthDay(rule); ok {
+		return fixedHoliday(month, day), nil
+	}
+
+	// "<nth> <weekday> of <month>"
+	parts := strings.Fields(rule)
+	if len(parts) == 4 && parts[2] == "of" {
+		n, okN := holidayOrdinals[parts[0]]
+		weekday, okW := parseWeekday(parts[1])
+		month, okM := parseMonthName(parts[3])
+		if okN && okW && okM {
+			return nthWeekdayHoliday(month, weekday, n), nil
+		}
+	}
+	return nil, fmt.Errorf("unrecognized date %q; use %s", text, holidayDateRuleSyntax)
+}
+
+// parseMonthDay parses "MM-DD", accepting any day that exists in a leap year.
+func parseMonthDay(s string) (time.Month, int, bool) {
+	var month, day int
+	if n, err := fmt.Sscanf(s, "%d-%d", &month, &day); err != nil || n != 2 || fmt.Sprintf("%02d-%02d", month, day) != s {
+		return 0, 0, false
+	}
+	if month < 1 || month > 12 || day < 1 || day > time.Date(2024, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		return 0, 0, false
+	}
+	return time.Month(month), day, true
+}
+
+// parseWeekday parses a full or three-letter weekday name.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseMonthName parses a full or three-letter month name.
+func parseMonthName(s string) (time.Month, bool) {
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if s == name || s == name[:3] {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// fixedHoliday returns a rule for an occasion on the same date every year.
+func fixedHoliday(month time.Month, day int) holidayDateRule {
+	return func(int) (time.Month, int) {
+		return month, day
+	}
+}
+
+// nthWeekdayHoliday returns a rule for an occasion on the nth weekday of a month, such as the
+// second Sunday in May. A negative n counts from the end of the month, so -1 is the last one.
+// An n past the end of the month gives the last such weekday.
+func nthWeekdayHoliday(month time.Month, weekday time.Weekday, n int) holidayDateRule {
+	return func(year int) (time.Month, int) {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		firstMatch := 1 + (int(weekday)-int(first.Weekday())+7)%7
+		lastMatch := firstMatch + (daysInMonth-firstMatch)/7*7
+		if n < 0 {
+			return month, lastMatch + (n+1)*7
+		}
+		day := firstMatch + (n-1)*7
+		if day > daysInMonth {
+			day = lastMatch
+		}
+		return month, day
+	}
+}
+
+// westernEaster returns Western (Gregorian) Easter Sunday using the anonymous Gregorian computus.
+func westernEaster(year int) (time.Month, int) {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Month(month), day
+}
+
+// hanukkahFirstDay returns the first full day of Hanukkah (25 Kislev) that falls in the given
+// Gregorian year. The first candle is lit the evening before.
+func hanukkahFirstDay(year int) (time.Month, int) {
+	// Kislev falls in the autumn of the Hebrew year that starts in the same Gregorian year.
+	hebrewYear := year + 3761
+	newYear := hebrewNewYear(hebrewYear)
+	cheshvanDays := 29
+	// Only "complete" years (355 or 385 days) give Cheshvan a 30th day.
+	if yearLength := hebrewNewYear(hebrewYear+1) - newYear; yearLength%10 == 5 {
+		cheshvanDays = 30
+	}
+	// Tishrei always has 30 days, and 25 Kislev is 24 days after 1 Kislev.
+	date := fixedDayToDate(newYear + 30 + cheshvanDays + 24)
+	return date.Month(), date.Day()
+}
+
+// hebrewEpoch is the fixed day number (days counted from January 1 of year 1 in the proleptic
+// Gregorian calendar, which is day 1) of 1 Tishrei, year 1 of the Hebrew calendar.
+const hebrewEpoch = -1373427
+
+// hebrewNewYear returns the fixed day number of Rosh Hashanah (1 Tishrei) for a Hebrew year,
+// following the arithmetic rules of the fixed Hebrew calendar, postponements included.
+func hebrewNewYear(year int) int {
+	return hebrewEpoch + hebrewElapsedDays(year) + hebrewYearLengthCorrection(year)
+}
+
+// hebrewElapsedDays counts the days from the epoch to the molad of Tishrei of year, moved a day
+// later when the molad would place Rosh Hashanah on a Sunday, Wednesday, or Friday.
+func hebrewElapsedDays(year int) int {
+	monthsElapsed := floorDiv(235*year-234, 19)
+	partsElapsed := 12084 + 13753*monthsElapsed
+	days := 29*monthsElapsed + floorDiv(partsElapsed, 25920)
+	if floorMod(3*(days+1), 7) < 3 {
+		days++
+	}
+	return days
+}
+
+// hebrewYearLengthCorrection applies the postponements that keep Hebrew years within their
+// allowed lengths.
+func hebrewYearLengthCorrection(year int) int {
+	previous := hebrewElapsedDays(year - 1)
+	current := hebrewElapsedDays(year)
+	next := hebrewElapsedDays(year + 1)
+	switch {
+	case next-current == 356:
+		return 2
+	case current-previous == 382:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// fixedDayToDate converts a fixed day number to a UTC date.
+func fixedDayToDate(fixed int) time.Time {
+	return time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, fixed-1)
+}
+
+// floorDiv divides rounding toward negative infinity.
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// floorMod returns the remainder of floorDiv, which takes the sign of b.
+func floorMod(a, b int) int {
+	return a - b*floorDiv(a, b)
+}
@@ -0,0 +1,66 @@
+package hotsheet
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestHolidayCalendarComputesMovableDates checks the movable holidays against published dates
+// for several years.
+func TestHolidayCalendarComputesMovableDates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		occasion string
+		year     int
+		want     string
+	}{
+		{"EASTER", 2024, "March 31"},
+		{"EASTER", 2025, "April 20"},
+		{"EASTER", 2026, "April 5"},
+		{"EASTER", 2027, "March 28"},
+		{"MOTHER'S DAY", 2025, "May 11"},
+		{"MOTHER'S DAY", 2026, "May 10"},
+		{"FATHER'S DAY", 2025, "June 15"},
+		{"FATHER'S DAY", 2026, "June 21"},
+		{"THANKSGIVING", 2025, "November 27"},
+		{"THANKSGIVING", 2026, "November 26"},
+		{"HANUKKAH", 2023, "December 8"},
+		{"HANUKKAH", 2024, "December 26"},
+		{"HANUKKAH", 2025, "December 15"},
+		{"HANUKKAH", 2026, "December 5"},
+		{"HANUKKAH", 2027, "December 25"},
+	}
+	for _, tt := range tests {
+		occ, ok := builtinHolidayCalendar.occasions[tt.occasion]
+		if !ok {
+			t.Fatalf("%s is missing from the holiday calendar", tt.occasion)
+		}
+		month, day := occ.date(tt.year)
+		if got := fmt.Sprintf("%s %d", month, day); got != tt.want {
+			t.Errorf("%s %d = %s, want %s", tt.occasion, tt.year, got, tt.want)
+		}
+	}
+}
+
+// TestDataInsightsDateInfoUsesComputedDates verifies that the display, sort key, and completion
+// status follow the computed date for the reporting year.
+func TestDataInsightsDateInfoUsesComputedDates(t *testing.T) {
+	t.Parallel()
+
+	info := dataInsightsDateInfo(nil, "Spring", "Easter", time.Date(2025, time.April, 10, 12, 0, 0, 0, time.UTC))
+	if info.Display != "April 20" || info.SortKey != 420 || info.Complete {
+		t.Fatalf("unexpected Easter 2025 info: %+v", info)
+	}
+
+	info = dataInsightsDateInfo(nil, "Spring", "Easter", time.Date(2025, time.April, 21, 12, 0, 0, 0, time.UTC))
+	if !info.Complete {
+		t.Fatal("expected Easter to be complete the day after Easter Sunday")
+	}
+
+	info = dataInsightsDateInfo(nil, "Winter", "Thanksgiving", time.Date(2025, time.November, 26, 12, 0, 0, 0, time.UTC))
+	if info.Display != "November 27" || info.Complete {
+		t.Fatalf("unexpected Thanksgiving 2025 info: %+v", info)
+	}
+}
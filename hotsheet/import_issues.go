@@ -0,0 +1,310 @@
+package hotsheet
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// importIssuesSheetName is the tab that lists import problems inside a hotsheet and in the
+// standalone issues workbook.
+const importIssuesSheetName = "Import Issues"
+
+// ImportIssueKind classifies a problem found while importing the Sage reports.
+type ImportIssueKind string
+
+const (
+	// IssueUnparseableNumber marks a numeric cell whose text could not be read as a number.
+	// The value is imported as 0.
+	IssueUnparseableNumber ImportIssueKind = "Unparseable number"
+	// IssueDuplicateSKU marks an item code that appears more than once in the inventory report.
+	IssueDuplicateSKU ImportIssueKind = "Duplicate SKU"
+	// IssueMissingProductLine marks an item without a product line. Such items are left out
+	// of every hotsheet.
+	IssueMissingProductLine ImportIssueKind = "Missing product line"
+	// IssueNegativeOnHand marks an item whose on-hand quantity is below zero.
+	IssueNegativeOnHand ImportIssueKind = "Negative on-hand"
+	// IssueUnknownStatus marks an item status outside the configured statuses, or one that looks
+	// like a misspelled shaded status.
+	IssueUnknownStatus ImportIssueKind = "Unknown status"
+	// IssueSkippedBlock marks an item-code row or value row that the layout scanner could
+	// not pair up, so the item is missing from the hotsheets.
+	IssueSkippedBlock ImportIssueKind = "Skipped item block"
+	// IssueMissingPODateColumn marks a PO report whose header names no expected-date column.
+	// Every PO line is imported without a date.
+	IssueMissingPODateColumn ImportIssueKind = "Missing PO date column"
+	// IssueMissingPOStatus marks a PO line without a status in column G. It is read as a line
+	// of the item above it, with its quantity taken from the QTY on PO column.
+	IssueMissingPOStatus ImportIssueKind = "Missing PO status"
+	// IssuePOOnlySKU marks an item with open PO lines that is not in the inventory report. It
+	// belongs to no product line, so its lines are listed on the PO Reconciliation sheet of the
+	// consolidated workbook, or of the standalone issues workbook when each product line gets
+	// its own hotsheet.
+	IssuePOOnlySKU ImportIssueKind = "PO-only SKU"
+	// IssueUnmatchedOccasion marks an occasion that matched no token in the occasion mapping.
+	// The item is listed on the Everyday sheet.
+	IssueUnmatchedOccasion ImportIssueKind = "Unmatched occasion"
+)
+
+// Report names used in ImportIssue.Report.
+const (
+	inventoryReportName = "Inventory"
+	poReportName        = "PO"
+)
+
+// Item statuses the standard sheets shade.
+const (
+	statusRundown      = "Rundown"
+	statusDiscontinued = "Discontinued"
+)
+
+// shadedStatuses are the item statuses the hotsheet acts on. A typo in Sage silently drops their
+// row shading, so near misses are reported.
+var shadedStatuses = []string{statusRundown, statusDiscontinued}
+
+// maxStatusTypoDistance is the most single-letter edits a status may be from a shaded status to
+// be reported as a likely misspelling of it.
+const maxStatusTypoDistance = 2
+
+// ImportIssue describes one problem found in a source report, located precisely enough that a
+// buyer can find and fix the row in Sage.
+type ImportIssue struct {
+	Kind ImportIssueKind `json:"kind"`
+	// Report is "Inventory" or "PO".
+	Report string `json:"report"`
+	// Sheet is the worksheet the row was read from.
+	Sheet string `json:"sheet"`
+	// Row is the 1-based worksheet row.
+	Row int `json:"row"`
+	// Column is the Excel column letter, or empty when the issue concerns the whole row.
+	Column      string `json:"column,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	ProductLine string `json:"productLine,omitempty"`
+	// Value is the offending cell text, when there is one.
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// importValidator collects import issues for one source report.
+type importValidator struct {
+	report string
+	sheet  string
+	// statuses are the configured item statuses besides shadedStatuses; see unknownStatusMessage.
+	statuses []string
+	issues   []ImportIssue
+}
+
+// newImportValidator creates a collector for issues found in report's sheet.
+func newImportValidator(report, sheet string) *importValidator {
+	return &importValidator{report: report, sheet: sheet}
+}
+
+// add records one issue. colIdx is the zero-based column, or -1 for a row-level issue.
+func (v *importValidator) add(kind ImportIssueKind, row, colIdx int, sku, productLine, value, message string) {
+	if v == nil {
+		return
+	}
+	column := ""
+	if colIdx >= 0 {
+		column, _ = excelize.ColumnNumberToName(colIdx + 1)
+	}
+	v.issues = append(v.issues, ImportIssue{
+		Kind:        kind,
+		Report:      v.report,
+		Sheet:       v.sheet,
+		Row:         row,
+		Column:      column,
+		SKU:         sku,
+		ProductLine: productLine,
+		Value:       value,
+		Message:     message,
+	})
+}
+
+// Issues returns the issues collected so far.
+func (v *importValidator) Issues() []ImportIssue {
+	if v == nil {
+		return nil
+	}
+	return v.issues
+}
+
+// unknownStatusMessage returns why an item status should be reported, or "" when it is fine.
+// With configured statuses, anything outside them and shadedStatuses is reported. Without them
+// the report's ordinary statuses are unknown, so only near misses of a shaded status are.
+func (v *importValidator) unknownStatusMessage(status string) string {
+	if v == nil {
+		return ""
+	}
+	status = strings.TrimSpace(status)
+	known := append(slices.Clone(shadedStatuses), v.statuses...)
+	for _, s := range known {
+		if strings.EqualFold(status, s) {
+			return ""
+		}
+	}
+	if len(v.statuses) > 0 {
+		return fmt.Sprintf("Status is not one of %s.", strings.Join(known, ", "))
+	}
+	for _, s := range shadedStatuses {
+		if editDistance(strings.ToLower(status), strings.ToLower(s)) <= maxStatusTypoDistance {
+			return fmt.Sprintf("Status looks like a misspelling of %s, so the row is not shaded.", s)
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted in runes.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range ar {
+		curr := make([]int, len(br)+1)
+		curr[0] = i + 1
+		for j := range br {
+			cost := 1
+			if ar[i] == br[j] {
+				cost = 0
+			}
+			curr[j+1] = min(prev[j+1]+1, curr[j]+1, prev[j]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(br)]
+}
+
+// sortImportIssues orders issues by report, then row, then column so the sheet reads top to
+// bottom like the source reports.
+func sortImportIssues(issues []ImportIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Report != issues[j].Report {
+			return issues[i].Report < issues[j].Report
+		}
+		if issues[i].Row != issues[j].Row {
+			return issues[i].Row < issues[j].Row
+		}
+		return issues[i].Column < issues[j].Column
+	})
+}
+
+// importIssuesForProductLine returns the issues that belong to one product line.
+func importIssuesForProductLine(issues []ImportIssue, productLine string) []ImportIssue {
+	var filtered []ImportIssue
+	for _, issue := range issues {
+		if issue.ProductLine == productLine {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// importIssueHeaders are the column titles of the Import Issues sheet.
+var importIssueHeaders = []string{"Report", "Sheet", "Row", "Column", "Item Code", "Product Line", "Issue", "Value", "Details"}
+
+// importIssueColumnWidths keeps the Import Issues sheet readable without manual resizing.
+var importIssueColumnWidths = []float64{12, 12, 8, 8, 20, 14, 22, 20, 60}
+
+// writeImportIssuesSheet creates the Import Issues sheet in f and lists issues on it.
+func writeImportIssuesSheet(f *excelize.File, issues []ImportIssue) error {
+	sheetName := importIssuesSheetName
+	if _, err := f.NewSheet(sheetName); err != nil {
+		return fmt.Errorf("failed to create %s sheet: %w", sheetName, err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(standardHeaderFill),
+		Font:      boldFont(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s header style: %w", sheetName, err)
+	}
+	dataStyle, err := f.NewStyle(&excelize.Style{Border: thinBlackBorder()})
+	if err != nil {
+		return fmt.Errorf("failed to create %s data style: %w", sheetName, err)
+	}
+
+	for c, h := range importIssueHeaders {
+		cell, _ := excelize.CoordinatesToCellName(c+1, 1)
+		if err := f.SetCellValue(sheetName, cell, h); err != nil {
+			return fmt.Errorf("failed to set %s header %s: %w", sheetName, cell, err)
+		}
+		col, _ := excelize.ColumnNumberToName(c + 1)
+		if err := f.SetColWidth(sheetName, col, col, importIssueColumnWidths[c]); err != nil {
+			return fmt.Errorf("failed to set width for %s column %s: %w", sheetName, col, err)
+		}
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(importIssueHeaders))
+	if err := f.SetCellStyle(sheetName, "A1", lastCol+"1", headerStyle); err != nil {
+		return fmt.Errorf("failed to style %s header row: %w", sheetName, err)
+	}
+
+	for i, issue := range issues {
+		rowNum := i + 2
+		values := []interface{}{issue.Report, issue.Sheet, issue.Row, issue.Column, issue.SKU, issue.ProductLine, string(issue.Kind), issue.Value, issue.Message}
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
+			return fmt.Errorf("failed to write %s row %d: %w", sheetName, rowNum, err)
+		}
+		if err := f.SetCellStyle(sheetName, cell, fmt.Sprintf("%s%d", lastCol, rowNum), dataStyle); err != nil {
+			return fmt.Errorf("failed to style %s row %d: %w", sheetName, rowNum, err)
+		}
+	}
+
+	if err := f.AutoFilter(sheetName, fmt.Sprintf("A1:%s1", lastCol), nil); err != nil {
+		return fmt.Errorf("failed to set autofilter for %s: %w", sheetName, err)
+	}
+	return nil
+}
+
+// WriteImportIssuesWorkbook writes issues to a standalone workbook at path containing a single
+// Import Issues sheet.
+func WriteImportIssuesWorkbook(path string, issues []ImportIssue) error {
+	return writeImportIssuesWorkbook(path, issues, nil)
+}
+
+// writeImportIssuesWorkbook writes the standalone issues workbook at path. When poOnly is not
+// empty, a PO Reconciliation sheet after the Import Issues sheet lists those PO-only SKUs with
+// their PO lines.
+func writeImportIssuesWorkbook(path string, issues []ImportIssue, poOnly []poOnlyItem) error {
+	f := excelize.NewFile()
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if err := writeImportIssuesSheet(f, issues); err != nil {
+		return err
+	}
+	if len(poOnly) > 0 {
+		if err := writePOReconciliationSheet(f, nil, poOnly); err != nil {
+			return err
+		}
+	}
+	idx, _ := f.GetSheetIndex(importIssuesSheetName)
+	f.SetActiveSheet(idx)
+	if idxSheet, _ := f.GetSheetIndex("Sheet1"); idxSheet != -1 {
+		_ = f.DeleteSheet("Sheet1")
+	}
+	if err := f.SaveAs(path); err != nil {
+		return fmt.Errorf("failed to save import issues workbook %s: %w", path, err)
+	}
+	return nil
+}
+
+// importIssuesWorkbookPath returns the path of the run's standalone issues workbook, which sits
+// next to the hotsheets.
+func importIssuesWorkbookPath(outputDir, dateStr string) string {
+	outDir := outputDir
+	if strings.TrimSpace(outDir) == "" {
+		outDir = "."
+	}
+	return filepath.Join(outDir, fmt.Sprintf("import_issues_%s.xlsx", dateStr))
+}
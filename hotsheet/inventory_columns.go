@@ -0,0 +1,177 @@
+package hotsheet
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// inventoryHeaderScanRows limits how far into the report the header search looks so a
+// data cell that happens to match a label deep in the sheet cannot be mistaken for a header.
+const inventoryHeaderScanRows = 10
+
+// inventoryField identifies one value read from the inventory report.
+type inventoryField int
+
+const (
+	inventoryFieldSKU inventoryField = iota
+	inventoryFieldProductLine
+	inventoryFieldClass
+	inventoryFieldStatus
+	inventoryFieldOnHand
+	inventoryFieldOnPO
+	inventoryFieldOnSO
+	inventoryFieldOnBO
+	inventoryFieldTotalAvailable
+	inventoryFieldYTDSold
+	inventoryFieldYTDIssued
+	inventoryFieldSoldPY
+	inventoryFieldIssuedPY
+	inventoryFieldFoil
+	inventoryFieldOccasion
+	inventoryFieldDescription
+	inventoryFieldUPC
+	inventoryFieldRoyaltyCode
+	inventoryFieldDollarYTD
+	inventoryFieldDollarPY
+	inventoryFieldCount
+)
+
+// inventoryFieldSpec describes how one inventory field is recognized in the report header.
+//
+// Labels are compared after normalizeHeaderLabel, so they are written in upper case with
+// punctuation replaced by single spaces.
+type inventoryFieldSpec struct {
+	Name     string
+	Labels   []string
+	Required bool
+}
+
+// inventoryFieldSpecs lists the header labels accepted for each field of the Sage 100
+// "Item Listing With Sales History" layout.
+var inventoryFieldSpecs = [inventoryFieldCount]inventoryFieldSpec{
+	inventoryFieldSKU:            {Name: "Item Code", Labels: []string{"ITEM CODE", "ITEM", "ITEM NUMBER", "ITEM NO"}, Required: true},
+	inventoryFieldProductLine:    {Name: "Product Line", Labels: []string{"PRODUCT LINE", "PROD LINE"}, Required: true},
+	inventoryFieldClass:          {Name: "Class", Labels: []string{"CLASS", "CLASS DESCRIPTION", "CATEGORY", "CATEGORY DESCRIPTION"}, Required: true},
+	inventoryFieldStatus:         {Name: "Status", Labels: []string{"STATUS", "ITEM STATUS"}, Required: true},
+	inventoryFieldOnHand:         {Name: "QTY on Hand", Labels: []string{"QTY ON HAND", "QUANTITY ON HAND", "ON HAND"}, Required: true},
+	inventoryFieldOnPO:           {Name: "QTY on PO", Labels: []string{"QTY ON PO", "QUANTITY ON PO", "ON PO", "TOTAL QTY ON PO", "QTY ON PURCHASE ORDER"}, Required: true},
+	inventoryFieldOnSO:           {Name: "QTY on SO", Labels: []string{"QTY ON SO", "QUANTITY ON SO", "ON SO", "QTY ON SALES ORDER"}, Required: true},
+	inventoryFieldOnBO:           {Name: "QTY on BO", Labels: []string{"QTY ON BO", "QUANTITY ON BO", "ON BO", "QTY ON BACK ORDER", "QTY ON BACKORDER"}, Required: true},
+	inventoryFieldTotalAvailable: {Name: "Total Available", Labels: []string{"TOTAL AVAILABLE", "QTY AVAILABLE", "AVAILABLE"}},
+	inventoryFieldYTDSold:        {Name: "QTY Sold YTD", Labels: []string{"QTY SOLD YTD", "YTD SOLD", "SOLD YTD", "YTD QTY SOLD"}, Required: true},
+	inventoryFieldYTDIssued:      {Name: "QTY Issued YTD", Labels: []string{"QTY ISSUED YTD", "YTD ISSUED", "ISSUED YTD", "YTD QTY ISSUED"}, Required: true},
+	inventoryFieldSoldPY:         {Name: "QTY Sold PY", Labels: []string{"QTY SOLD PY", "PY SOLD", "SOLD PY", "PRIOR YEAR SOLD", "QTY SOLD PRIOR YEAR"}, Required: true},
+	inventoryFieldIssuedPY:       {Name: "QTY Issued PY", Labels: []string{"QTY ISSUED PY", "PY ISSUED", "ISSUED PY", "PRIOR YEAR ISSUED", "QTY ISSUED PRIOR YEAR"}, Required: true},
+	inventoryFieldFoil:           {Name: "Foil", Labels: []string{"FOIL"}},
+	inventoryFieldOccasion:       {Name: "Occasion", Labels: []string{"OCCASION"}, Required: true},
+	inventoryFieldDescription:    {Name: "Description", Labels: []string{"DESCRIPTION", "ITEM DESCRIPTION"}},
+	inventoryFieldUPC:            {Name: "UPC", Labels: []string{"UPC", "UPC CODE"}},
+	inventoryFieldRoyaltyCode:    {Name: "Royalty Code", Labels: []string{"ROYALTY CODE", "ROYALTY"}},
+	inventoryFieldDollarYTD:      {Name: "Dollar Sold YTD", Labels: []string{"DOLLARS SOLD YTD", "DOLLAR SOLD YTD", "YTD DOLLARS SOLD", "SALES YTD", "YTD SALES"}, Required: true},
+	inventoryFieldDollarPY:       {Name: "Dollar Sold PY", Labels: []string{"DOLLARS SOLD PY", "DOLLAR SOLD PY", "PY DOLLARS SOLD", "SALES PY", "PY SALES", "PRIOR YEAR SALES"}, Required: true},
+}
+
+// inventoryColumns maps every inventory field to its zero-based column index. Optional fields
+// that were not found in the header hold -1, which the cell helpers treat as an empty value.
+type inventoryColumns [inventoryFieldCount]int
+
+// detectInventoryColumns finds the inventory header row and builds the column map from its
+// labels. It returns the column map plus the 1-based header row number; item data starts on
+// the row after it.
+//
+// The header row is the scanned row with the most recognized labels. Fields still missing
+// after that row are looked up in the rows above it so a title split over two header lines
+// is still recognized.
+func detectInventoryColumns(rows [][]string) (inventoryColumns, int, error) {
+	var cols inventoryColumns
+	for i := range cols {
+		cols[i] = -1
+	}
+
+	labelFields := inventoryLabelLookup()
+	headerRow, bestMatches := 0, 0
+	for rowNum := 1; rowNum <= inventoryHeaderScanRows && rowNum <= len(rows); rowNum++ {
+		matches := 0
+		for _, cell := range rows[rowNum-1] {
+			for _, label := range splitHeaderCell(cell) {
+				if _, ok := labelFields[label]; ok {
+					matches++
+				}
+			}
+		}
+		if matches > bestMatches {
+			headerRow, bestMatches = rowNum, matches
+		}
+	}
+	if headerRow == 0 {
+		return cols, 0, fmt.Errorf("could not find the inventory header row in the first %d rows", inventoryHeaderScanRows)
+	}
+
+	// Search the header row first, then walk upward so the primary header row wins ties.
+	for rowNum := headerRow; rowNum >= 1; rowNum-- {
+		for colIdx, cell := range rows[rowNum-1] {
+			for _, label := range splitHeaderCell(cell) {
+				field, ok := labelFields[label]
+				if ok && cols[field] < 0 {
+					cols[field] = colIdx
+				}
+			}
+		}
+	}
+
+	// Sage stacks the product line beneath the item code in the same column, so reports
+	// that only label the item code still resolve the product line.
+	if cols[inventoryFieldProductLine] < 0 {
+		cols[inventoryFieldProductLine] = cols[inventoryFieldSKU]
+	}
+
+	var missing []string
+	for field, spec := range inventoryFieldSpecs {
+		if spec.Required && cols[field] < 0 {
+			missing = append(missing, spec.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return cols, headerRow, fmt.Errorf("inventory report header (row %d) is missing required column(s): %s", headerRow, strings.Join(missing, ", "))
+	}
+
+	return cols, headerRow, nil
+}
+
+// inventoryLabelLookup indexes the accepted header labels by normalized text.
+func inventoryLabelLookup() map[string]inventoryField {
+	lookup := make(map[string]inventoryField)
+	for field, spec := range inventoryFieldSpecs {
+		for _, label := range spec.Labels {
+			lookup[normalizeHeaderLabel(label)] = inventoryField(field)
+		}
+	}
+	return lookup
+}
+
+// splitHeaderCell returns the normalized labels contained in one header cell. Stacked headers
+// such as "Item Code / Product Line" or a label with an embedded line break yield one label
+// per part.
+func splitHeaderCell(cell string) []string {
+	parts := strings.FieldsFunc(cell, func(r rune) bool { return r == '/' || r == '\n' || r == '\r' })
+	labels := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if label := normalizeHeaderLabel(part); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// normalizeHeaderLabel upper-cases a header label and collapses punctuation and repeated
+// whitespace so "Qty. On Hand" and "QTY on Hand" compare equal.
+func normalizeHeaderLabel(s string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(mapped), " ")
+}
@@ -0,0 +1,166 @@
+package hotsheet
+
+import "strings"
+
+// inventoryMaxBlockRows bounds how many rows an item block may span before the scanner gives
+// up on finding its value row. Regular Sage blocks use three rows; the extra room absorbs
+// wrapped descriptions and similar continuation lines.
+const inventoryMaxBlockRows = 6
+
+// inventoryQuantityFields are the numeric fields that identify a value row. An item-code row
+// never carries numbers in these columns, so any numeric cell here marks the row as values.
+var inventoryQuantityFields = []inventoryField{
+	inventoryFieldOnHand,
+	inventoryFieldOnPO,
+	inventoryFieldOnSO,
+	inventoryFieldOnBO,
+	inventoryFieldYTDSold,
+	inventoryFieldYTDIssued,
+	inventoryFieldSoldPY,
+	inventoryFieldIssuedPY,
+}
+
+// inventoryBlock is one item found in the inventory report: the item-code row and the value
+// row that belongs to it.
+type inventoryBlock struct {
+	SKU      string
+	SKURow   int
+	ValueRow int
+	Values   []string
+}
+
+// inventoryLayoutStats summarizes how regular the scanned report layout was.
+type inventoryLayoutStats struct {
+	// Blocks counts item blocks that were parsed.
+	Blocks int
+	// Irregular counts parsed blocks whose value row was not two rows below the item code.
+	Irregular int
+	// Skipped counts item-code rows without a value row and value rows without an item code.
+	Skipped int
+}
+
+// inventoryRowKind classifies one inventory row for the block scanner.
+type inventoryRowKind int
+
+const (
+	inventoryRowBlank inventoryRowKind = iota
+	inventoryRowItem
+	inventoryRowValues
+	inventoryRowHeader
+	inventoryRowFooter
+	inventoryRowOther
+)
+
+// inventoryBlockScanner finds item blocks by their structure instead of assuming a fixed
+// stride: an item-code row opens a block and the next value row closes it. Rows fed in
+// between (wrapped descriptions, repeated page headers, blank lines) are ignored, and a block
+// that never receives its value row is counted as skipped so the scan resyncs on the next
+// item code.
+type inventoryBlockScanner struct {
+	cols        inventoryColumns
+	labelFields map[string]inventoryField
+	pendingSKU  string
+	pendingRow  int
+	stats       inventoryLayoutStats
+	skippedRows []int
+}
+
+// newInventoryBlockScanner creates a scanner for rows laid out according to cols.
+func newInventoryBlockScanner(cols inventoryColumns) *inventoryBlockScanner {
+	return &inventoryBlockScanner{cols: cols, labelFields: inventoryLabelLookup()}
+}
+
+// Feed classifies one 1-based row. It returns a completed block when row closes one, and
+// stop=true once the report footer is reached.
+func (s *inventoryBlockScanner) Feed(rowNum int, row []string) (block *inventoryBlock, stop bool) {
+	if s.pendingSKU != "" && rowNum-s.pendingRow >= inventoryMaxBlockRows {
+		s.skipPending()
+	}
+
+	switch s.classify(row) {
+	case inventoryRowFooter:
+		s.Finish()
+		return nil, true
+	case inventoryRowItem:
+		if s.pendingSKU != "" {
+			s.skipPending()
+		}
+		s.pendingSKU = strings.TrimSpace(getCell(row, s.cols[inventoryFieldSKU]))
+		s.pendingRow = rowNum
+	case inventoryRowValues:
+		if s.pendingSKU == "" {
+			s.stats.Skipped++
+			s.skippedRows = append(s.skippedRows, rowNum)
+			return nil, false
+		}
+		block = &inventoryBlock{SKU: s.pendingSKU, SKURow: s.pendingRow, ValueRow: rowNum, Values: row}
+		s.stats.Blocks++
+		if rowNum-s.pendingRow != 2 {
+			s.stats.Irregular++
+		}
+		s.pendingSKU, s.pendingRow = "", 0
+		return block, false
+	}
+	return nil, false
+}
+
+// Finish closes the scan, counting a trailing item-code row that never got its values.
+func (s *inventoryBlockScanner) Finish() {
+	if s.pendingSKU != "" {
+		s.skipPending()
+	}
+}
+
+// Stats returns the layout statistics collected so far.
+func (s *inventoryBlockScanner) Stats() inventoryLayoutStats {
+	return s.stats
+}
+
+// SkippedRows returns the 1-based rows that started or ended a skipped block.
+func (s *inventoryBlockScanner) SkippedRows() []int {
+	return s.skippedRows
+}
+
+// skipPending drops the open item-code row as an incomplete block.
+func (s *inventoryBlockScanner) skipPending() {
+	s.stats.Skipped++
+	s.skippedRows = append(s.skippedRows, s.pendingRow)
+	s.pendingSKU, s.pendingRow = "", 0
+}
+
+// classify decides what kind of row the scanner is looking at.
+func (s *inventoryBlockScanner) classify(row []string) inventoryRowKind {
+	skuCell := strings.TrimSpace(getCell(row, s.cols[inventoryFieldSKU]))
+	// A run date in the item-code column marks the report footer.
+	if isRunDate(skuCell) {
+		return inventoryRowFooter
+	}
+
+	for _, field := range inventoryQuantityFields {
+		if isNumericCell(getCell(row, s.cols[field])) {
+			return inventoryRowValues
+		}
+	}
+
+	if skuCell == "" {
+		if rowIsBlank(row) {
+			return inventoryRowBlank
+		}
+		return inventoryRowOther
+	}
+	// Page breaks repeat the header; its item-code label must not open a block.
+	if field, ok := s.labelFields[normalizeHeaderLabel(skuCell)]; ok && field == inventoryFieldSKU {
+		return inventoryRowHeader
+	}
+	return inventoryRowItem
+}
+
+// rowIsBlank reports whether every cell in row is empty after trimming.
+func rowIsBlank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
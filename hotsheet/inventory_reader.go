@@ -1,130 +1,175 @@
 package hotsheet
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"strings"
-
-	"github.com/xuri/excelize/v2"
-)
-
-// Shared workbook column lookups keep the row-parsing helpers aligned with the source report layout.
-var (
-	inventorySKUIdx         = colToIndex("B")
-	inventoryProductLineIdx = colToIndex("B")
-	inventoryClassIdx       = colToIndex("D")
-	inventoryStatusIdx      = colToIndex("F")
-	inventoryOnHandIdx      = colToIndex("H")
-	inventoryOnPOIdx        = colToIndex("J")
-	inventoryOnSOIdx        = colToIndex("L")
-	inventoryOnBOIdx        = colToIndex("N")
-	inventoryTotalAvailIdx  = colToIndex("P")
-	inventoryYTDSoldIdx     = colToIndex("R")
-	inventoryYTDIssuedIdx   = colToIndex("T")
-	inventorySoldPYIdx      = colToIndex("V")
-	inventoryIssuedPYIdx    = colToIndex("X")
-	inventoryFoilIdx        = colToIndex("Z")
-	inventoryOccasionIdx    = colToIndex("AB")
-	inventoryDescIdx        = colToIndex("AD")
-	inventoryUPCIdx         = colToIndex("AF")
-	inventoryRoyaltyCodeIdx = colToIndex("AH")
-	inventoryDollarYTDIdx   = colToIndex("AJ")
-	inventoryDollarPYIdx    = colToIndex("AL")
 )
 
-// loadInventoryEntries opens the inventory workbook, parses the inventory rows, and returns
-// the populated inventory map keyed by SKU.
-func loadInventoryEntries(inventoryPath string, logger *slog.Logger) (map[string]*inventoryEntry, error) {
+// loadInventoryEntries streams the inventory report (a workbook, or a CSV/TSV export), parses the
+// inventory rows, and returns the populated inventory map keyed by SKU together with the import
+// issues found along the way. sheet chooses the worksheet as described in resolveReportSheet,
+// occasions assigns each item's season (nil uses the built-in rules), and statuses lists the
+// expected Status values (nil only reports near misses of the shaded ones). Only the header area
+// is buffered; item rows are parsed as they are read, and progress, when non-nil, receives the
+// fraction of the report read so far. Reading stops with ctx's error once ctx is cancelled.
+func loadInventoryEntries(ctx context.Context, inventoryPath, sheet string, occasions *occasionMapping, statuses []string, logger *slog.Logger, progress readProgressFunc) (map[string]*inventoryEntry, []ImportIssue, error) {
 	if logger != nil {
-		logger.Info("loading inventory report", "path", inventoryPath)
+		logger.Info("loading inventory report", "path", inventoryPath, "sheet", sheet)
 	}
 
-	wbInv, err := excelize.OpenFile(inventoryPath)
+	reader, sheetName, err := openReportRows(inventoryPath, sheet)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open inventory report %s: %w", inventoryPath, err)
+		return nil, nil, fmt.Errorf("failed to read inventory report %s: %w", inventoryPath, err)
 	}
 	defer func() {
-		_ = wbInv.Close()
+		_ = reader.Close()
 	}()
 
-	invRows, err := wbInv.GetRows("Sheet1")
+	headRows, rows, err := peekReportRows(withReadContext(ctx, reader), inventoryHeaderScanRows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read inventory report %s: %w", inventoryPath, err)
+	}
+	if len(headRows) < 1 {
+		return nil, nil, fmt.Errorf("inventory report appears empty")
+	}
+
+	cols, headerRow, err := detectInventoryColumns(headRows)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read inventory sheet: %w", err)
+		return nil, nil, err
 	}
-	if len(invRows) < 1 {
-		return nil, fmt.Errorf("inventory report appears empty")
+	if logger != nil {
+		logger.Debug("inventory columns detected", "headerRow", headerRow, "columns", cols)
 	}
 
+	validator := newImportValidator(inventoryReportName, sheetName)
+	validator.statuses = statuses
 	inventoryBySKU := make(map[string]*inventoryEntry)
-	for rowNum := 2; ; rowNum += 3 {
-		item, stop := parseInventoryEntry(invRows, rowNum, logger)
+	skuRows := make(map[string]int)
+	scanner := newInventoryBlockScanner(cols)
+	for rowNum := 1; rows.Next(); rowNum++ {
+		if progress != nil {
+			progress(rows.Fraction())
+		}
+		if rowNum <= headerRow {
+			continue
+		}
+		block, stop := scanner.Feed(rowNum, rows.Row())
 		if stop {
+			if logger != nil {
+				logger.Info("Encountered run-date/footer, stopping parse", "row", rowNum)
+			}
 			break
 		}
-		if item == nil {
+		if block == nil {
 			continue
 		}
+		item := parseInventoryEntry(block, cols, occasions, validator, logger)
+		if firstRow, ok := skuRows[item.SKU]; ok {
+			// The later row still wins, matching the historical import, but the duplicate is
+			// reported so the buyer can clean up the Sage item. Both rows are item code rows.
+			validator.add(IssueDuplicateSKU, block.SKURow, cols[inventoryFieldSKU], item.SKU, item.ProductLine, item.SKU,
+				fmt.Sprintf("Item code also appears on row %d; the values of the item on this row are used.", firstRow))
+		}
+		skuRows[item.SKU] = block.SKURow
 		inventoryBySKU[item.SKU] = item
 	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read inventory report %s: %w", inventoryPath, err)
+	}
+	scanner.Finish()
+	for _, row := range scanner.SkippedRows() {
+		validator.add(IssueSkippedBlock, row, -1, "", "", "", "Row could not be paired with an item code or value row; the item is missing from the hotsheets.")
+	}
 
-	return inventoryBySKU, nil
+	stats := scanner.Stats()
+	if logger != nil {
+		logger.Info("inventory layout scanned",
+			"blocks", stats.Blocks,
+			"irregularBlocks", stats.Irregular,
+			"skippedBlocks", stats.Skipped,
+			"skippedRows", scanner.SkippedRows(),
+		)
+	}
+
+	return inventoryBySKU, validator.Issues(), nil
 }
 
-// parseInventoryEntry parses one inventory item from the worksheet rows and reports whether
-// parsing should stop because the scan reached the workbook footer or ran out of rows.
-func parseInventoryEntry(rows [][]string, rowNum int, logger *slog.Logger) (*inventoryEntry, bool) {
-	if rowNum-1 >= len(rows) {
-		return nil, true
+// parseInventoryEntry builds an inventory entry from one item block found by the layout scanner,
+// maps its occasion to a season with occasions, and records any value problems with validator.
+func parseInventoryEntry(block *inventoryBlock, cols inventoryColumns, occasions *occasionMapping, validator *importValidator, logger *slog.Logger) *inventoryEntry {
+	values := block.Values
+	cell := func(field inventoryField) string {
+		return strings.TrimSpace(getCell(values, cols[field]))
 	}
-
-	// Item codes start at B2 and repeat every 3 rows in the inventory export.
-	sku := getCellAt(rows, rowNum, inventorySKUIdx)
-	if sku == "" {
-		if logger != nil {
-			logger.Info("Skipping empty SKU at inventory row", "row", rowNum)
-		}
-		return nil, false
+	var item *inventoryEntry
+	reportUnparseable := func(field inventoryField, text string) {
+		validator.add(IssueUnparseableNumber, block.ValueRow, cols[field], item.SKU, item.ProductLine, text,
+			fmt.Sprintf("%s is not a number; it was imported as 0.", inventoryFieldSpecs[field].Name))
 	}
-	// If the SKU cell looks like a run date, stop parsing so footer metadata is ignored.
-	if isRunDate(sku) {
-		if logger != nil {
-			logger.Info("Encountered run-date/footer, stopping parse", "value", sku, "row", rowNum)
+	number := func(field inventoryField) int {
+		text := cell(field)
+		n, ok := parseIntStrict(text)
+		if !ok {
+			reportUnparseable(field, text)
 		}
-		return nil, true
+		return n
 	}
-
-	valRow := rowNum + 2
-	if valRow-1 >= len(rows) {
-		return nil, true
+	dollars := func(field inventoryField) float64 {
+		text := cell(field)
+		n, ok := parseInventoryDollarStrict(text)
+		if !ok {
+			reportUnparseable(field, text)
+		}
+		return n
 	}
 
-	item := &inventoryEntry{SKU: sku}
-	item.ProductLine = getCellAt(rows, valRow, inventoryProductLineIdx)
-	item.ClassDesc = getCellAt(rows, valRow, inventoryClassIdx)
+	item = &inventoryEntry{SKU: block.SKU}
+	item.ProductLine = cell(inventoryFieldProductLine)
+	item.ClassDesc = cell(inventoryFieldClass)
 	item.RawClassDesc = item.ClassDesc
-	item.Status = getCellAt(rows, valRow, inventoryStatusIdx)
-	item.OnHand = parseInt(getCellAt(rows, valRow, inventoryOnHandIdx))
-	item.OnPO = parseInt(getCellAt(rows, valRow, inventoryOnPOIdx))
-	item.OnSO = parseInt(getCellAt(rows, valRow, inventoryOnSOIdx))
-	item.OnBO = parseInt(getCellAt(rows, valRow, inventoryOnBOIdx))
-	item.TotalAvailable = parseInt(getCellAt(rows, valRow, inventoryTotalAvailIdx))
-	item.YTDSold = parseInt(getCellAt(rows, valRow, inventoryYTDSoldIdx))
-	item.YTDIssued = parseInt(getCellAt(rows, valRow, inventoryYTDIssuedIdx))
-	item.SoldPY = parseInt(getCellAt(rows, valRow, inventorySoldPYIdx))
-	item.IssuedPY = parseInt(getCellAt(rows, valRow, inventoryIssuedPYIdx))
-	item.Foil = getCellAt(rows, valRow, inventoryFoilIdx)
-	item.Occasion = getCellAt(rows, valRow, inventoryOccasionIdx)
-	item.Description = getCellAt(rows, valRow, inventoryDescIdx)
-	item.UPC = getCellAt(rows, valRow, inventoryUPCIdx)
-	item.RoyaltyCode = getCellAt(rows, valRow, inventoryRoyaltyCodeIdx)
-	item.DollarSoldYTD = parseInventoryDollar(getCellAt(rows, valRow, inventoryDollarYTDIdx))
-	item.DollarSoldPY = parseInventoryDollar(getCellAt(rows, valRow, inventoryDollarPYIdx))
+	item.Status = cell(inventoryFieldStatus)
+	item.OnHand = number(inventoryFieldOnHand)
+	item.OnPO = number(inventoryFieldOnPO)
+	item.OnSO = number(inventoryFieldOnSO)
+	item.OnBO = number(inventoryFieldOnBO)
+	item.TotalAvailable = number(inventoryFieldTotalAvailable)
+	item.YTDSold = number(inventoryFieldYTDSold)
+	item.YTDIssued = number(inventoryFieldYTDIssued)
+	item.SoldPY = number(inventoryFieldSoldPY)
+	item.IssuedPY = number(inventoryFieldIssuedPY)
+	item.Foil = cell(inventoryFieldFoil)
+	item.Occasion = cell(inventoryFieldOccasion)
+	season, matched := occasions.season(item.Occasion)
+	item.Season = season
+	item.Description = cell(inventoryFieldDescription)
+	item.UPC = cell(inventoryFieldUPC)
+	item.RoyaltyCode = cell(inventoryFieldRoyaltyCode)
+	item.DollarSoldYTD = dollars(inventoryFieldDollarYTD)
+	item.DollarSoldPY = dollars(inventoryFieldDollarPY)
+
+	if item.ProductLine == "" {
+		validator.add(IssueMissingProductLine, block.ValueRow, cols[inventoryFieldProductLine], item.SKU, "", "",
+			"Item has no product line and is left out of every hotsheet.")
+	}
+	if item.OnHand < 0 {
+		validator.add(IssueNegativeOnHand, block.ValueRow, cols[inventoryFieldOnHand], item.SKU, item.ProductLine, cell(inventoryFieldOnHand),
+			"QTY on Hand is negative.")
+	}
+	if item.Occasion != "" && !matched {
+		validator.add(IssueUnmatchedOccasion, block.ValueRow, cols[inventoryFieldOccasion], item.SKU, item.ProductLine, item.Occasion,
+			"Occasion matches no token in the occasion mapping; the item is listed on the Everyday sheet.")
+	}
+	if message := validator.unknownStatusMessage(item.Status); item.Status != "" && message != "" {
+		validator.add(IssueUnknownStatus, block.ValueRow, cols[inventoryFieldStatus], item.SKU, item.ProductLine, item.Status, message)
+	}
 
 	if logger != nil {
 		logger.Debug("Inventory parse",
 			"SKU", item.SKU,
-			"skuRow", rowNum,
-			"valRow", valRow,
+			"skuRow", block.SKURow,
+			"valRow", block.ValueRow,
 			"ProductLine", item.ProductLine,
 			"ClassDesc", item.ClassDesc,
 			"Status", item.Status,
@@ -139,6 +184,7 @@ func parseInventoryEntry(rows [][]string, rowNum int, logger *slog.Logger) (*inv
 			"IssuedPY", item.IssuedPY,
 			"Foil", item.Foil,
 			"Occasion", item.Occasion,
+			"Season", item.Season,
 			"Description", item.Description,
 			"UPC", item.UPC,
 			"RoyaltyCode", item.RoyaltyCode,
@@ -147,19 +193,20 @@ func parseInventoryEntry(rows [][]string, rowNum int, logger *slog.Logger) (*inv
 		)
 	}
 
-	return item, false
+	return item
 }
 
-// parseInventoryDollar converts inventory currency text into a float64 while preserving the
-// forgiving parsing used by the current workbook import.
-func parseInventoryDollar(s string) float64 {
+// parseInventoryDollarStrict converts inventory currency text into a float64 while preserving
+// the forgiving parsing used by the workbook import, and reports whether the text was a usable
+// number.
+func parseInventoryDollarStrict(s string) (float64, bool) {
 	trimmed := strings.TrimSpace(s)
 	if trimmed == "" {
-		return 0
+		return 0, true
 	}
 	trimmed = strings.ReplaceAll(trimmed, "$", "")
 	trimmed = strings.ReplaceAll(trimmed, ",", "")
 	trimmed = strings.ReplaceAll(trimmed, "(", "-")
 	trimmed = strings.ReplaceAll(trimmed, ")", "")
-	return parseFloat(trimmed)
+	return parseFloatStrict(trimmed)
 }
@@ -0,0 +1,246 @@
+package hotsheet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+)
+
+// TestDetectInventoryColumnsUsesHeaderLabels verifies that columns are resolved from the
+// header labels rather than fixed letters.
+func TestDetectInventoryColumnsUsesHeaderLabels(t *testing.T) {
+	t.Parallel()
+
+	rows := [][]string{testinputs.InventoryHeader}
+	cols, headerRow, err := detectInventoryColumns(rows)
+	if err != nil {
+		t.Fatalf("detectInventoryColumns returned error: %v", err)
+	}
+	if headerRow != 1 {
+		t.Fatalf("expected header row 1, got %d", headerRow)
+	}
+	if cols[inventoryFieldOnHand] != 5 {
+		t.Fatalf("expected QTY on Hand in column index 5, got %d", cols[inventoryFieldOnHand])
+	}
+	if cols[inventoryFieldOccasion] != 2 {
+		t.Fatalf("expected Occasion in column index 2, got %d", cols[inventoryFieldOccasion])
+	}
+	if cols[inventoryFieldProductLine] != cols[inventoryFieldSKU] {
+		t.Fatalf("expected Product Line to share the Item Code column, got %d vs %d", cols[inventoryFieldProductLine], cols[inventoryFieldSKU])
+	}
+	if cols[inventoryFieldUPC] != -1 {
+		t.Fatalf("expected missing optional UPC column to resolve to -1, got %d", cols[inventoryFieldUPC])
+	}
+}
+
+// TestDetectInventoryColumnsReportsMissingFields verifies that a header without required labels
+// fails with an error naming each missing field.
+func TestDetectInventoryColumnsReportsMissingFields(t *testing.T) {
+	t.Parallel()
+
+	header := make([]string, 0, len(testinputs.InventoryHeader))
+	for _, label := range testinputs.InventoryHeader {
+		if label == "Qty On Hand" || label == "Occasion" {
+			continue
+		}
+		header = append(header, label)
+	}
+
+	_, _, err := detectInventoryColumns([][]string{header})
+	if err == nil {
+		t.Fatal("expected an error for a header without QTY on Hand and Occasion")
+	}
+	for _, want := range []string{"QTY on Hand", "Occasion"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to name %q, got %q", want, err.Error())
+		}
+	}
+}
+
+// scanTestInventoryRows runs the block scanner over rows and returns the parsed entries in
+// report order along with the layout statistics.
+func scanTestInventoryRows(t *testing.T, rows [][]string) ([]*inventoryEntry, inventoryLayoutStats) {
+	t.Helper()
+
+	cols, headerRow, err := detectInventoryColumns(rows)
+	if err != nil {
+		t.Fatalf("detectInventoryColumns returned error: %v", err)
+	}
+
+	scanner := newInventoryBlockScanner(cols)
+	var entries []*inventoryEntry
+	for rowNum := headerRow + 1; rowNum <= len(rows); rowNum++ {
+		block, stop := scanner.Feed(rowNum, rows[rowNum-1])
+		if stop {
+			break
+		}
+		if block != nil {
+			entries = append(entries, parseInventoryEntry(block, cols, nil, nil, nil))
+		}
+	}
+	scanner.Finish()
+	return entries, scanner.Stats()
+}
+
+// TestParseInventoryEntryReadsDetectedColumns verifies that item values are read through the
+// detected column map.
+func TestParseInventoryEntryReadsDetectedColumns(t *testing.T) {
+	t.Parallel()
+
+	entries, _ := scanTestInventoryRows(t, [][]string{
+		testinputs.InventoryHeader,
+		{"", "ABC123"},
+		{},
+		testinputs.InventoryValueRow("BAS", "Birthday", "25"),
+	})
+	if len(entries) != 1 {
+		t.Fatalf("expected one inventory item, got %d", len(entries))
+	}
+	item := entries[0]
+	if item.SKU != "ABC123" || item.ProductLine != "BAS" || item.OnHand != 25 || item.Occasion != "Birthday" {
+		t.Fatalf("unexpected parsed item: %+v", item)
+	}
+	if item.DollarSoldYTD != 120 || item.DollarSoldPY != 90 {
+		t.Fatalf("unexpected dollar values: ytd=%v py=%v", item.DollarSoldYTD, item.DollarSoldPY)
+	}
+}
+
+// TestInventoryBlockScannerResyncsAfterIrregularBlocks verifies that extra rows inside a
+// block, repeated page headers, and orphaned item codes do not shift later items.
+func TestInventoryBlockScannerResyncsAfterIrregularBlocks(t *testing.T) {
+	t.Parallel()
+
+	entries, stats := scanTestInventoryRows(t, [][]string{
+		testinputs.InventoryHeader,
+		{"", "AAA1"},
+		{},
+		testinputs.InventoryValueRow("BAS", "Birthday", "1"),
+		// A wrapped description adds a fourth row to this block.
+		{"", "BBB2"},
+		{"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "continued description"},
+		{},
+		testinputs.InventoryValueRow("BAS", "Christmas", "2"),
+		// A page break repeats the header between blocks.
+		testinputs.InventoryHeader,
+		// This item code never receives its value row.
+		{"", "CCC3"},
+		{"", "DDD4"},
+		{},
+		testinputs.InventoryValueRow("OAT", "Easter", "4"),
+		{"", "04/09/2026 10:15 AM"},
+		{"", "EEE5"},
+		{},
+		testinputs.InventoryValueRow("OAT", "Easter", "5"),
+	})
+
+	got := make([]string, 0, len(entries))
+	for _, e := range entries {
+		got = append(got, e.SKU+"="+e.ProductLine)
+	}
+	if want := "AAA1=BAS|BBB2=BAS|DDD4=OAT"; strings.Join(got, "|") != want {
+		t.Fatalf("unexpected parsed items %q, want %q", strings.Join(got, "|"), want)
+	}
+	if entries[1].OnHand != 2 || entries[2].OnHand != 4 {
+		t.Fatalf("expected values to stay aligned after irregular blocks, got %d and %d", entries[1].OnHand, entries[2].OnHand)
+	}
+	if stats.Blocks != 3 || stats.Irregular != 1 || stats.Skipped != 1 {
+		t.Fatalf("unexpected layout stats: %+v", stats)
+	}
+}
+
+// TestParseInventoryEntryReportsImportIssues verifies that bad cell values are reported with
+// their row and column instead of being silently imported.
+func TestParseInventoryEntryReportsImportIssues(t *testing.T) {
+	t.Parallel()
+
+	rows := [][]string{testinputs.InventoryHeader, {"", "ABC123"}, {}, testinputs.InventoryValueRow("BAS", "Birthday", "-3")}
+	rows[3][4] = "Actve"
+	rows[3][7] = "12x"
+
+	cols, headerRow, err := detectInventoryColumns(rows)
+	if err != nil {
+		t.Fatalf("detectInventoryColumns returned error: %v", err)
+	}
+	scanner := newInventoryBlockScanner(cols)
+	validator := newImportValidator(inventoryReportName, defaultReportSheetName)
+	validator.statuses = []string{"Active"}
+	var item *inventoryEntry
+	for rowNum := headerRow + 1; rowNum <= len(rows); rowNum++ {
+		if block, _ := scanner.Feed(rowNum, rows[rowNum-1]); block != nil {
+			item = parseInventoryEntry(block, cols, nil, validator, nil)
+		}
+	}
+	if item == nil {
+		t.Fatal("expected one inventory item")
+	}
+	if item.OnSO != 0 {
+		t.Fatalf("expected unparseable QTY on SO to import as 0, got %d", item.OnSO)
+	}
+
+	got := make([]string, 0, len(validator.Issues()))
+	for _, issue := range validator.Issues() {
+		got = append(got, fmt.Sprintf("%s@%s%d", issue.Kind, issue.Column, issue.Row))
+	}
+	want := []string{
+		fmt.Sprintf("%s@H4", IssueUnparseableNumber),
+		fmt.Sprintf("%s@F4", IssueNegativeOnHand),
+		fmt.Sprintf("%s@E4", IssueUnknownStatus),
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected issues %q, want %q", got, want)
+	}
+}
+
+// TestUnknownStatusMessage verifies that configured statuses are checked strictly and that,
+// without them, only near misspellings of the shaded statuses are reported.
+func TestUnknownStatusMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		status   string
+		statuses []string
+		want     bool
+	}{
+		{status: "Rundown"},
+		{status: "discontinued"},
+		{status: "Active"},
+		{status: "Clearance"},
+		{status: "Rundwn", want: true},
+		{status: "Discontinue", want: true},
+		{status: "Active", statuses: []string{"Active"}},
+		{status: "Actve", statuses: []string{"Active"}, want: true},
+		{status: "Rundown", statuses: []string{"Active"}},
+	}
+	for _, tt := range tests {
+		validator := newImportValidator(inventoryReportName, defaultReportSheetName)
+		validator.statuses = tt.statuses
+		if got := validator.unknownStatusMessage(tt.status); (got != "") != tt.want {
+			t.Errorf("unknownStatusMessage(%q) with statuses %q = %q, want reported: %v", tt.status, tt.statuses, got, tt.want)
+		}
+	}
+}
+
+// TestLoadInventoryEntriesReportsDuplicateSKURows verifies that a duplicate item code is reported
+// on its item code row and names the item code row of the earlier item.
+func TestLoadInventoryEntriesReportsDuplicateSKURows(t *testing.T) {
+	t.Parallel()
+
+	values := strings.Join(testinputs.InventoryValueRow("BAS", "Birthday", "25"), ",")
+	lines := []string{strings.Join(testinputs.InventoryHeader, ","), ",ABC123", values, ",ABC123", values}
+	path := filepath.Join(t.TempDir(), "inventory.csv")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	_, issues, err := loadInventoryEntries(context.Background(), path, "", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("loadInventoryEntries returned error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Kind != IssueDuplicateSKU || issues[0].Row != 4 || !strings.Contains(issues[0].Message, "row 2;") {
+		t.Fatalf("issues = %+v, want a duplicate on row 4 naming row 2", issues)
+	}
+}
@@ -1,8 +1,24 @@
 package hotsheet
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Season names; each is also the name of the standard sheet its items are listed on.
+const (
+	seasonEveryday = "Everyday"
+	seasonWinter   = "Winter"
+	seasonSpring   = "Spring"
+)
+
+// occasionConfigFileName is the occasion mapping file looked up in the app's config folder.
+const occasionConfigFileName = "occasions.json"
 
-// Token lists used by mapOccasion.
+// Built-in token lists, used when no occasion mapping file exists.
 var (
 	everTokens = []string{
 		"ALL OCCASION",
@@ -53,28 +69,176 @@ var (
 	}
 )
 
-// mapOccasion maps raw occasion text to one of: "Everyday", "Winter", or "Spring".
-// The token lists are checked in season order so more specific holiday matches win before
-// the broad Everyday fallback can claim the row.
-func mapOccasion(occ string) string {
-	o := strings.ToUpper(strings.TrimSpace(occ))
-	if o == "" {
-		return "Everyday"
+// occasionRule assigns every occasion containing one of Tokens to Season. Rules are tried in
+// ascending Priority; rules with the same priority keep their order in the file.
+type occasionRule struct {
+	Season   string   `json:"season"`
+	Priority int      `json:"priority"`
+	Tokens   []string `json:"tokens"`
+}
+
+// occasionConfig is the layout of the occasion mapping file.
+type occasionConfig struct {
+	Rules []occasionRule `json:"rules"`
+}
+
+// occasionMapping maps raw occasion text to a season using rules in match order.
+type occasionMapping struct {
+	rules []occasionRule
+}
+
+// defaultOccasionRules returns the built-in rules. Spring and Winter are checked before the
+// broad Everyday list so specific holiday matches win.
+func defaultOccasionRules() []occasionRule {
+	return []occasionRule{
+		{Season: seasonSpring, Priority: 10, Tokens: springTokens},
+		{Season: seasonWinter, Priority: 20, Tokens: winterTokens},
+		{Season: seasonEveryday, Priority: 30, Tokens: everTokens},
 	}
-	for _, t := range springTokens {
-		if strings.Contains(o, t) {
-			return "Spring"
+}
+
+// builtinOccasionMapping is used when no mapping is supplied.
+var builtinOccasionMapping = mustOccasionMapping(defaultOccasionRules())
+
+// newOccasionMapping validates rules and puts them in match order. Tokens are trimmed and
+// upper-cased so the file can use any case.
+func newOccasionMapping(rules []occasionRule) (*occasionMapping, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no occasion rules defined")
+	}
+	sorted := make([]occasionRule, 0, len(rules))
+	for i, rule := range rules {
+		season, ok := canonicalSeason(rule.Season)
+		if !ok {
+			return nil, fmt.Errorf("rule %d: season %q must be %s, %s, or %s", i+1, rule.Season, seasonEveryday, seasonWinter, seasonSpring)
 		}
+		tokens := make([]string, 0, len(rule.Tokens))
+		for _, token := range rule.Tokens {
+			if token = strings.ToUpper(strings.TrimSpace(token)); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("rule %d (%s): no tokens", i+1, season)
+		}
+		sorted = append(sorted, occasionRule{Season: season, Priority: rule.Priority, Tokens: tokens})
 	}
-	for _, t := range winterTokens {
-		if strings.Contains(o, t) {
-			return "Winter"
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	return &occasionMapping{rules: sorted}, nil
+}
+
+// mustOccasionMapping builds a mapping from rules known to be valid.
+func mustOccasionMapping(rules []occasionRule) *occasionMapping {
+	m, err := newOccasionMapping(rules)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// canonicalSeason returns the sheet name for a season, ignoring case.
+func canonicalSeason(s string) (string, bool) {
+	for _, season := range []string{seasonEveryday, seasonWinter, seasonSpring} {
+		if strings.EqualFold(strings.TrimSpace(s), season) {
+			return season, true
 		}
 	}
-	for _, t := range everTokens {
-		if strings.Contains(o, t) {
-			return "Everyday"
+	return "", false
+}
+
+// loadOccasionMapping reads the occasion mapping file at path. When optional is set, a missing
+// file is not an error: the built-in rules are returned with found=false.
+func loadOccasionMapping(path string, optional bool) (mapping *occasionMapping, found bool, err error) {
+	var cfg occasionConfig
+	found, err = readJSONConfig(path, "occasion mapping", optional, &cfg)
+	if err != nil {
+		return nil, found, err
+	}
+	if !found {
+		return builtinOccasionMapping, false, nil
+	}
+	mapping, err = newOccasionMapping(cfg.Rules)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid occasion mapping %s: %w", path, err)
+	}
+	return mapping, true, nil
+}
+
+// resolveOccasionMapping loads the occasion mapping from path, or from the default location when
+// path is empty. Only the default file may be missing.
+func resolveOccasionMapping(path string, logger *slog.Logger) (*occasionMapping, error) {
+	optional := path == ""
+	if optional {
+		var err error
+		if path, err = userConfigFilePath(occasionConfigFileName); err != nil {
+			// Without a config directory there is no file to read, so the built-in rules apply.
+			if logger != nil {
+				logger.Warn("using built-in occasion mapping", "err", err)
+			}
+			return builtinOccasionMapping, nil
 		}
 	}
-	return "Everyday"
+	mapping, found, err := loadOccasionMapping(path, optional)
+	if err != nil {
+		return nil, err
+	}
+	if logger != nil {
+		logger.Info("occasion mapping loaded", "path", path, "fromFile", found, "rules", len(mapping.rules))
+	}
+	return mapping, nil
+}
+
+// UnmatchedOccasions returns the distinct occasions reported as IssueUnmatchedOccasion, sorted,
+// so callers can show which occasions need a token in the mapping file.
+func UnmatchedOccasions(issues []ImportIssue) []string {
+	seen := make(map[string]bool)
+	var occasions []string
+	for _, issue := range issues {
+		if issue.Kind != IssueUnmatchedOccasion || seen[strings.ToUpper(issue.Value)] {
+			continue
+		}
+		seen[strings.ToUpper(issue.Value)] = true
+		occasions = append(occasions, issue.Value)
+	}
+	sort.Strings(occasions)
+	return occasions
+}
+
+// season maps raw occasion text to Everyday, Winter, or Spring and reports whether a token
+// matched. Blank occasions and occasions that match no token fall back to Everyday. A nil
+// mapping uses the built-in rules.
+func (m *occasionMapping) season(occ string) (string, bool) {
+	if m == nil {
+		m = builtinOccasionMapping
+	}
+	o := strings.ToUpper(strings.TrimSpace(occ))
+	if o == "" {
+		return seasonEveryday, false
+	}
+	for _, rule := range m.rules {
+		for _, t := range rule.Tokens {
+			if strings.Contains(o, t) {
+				return rule.Season, true
+			}
+		}
+	}
+	return seasonEveryday, false
+}
+
+// mapOccasion maps raw occasion text to one of: "Everyday", "Winter", or "Spring" using the
+// built-in rules.
+func mapOccasion(occ string) string {
+	season, _ := builtinOccasionMapping.season(occ)
+	return season
+}
+
+// entrySeason returns the season resolved for e at import, falling back to the built-in rules
+// for entries that were not read from a report.
+func entrySeason(e *inventoryEntry) string {
+	if e.Season != "" {
+		return e.Season
+	}
+	return mapOccasion(e.Occasion)
 }
@@ -0,0 +1,92 @@
+package hotsheet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+)
+
+// TestLoadOccasionMappingUsesFilePriority verifies that the mapping file's priorities decide the
+// match order, that a missing file falls back to the built-in rules, and that bad seasons are
+// rejected.
+func TestLoadOccasionMappingUsesFilePriority(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, occasionConfigFileName)
+	config := `{"rules": [
+		{"season": "everyday", "priority": 2, "tokens": ["birthday", "all occasion"]},
+		{"season": "Winter", "priority": 1, "tokens": ["diwali", "holiday birthday"]}
+	]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	mapping, found, err := loadOccasionMapping(path, false)
+	if err != nil || !found {
+		t.Fatalf("loadOccasionMapping = %v, %v; want the file mapping", found, err)
+	}
+	for occasion, want := range map[string]string{
+		"Diwali":           seasonWinter,
+		"Holiday Birthday": seasonWinter,
+		"Birthday":         seasonEveryday,
+		"Easter":           seasonEveryday,
+	} {
+		if got, _ := mapping.season(occasion); got != want {
+			t.Fatalf("season(%q) = %q, want %q", occasion, got, want)
+		}
+	}
+	if _, matched := mapping.season("Easter"); matched {
+		t.Fatal("expected Easter to match no token in the file mapping")
+	}
+
+	mapping, found, err = loadOccasionMapping(filepath.Join(dir, "missing.json"), true)
+	if err != nil || found || mapping != builtinOccasionMapping {
+		t.Fatalf("expected the built-in mapping for a missing default file, got %v, %v", found, err)
+	}
+	if _, _, err := loadOccasionMapping(filepath.Join(dir, "missing.json"), false); err == nil {
+		t.Fatal("expected an error for a missing file that was chosen explicitly")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"rules": [{"season": "Summer", "tokens": ["BEACH"]}]}`), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if _, _, err := loadOccasionMapping(path, false); err == nil || !strings.Contains(err.Error(), "Summer") {
+		t.Fatalf("expected an error naming the bad season, got %v", err)
+	}
+}
+
+// TestParseInventoryEntryReportsUnmatchedOccasion verifies that an occasion with no matching
+// token lands on Everyday and is reported.
+func TestParseInventoryEntryReportsUnmatchedOccasion(t *testing.T) {
+	t.Parallel()
+
+	rows := [][]string{
+		testinputs.InventoryHeader,
+		{"", "ABC123"}, testinputs.InventoryValueRow("BAS", "Lunar New Year", "5"),
+		{"", "DEF456"}, testinputs.InventoryValueRow("BAS", "Christmas", "5"),
+	}
+	cols, headerRow, err := detectInventoryColumns(rows)
+	if err != nil {
+		t.Fatalf("detectInventoryColumns returned error: %v", err)
+	}
+	scanner := newInventoryBlockScanner(cols)
+	validator := newImportValidator(inventoryReportName, defaultReportSheetName)
+	seasons := make(map[string]string)
+	for rowNum := headerRow + 1; rowNum <= len(rows); rowNum++ {
+		if block, _ := scanner.Feed(rowNum, rows[rowNum-1]); block != nil {
+			item := parseInventoryEntry(block, cols, nil, validator, nil)
+			seasons[item.SKU] = item.Season
+		}
+	}
+
+	if seasons["ABC123"] != seasonEveryday || seasons["DEF456"] != seasonWinter {
+		t.Fatalf("unexpected seasons %v", seasons)
+	}
+	if got := UnmatchedOccasions(validator.Issues()); strings.Join(got, "|") != "Lunar New Year" {
+		t.Fatalf("UnmatchedOccasions = %q, want [Lunar New Year]", got)
+	}
+}
@@ -0,0 +1,108 @@
+package hotsheet
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// openPOsSheetName is the tab that lists every open PO line for the product line.
+const openPOsSheetName = "Open POs"
+
+// openPOsHeaders are the column titles of the Open POs sheet.
+var openPOsHeaders = []string{"Item Code", "Description", "Class", "Occasion", "Item Status", "PO Num", "PO Status", "QTY on PO", "Expected Date"}
+
+// openPOsColumnWidths keeps the Open POs sheet readable without manual resizing.
+var openPOsColumnWidths = []float64{20, 35, 20, 20, 15, 12, 15, 12, 15}
+
+// openPORow pairs one PO line with the item it belongs to.
+type openPORow struct {
+	entry *inventoryEntry
+	line  poLine
+}
+
+// buildOpenPORows flattens the PO lines of entries and orders them by expected date, with
+// undated lines last, then by item code and PO number.
+func buildOpenPORows(entries []*inventoryEntry) []openPORow {
+	var rows []openPORow
+	for _, e := range entries {
+		for _, line := range e.POs {
+			rows = append(rows, openPORow{entry: e, line: line})
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b openPORow) int {
+		return comparePOLines(a.entry.SKU, a.line, b.entry.SKU, b.line)
+	})
+	return rows
+}
+
+// writeOpenPOsSheet creates the Open POs sheet in f and lists every PO line of entries on it.
+func writeOpenPOsSheet(f *excelize.File, entries []*inventoryEntry) error {
+	sheetName := openPOsSheetName
+	if _, err := f.NewSheet(sheetName); err != nil {
+		return fmt.Errorf("failed to create %s sheet: %w", sheetName, err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(standardHeaderFill),
+		Font:      boldFont(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s header style: %w", sheetName, err)
+	}
+	dataStyle, err := f.NewStyle(&excelize.Style{Alignment: centeredAlignment(), Border: thinBlackBorder()})
+	if err != nil {
+		return fmt.Errorf("failed to create %s data style: %w", sheetName, err)
+	}
+	dateStyle, err := f.NewStyle(&excelize.Style{Alignment: centeredAlignment(), Border: thinBlackBorder(), CustomNumFmt: dateNumFmt()})
+	if err != nil {
+		return fmt.Errorf("failed to create %s date style: %w", sheetName, err)
+	}
+
+	for c, h := range openPOsHeaders {
+		cell, _ := excelize.CoordinatesToCellName(c+1, 1)
+		if err := f.SetCellValue(sheetName, cell, h); err != nil {
+			return fmt.Errorf("failed to set %s header %s: %w", sheetName, cell, err)
+		}
+		col, _ := excelize.ColumnNumberToName(c + 1)
+		if err := f.SetColWidth(sheetName, col, col, openPOsColumnWidths[c]); err != nil {
+			return fmt.Errorf("failed to set width for %s column %s: %w", sheetName, col, err)
+		}
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(openPOsHeaders))
+	if err := f.SetCellStyle(sheetName, "A1", lastCol+"1", headerStyle); err != nil {
+		return fmt.Errorf("failed to style %s header row: %w", sheetName, err)
+	}
+
+	for i, r := range buildOpenPORows(entries) {
+		rowNum := i + 2
+		var expected interface{} = ""
+		if !r.line.ExpectedDate.IsZero() {
+			expected = r.line.ExpectedDate
+		}
+		values := []interface{}{r.entry.SKU, r.entry.Description, r.entry.RawClassDesc, r.entry.Occasion, r.entry.Status, r.line.Number, r.line.Status, r.line.Qty, expected}
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
+			return fmt.Errorf("failed to write %s row %d: %w", sheetName, rowNum, err)
+		}
+		if err := f.SetCellStyle(sheetName, cell, fmt.Sprintf("%s%d", lastCol, rowNum), dataStyle); err != nil {
+			return fmt.Errorf("failed to style %s row %d: %w", sheetName, rowNum, err)
+		}
+		if _, isDate := expected.(time.Time); isDate {
+			// Expected Date is the last column.
+			dateCell := fmt.Sprintf("%s%d", lastCol, rowNum)
+			if err := f.SetCellStyle(sheetName, dateCell, dateCell, dateStyle); err != nil {
+				return fmt.Errorf("failed to style %s date cell %s: %w", sheetName, dateCell, err)
+			}
+		}
+	}
+
+	if err := f.AutoFilter(sheetName, fmt.Sprintf("A1:%s1", lastCol), nil); err != nil {
+		return fmt.Errorf("failed to set autofilter for %s: %w", sheetName, err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // colToIndex converts Excel column letters to zero-based index (A->0).
@@ -20,9 +21,16 @@ func colToIndex(col string) int {
 
 // parseInt parses numbers permissively (commas, floats fallback, trailing "-" interpreted as negative).
 func parseInt(s string) int {
+	v, _ := parseIntStrict(s)
+	return v
+}
+
+// parseIntStrict parses numbers like parseInt but also reports whether the text was a usable
+// number. Blank cells count as a valid zero so only genuinely malformed values are flagged.
+func parseIntStrict(s string) (int, bool) {
 	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
 	if s == "" {
-		return 0
+		return 0, true
 	}
 	if strings.HasSuffix(s, "-") {
 		s = "-" + strings.TrimSuffix(s, "-")
@@ -31,40 +39,42 @@ func parseInt(s string) int {
 	if err != nil {
 		f, err2 := strconv.ParseFloat(s, 64)
 		if err2 != nil {
-			return 0
+			return 0, false
 		}
-		return int(f)
+		return int(f), true
 	}
-	return v
+	return v, true
 }
 
-// parseFloat parses numbers permissively (commas, trailing "-" interpreted as negative).
-// Returns a float64, or 0.0 on parse failure.
-func parseFloat(s string) float64 {
+// parseFloatStrict parses numbers permissively (commas, trailing "-" interpreted as negative) and
+// reports whether the text was a usable number. Blank cells count as a valid zero.
+func parseFloatStrict(s string) (float64, bool) {
 	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
 	if s == "" {
-		return 0.0
+		return 0.0, true
 	}
 	if strings.HasSuffix(s, "-") {
 		s = "-" + strings.TrimSuffix(s, "-")
 	}
 	v, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return 0.0
+		return 0.0, false
 	}
-	return v
+	return v, true
 }
 
-// getCellAt reads a cell from rows by 1-based row number and 0-based column index.
-func getCellAt(rows [][]string, rowNum int, colIdx int) string {
-	if rowNum-1 < 0 || rowNum-1 >= len(rows) {
-		return ""
-	}
-	row := rows[rowNum-1]
-	if colIdx < 0 || colIdx >= len(row) {
-		return ""
+// isNumericCell reports whether a cell holds a number in any of the forms accepted by
+// parseFloatStrict or parseInventoryDollarStrict (commas, currency signs, parentheses, trailing
+// "-").
+func isNumericCell(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
 	}
-	return strings.TrimSpace(row[colIdx])
+	s = strings.NewReplacer(",", "", "$", "", "(", "", ")", "").Replace(s)
+	s = strings.TrimSuffix(s, "-")
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
 }
 
 // isRunDate determines whether a cell looks like a run-date (tries to detect explicit dates/times or a "Run Date" label).
@@ -91,12 +101,51 @@ func isRunDate(s string) bool {
 	return false
 }
 
-// getRow returns the row at 1-based index vloc (or nil).
-func getRow(rows [][]string, vloc int) []string {
-	if vloc-1 >= 0 && vloc-1 < len(rows) {
-		return rows[vloc-1]
+// reportDateLayouts are the date formats Sage and Excel produce for date cells in the exported
+// reports, including excelize's rendering of the built-in short-date format.
+var reportDateLayouts = []string{
+	"01/02/2006",
+	"1/2/2006",
+	"01/02/06",
+	"1/2/06",
+	"01-02-06",
+	"01-02-2006",
+	"2006-01-02",
+	"2-Jan-06",
+	"Jan 2, 2006",
+}
+
+// parseReportDate parses a date cell from a Sage report. Trailing time-of-day text is ignored.
+func parseReportDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
 	}
-	return nil
+	if fields := strings.Fields(s); len(fields) > 1 && strings.Contains(fields[1], ":") {
+		s = fields[0]
+	}
+	for _, layout := range reportDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseReportDateOrSerial parses a date cell like parseReportDate and also accepts an Excel
+// serial day number, which is how an unformatted date cell is exported. Only use it on columns
+// already known to hold dates, since any plain quantity would otherwise read as a serial.
+func parseReportDateOrSerial(s string) (time.Time, bool) {
+	if t, ok := parseReportDate(s); ok {
+		return t, true
+	}
+	serial, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || serial < 1 {
+		return time.Time{}, false
+	}
+	// Excel's day 0 is 1899-12-30 once the 1900 leap-year bug is accounted for.
+	base := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.Local)
+	return base.AddDate(0, 0, int(serial)), true
 }
 
 // getCell returns the string at index idx in row r (or empty string).
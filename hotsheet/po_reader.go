@@ -1,11 +1,10 @@
 package hotsheet
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"strings"
-
-	"github.com/xuri/excelize/v2"
 )
 
 var (
@@ -15,97 +14,191 @@ var (
 	poOnPOBackorderIdx = colToIndex("K")
 )
 
-// mergePOData opens the optional PO workbook and merges PO numbers and quantities into the
-// provided inventory map. PO-only SKUs are skipped so the workbook does not create UNKNOWN groups.
-func mergePOData(poPath string, inventoryBySKU map[string]*inventoryEntry, logger *slog.Logger) error {
+// poHeaderScanRows limits how far into the PO report the expected-date header search looks.
+const poHeaderScanRows = 10
+
+// poDateLabels are the normalized header labels that identify the PO expected-date column, most
+// preferred first. Order and receipt dates are not arrival dates, so they are not listed.
+var poDateLabels = []string{
+	"REQUIRED DATE",
+	"REQ DATE",
+	"DATE REQUIRED",
+	"EXPECTED DATE",
+	"PROMISE DATE",
+	"PROMISED DATE",
+	"DUE DATE",
+}
+
+// mergePOData streams the optional PO report (a workbook, or a CSV/TSV export) and merges every
+// open PO line into the provided inventory map. PO-only SKUs are not added to the map, so the
+// workbook does not create UNKNOWN groups; they are returned with their PO lines, and reported as
+// import issues, instead. Quantities that cannot be parsed are returned as import issues. sheet
+// chooses the worksheet as described in resolveReportSheet, and progress, when non-nil, receives
+// the fraction of the report read so far. Reading stops with ctx's error once ctx is cancelled.
+//
+// The report lists an item-code row followed by its PO lines, and a row whose first cell starts
+// with "Item" closes the item. An item row is the first row with a value in column A after that
+// row or the start of the report, or a row without a status whose column A is an inventory item
+// code. Any other row inside an item is one of its PO lines, and a line without a status is
+// reported as an import issue rather than taken for the next item.
+func mergePOData(ctx context.Context, poPath, sheet string, inventoryBySKU map[string]*inventoryEntry, logger *slog.Logger, progress readProgressFunc) (*poMergeResult, error) {
+	result := &poMergeResult{}
 	if strings.TrimSpace(poPath) == "" {
-		return nil
+		return result, nil
 	}
 
-	wbPO, err := excelize.OpenFile(poPath)
+	reader, sheetName, err := openReportRows(poPath, sheet)
 	if err != nil {
-		return fmt.Errorf("failed to open PO report %s: %w", poPath, err)
+		return result, fmt.Errorf("failed to read PO report %s: %w", poPath, err)
 	}
 	defer func() {
-		_ = wbPO.Close()
+		_ = reader.Close()
 	}()
 
-	poRows, err := wbPO.GetRows("Sheet1")
+	headRows, rows, err := peekReportRows(withReadContext(ctx, reader), poHeaderScanRows)
 	if err != nil {
-		return fmt.Errorf("failed to read PO sheet: %w", err)
+		return result, fmt.Errorf("failed to read PO report %s: %w", poPath, err)
 	}
-	if len(poRows) == 0 {
-		return nil
+	if len(headRows) == 0 {
+		return result, nil
 	}
 
-	for rowNum := 1; rowNum <= len(poRows); rowNum++ {
-		row := poRows[rowNum-1]
-		if poDataIdx >= len(row) {
-			continue
-		}
+	validator := newImportValidator(poReportName, sheetName)
+	dateIdx := detectPODateColumn(headRows)
+	if logger != nil {
+		logger.Debug("PO expected-date column detected", "column", dateIdx)
+	}
+	if dateIdx < 0 {
+		// Guessing from the first date in each line could pick up an order or receipt date, so
+		// the dates stay blank until the header is fixed.
+		validator.add(IssueMissingPODateColumn, 1, -1, "", "", "",
+			fmt.Sprintf("No expected-date column was found in the first %d rows; the PO dates and stockout projections are left blank.", poHeaderScanRows))
+	}
 
-		sku := strings.TrimSpace(row[poDataIdx])
-		if sku == "" {
+	var item *inventoryEntry
+	// poOnly collects the lines of the current item when it is missing from the inventory map.
+	var poOnly *poOnlyItem
+	// inItem is set from an item row until the row that closes the item.
+	inItem := false
+	skuRow := 0
+	flushPOOnly := func() {
+		if poOnly != nil && len(poOnly.POs) > 0 {
+			result.POOnly = append(result.POOnly, *poOnly)
+			validator.add(IssuePOOnlySKU, poOnly.Row, poDataIdx, poOnly.SKU, "", "",
+				fmt.Sprintf("%d open PO line(s) totaling %d for an item that is not in the inventory report", len(poOnly.POs), poLinesTotal(poOnly.POs)))
+		}
+		poOnly = nil
+	}
+	for rowNum := 1; rows.Next(); rowNum++ {
+		if progress != nil {
+			progress(rows.Fraction())
+		}
+		row := rows.Row()
+		dataCell := strings.TrimSpace(getCell(row, poDataIdx))
+		status := strings.TrimSpace(getCell(row, poStatusIdx))
+		_, inventoryItem := inventoryBySKU[dataCell]
+
+		switch {
+		case dataCell == "":
+			// skip empty lines
+			continue
+		case strings.HasPrefix(strings.ToUpper(dataCell), "ITEM"):
+			// end of PO block for this SKU
+			item, inItem = nil, false
+			flushPOOnly()
+			continue
+		case status == "" && (!inItem || inventoryItem):
+			item, inItem, skuRow = nil, true, rowNum
+			flushPOOnly()
+			if !inventoryItem {
+				if logger != nil {
+					logger.Info("Skipping PO-only SKU (not present in inventory)", "SKU", dataCell)
+				}
+				poOnly = &poOnlyItem{SKU: dataCell, Row: rowNum}
+				continue
+			}
+			item = inventoryBySKU[dataCell]
 			continue
+		case status == "":
+			sku, productLine := "", ""
+			if item != nil {
+				sku, productLine = item.SKU, item.ProductLine
+			} else if poOnly != nil {
+				sku = poOnly.SKU
+			}
+			validator.add(IssueMissingPOStatus, rowNum, poStatusIdx, sku, productLine, "",
+				fmt.Sprintf("PO line %s has no status; it was read as an open line of %s.", dataCell, sku))
 		}
 
-		item, ok := inventoryBySKU[sku]
-		if !ok {
-			if logger != nil {
-				logger.Info("Skipping PO-only SKU (not present in inventory)", "SKU", sku)
+		if item == nil {
+			if poOnly != nil {
+				// Parse the line against a scratch entry so the PO-only SKU keeps its lines
+				// for reconciliation without joining the inventory map.
+				scratch := &inventoryEntry{SKU: poOnly.SKU}
+				applyPOToEntry(scratch, row, rowNum, dateIdx, validator)
+				poOnly.POs = append(poOnly.POs, scratch.POs...)
 			}
 			continue
 		}
 
-		// Walk subsequent rows until we hit a line that starts with "Item" (end of section)
-		// or we've collected up to two PO lines for this SKU.
-		maxPOs := 2
-		poCount := 0
-		for scanRow := rowNum + 1; scanRow <= len(poRows) && poCount < maxPOs; scanRow++ {
-			nextRow := getRow(poRows, scanRow)
-			if nextRow == nil {
-				break
-			}
+		line := applyPOToEntry(item, row, rowNum, dateIdx, validator)
+		if logger != nil {
+			logger.Debug("Individual PO parse",
+				"SKU", item.SKU,
+				"skuRow", skuRow,
+				"poRow", rowNum,
+				"PO Num", line.Number,
+				"QTY", line.Qty,
+				"PO Status", line.Status,
+				"Expected Date", line.ExpectedDate,
+			)
+		}
+	}
+	flushPOOnly()
 
-			poCell := strings.TrimSpace(getCell(nextRow, poDataIdx))
-			if poCell == "" {
-				// skip empty lines
-				continue
-			}
-			if strings.HasPrefix(strings.ToUpper(poCell), "ITEM") {
-				// end of PO block for this SKU
-				break
-			}
+	result.Issues = validator.Issues()
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("failed to read PO report %s: %w", poPath, err)
+	}
+	return result, nil
+}
 
-			poNum, qty := applyPOToEntry(item, nextRow)
-			if logger != nil {
-				status := strings.TrimSpace(getCell(nextRow, poStatusIdx))
-				logger.Debug("Individual PO parse",
-					"SKU", sku,
-					"skuRow", rowNum,
-					"poRow", scanRow,
-					"PO Num", poNum,
-					"QTY", qty,
-					"PO Status", status,
-				)
+// detectPODateColumn returns the zero-based column whose header names the PO expected date, or
+// -1 when the report has no recognizable date header. When several columns match, the one with
+// the label listed first in poDateLabels wins, and then the leftmost.
+func detectPODateColumn(rows [][]string) int {
+	priority := make(map[string]int, len(poDateLabels))
+	for i, label := range poDateLabels {
+		priority[label] = i
+	}
+	best, bestPriority := -1, len(poDateLabels)
+	for rowNum := 1; rowNum <= poHeaderScanRows && rowNum <= len(rows); rowNum++ {
+		for colIdx, cell := range rows[rowNum-1] {
+			for _, label := range splitHeaderCell(cell) {
+				if p, ok := priority[label]; ok && p < bestPriority {
+					best, bestPriority = colIdx, p
+				}
 			}
-
-			poCount++
 		}
 	}
-
-	return nil
+	return best
 }
 
 // applyPOToEntry normalizes one PO line, chooses the correct quantity column based on the status,
-// and assigns the PO into the first available slot on the inventory entry.
-func applyPOToEntry(item *inventoryEntry, poRow []string) (string, int) {
+// reads the expected date, and appends the line to the inventory entry. rowNum is the 1-based
+// PO report row used when reporting an unparseable quantity to validator. dateIdx is the
+// detected expected-date column, or -1 to leave the date blank.
+func applyPOToEntry(item *inventoryEntry, poRow []string, rowNum, dateIdx int, validator *importValidator) poLine {
 	status := strings.TrimSpace(getCell(poRow, poStatusIdx))
-	var qty int
+	qtyIdx := poOnPOIdx
 	if strings.EqualFold(status, "Back Order") {
-		qty = parseInt(getCell(poRow, poOnPOBackorderIdx))
-	} else {
-		qty = parseInt(getCell(poRow, poOnPOIdx))
+		qtyIdx = poOnPOBackorderIdx
+	}
+	qtyText := strings.TrimSpace(getCell(poRow, qtyIdx))
+	qty, ok := parseIntStrict(qtyText)
+	if !ok {
+		validator.add(IssueUnparseableNumber, rowNum, qtyIdx, item.SKU, item.ProductLine, qtyText,
+			"PO quantity is not a number; it was imported as 0.")
 	}
 
 	// Normalize PO numbers by removing leading zeros while preserving a usable zero value.
@@ -113,27 +206,12 @@ func applyPOToEntry(item *inventoryEntry, poRow []string) (string, int) {
 	if poNum == "" {
 		poNum = "0"
 	}
-	assignPO(item, poNum, qty)
-	return poNum, qty
-}
 
-// assignPO assigns a PO number and quantity into the first available PO slot on the entry.
-// The workbook only exposes two visible PO lines, so any additional PO quantities are folded
-// into the first slot to avoid losing data.
-func assignPO(item *inventoryEntry, poNum string, qty int) {
-	if poNum == "" && qty == 0 {
-		return
+	line := poLine{Number: poNum, Status: status, Qty: qty}
+	if dateIdx >= 0 {
+		line.ExpectedDate, _ = parseReportDateOrSerial(getCell(poRow, dateIdx))
 	}
-	if item.PONum1 == "" {
-		item.PONum1 = poNum
-		item.OnPO1 = qty
-		return
-	}
-	if item.PONum2 == "" {
-		item.PONum2 = poNum
-		item.OnPO2 = qty
-		return
-	}
-	// fallback: accumulate into OnPO1
-	item.OnPO1 += qty
+
+	item.POs = append(item.POs, line)
+	return line
 }
@@ -0,0 +1,231 @@
+package hotsheet
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTestPOReport saves rows to Sheet1 of a new workbook and returns its path.
+func writeTestPOReport(t *testing.T, rows [][]interface{}) string {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer func() {
+		_ = f.Close()
+	}()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(defaultReportSheetName, cell, &row); err != nil {
+			t.Fatalf("SetSheetRow returned error: %v", err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "po.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatalf("SaveAs returned error: %v", err)
+	}
+	return path
+}
+
+// TestMergePODataKeepsEveryPOLine verifies that an item with more than two open POs keeps
+// every line with its own quantity and expected date, that the standard sheets show the two
+// earliest lines, and that a PO-only SKU is reported as an import issue.
+func TestMergePODataKeepsEveryPOLine(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestPOReport(t, [][]interface{}{
+		{"Item Code", "", "", "", "", "Required Date", "Status", "", "Qty On PO", "", "Qty Backordered"},
+		{"ABC123"},
+		{"0001001", "", "", "", "", "06/15/2026", "Open", "", 10},
+		{"0001002", "", "", "", "", "05/01/2026", "Back Order", "", "", "", 7},
+		{"0001003", "", "", "", "", "", "Open", "", 3},
+		{"Item Total"},
+		{"XYZ999"},
+		{"0002001", "", "", "", "", "07/04/2026", "Open", "", 4},
+		{"Item Total"},
+		{"NOTINV1"},
+		{"0003001", "", "", "", "", "07/04/2026", "Open", "", 6},
+	})
+	inventory := map[string]*inventoryEntry{
+		"ABC123": {SKU: "ABC123", ProductLine: "BAS", OnPO: 20},
+		"XYZ999": {SKU: "XYZ999", ProductLine: "BAS", OnPO: 5},
+	}
+
+	result, err := mergePOData(context.Background(), path, "", inventory, nil, nil)
+	if err != nil {
+		t.Fatalf("mergePOData returned error: %v", err)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Kind != IssuePOOnlySKU || result.Issues[0].SKU != "NOTINV1" || result.Issues[0].Row != 10 {
+		t.Fatalf("expected only the PO-only SKU issue, got %+v", result.Issues)
+	}
+
+	lines := inventory["ABC123"].POs
+	if len(lines) != 3 {
+		t.Fatalf("expected three PO lines for ABC123, got %+v", lines)
+	}
+	if lines[1].Number != "1002" || lines[1].Qty != 7 || lines[1].Status != "Back Order" {
+		t.Fatalf("unexpected back-order PO line: %+v", lines[1])
+	}
+	if want := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.Local); !lines[1].ExpectedDate.Equal(want) {
+		t.Fatalf("expected PO 1002 date %v, got %v", want, lines[1].ExpectedDate)
+	}
+	if !lines[2].ExpectedDate.IsZero() {
+		t.Fatalf("expected undated PO line to keep a zero date, got %v", lines[2].ExpectedDate)
+	}
+	if got := inventory["XYZ999"].POs; len(got) != 1 || got[0].Qty != 4 {
+		t.Fatalf("unexpected PO lines for XYZ999: %+v", got)
+	}
+
+	vals := standardSheetPOValues(inventory["ABC123"])
+	if vals[0] != "1002" || vals[3] != "1001" {
+		t.Fatalf("expected the standard sheets to show POs 1002 then 1001, got %v", vals)
+	}
+}
+
+// TestMergePODataReconcilesQuantities verifies that QTY on PO mismatches and PO-only SKUs are
+// listed for the PO Reconciliation sheet.
+func TestMergePODataReconcilesQuantities(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestPOReport(t, [][]interface{}{
+		{"ABC123"},
+		{"0001001", "", "", "", "", "06/15/2026", "Open", "", 10},
+		{"0001002", "", "", "", "", "05/01/2026", "Open", "", 10},
+		{"XYZ999"},
+		{"0002001", "", "", "", "", "07/04/2026", "Open", "", 4},
+		{"Item Total"},
+		{"NOTINV1"},
+		{"0003001", "", "", "", "", "07/04/2026", "Open", "", 6},
+	})
+	inventory := map[string]*inventoryEntry{
+		"ABC123": {SKU: "ABC123", ProductLine: "BAS", OnPO: 20},
+		"XYZ999": {SKU: "XYZ999", ProductLine: "BAS", OnPO: 5},
+	}
+
+	result, err := mergePOData(context.Background(), path, "", inventory, nil, nil)
+	if err != nil {
+		t.Fatalf("mergePOData returned error: %v", err)
+	}
+	if len(result.POOnly) != 1 || result.POOnly[0].SKU != "NOTINV1" || poLinesTotal(result.POOnly[0].POs) != 6 {
+		t.Fatalf("unexpected PO-only items: %+v", result.POOnly)
+	}
+	if _, ok := inventory["NOTINV1"]; ok {
+		t.Fatal("PO-only SKU must not be added to the inventory map")
+	}
+	if hasPOMismatch(inventory["ABC123"]) {
+		t.Fatal("expected ABC123 to reconcile")
+	}
+
+	rows := buildPOReconciliationRows([]*inventoryEntry{inventory["ABC123"], inventory["XYZ999"]}, result.POOnly)
+	if len(rows) != 2 {
+		t.Fatalf("expected one mismatch and one PO-only row, got %v", rows)
+	}
+	if rows[0][0] != "XYZ999" || rows[0][3] != poReconcileMismatch || rows[0][6] != 1 {
+		t.Fatalf("unexpected mismatch row: %v", rows[0])
+	}
+	if rows[1][0] != "NOTINV1" || rows[1][3] != poReconcilePOOnly {
+		t.Fatalf("unexpected PO-only row: %v", rows[1])
+	}
+}
+
+// TestMergePODataLeavesDatesBlankWithoutDateColumn verifies that a PO report without an
+// expected-date header imports its lines undated and reports the missing column once.
+func TestMergePODataLeavesDatesBlankWithoutDateColumn(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestPOReport(t, [][]interface{}{
+		{"ABC123"},
+		{"0001001", "", "", "", "", "06/15/2026", "Open", "", 10},
+		{"0001002", "", "", "", "", "05/01/2026", "Open", "", 5},
+	})
+	inventory := map[string]*inventoryEntry{
+		"ABC123": {SKU: "ABC123", ProductLine: "BAS", OnPO: 15},
+	}
+
+	result, err := mergePOData(context.Background(), path, "", inventory, nil, nil)
+	if err != nil {
+		t.Fatalf("mergePOData returned error: %v", err)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Kind != IssueMissingPODateColumn {
+		t.Fatalf("expected only the missing date column issue, got %+v", result.Issues)
+	}
+	lines := inventory["ABC123"].POs
+	if len(lines) != 2 {
+		t.Fatalf("expected two PO lines for ABC123, got %+v", lines)
+	}
+	for _, line := range lines {
+		if !line.ExpectedDate.IsZero() {
+			t.Fatalf("expected PO %s to stay undated, got %v", line.Number, line.ExpectedDate)
+		}
+	}
+}
+
+// TestMergePODataKeepsLinesWithoutStatus verifies that a PO line without a status stays a line of
+// its item and is reported, while an inventory item code still starts the next item without an
+// Item Total row in between.
+func TestMergePODataKeepsLinesWithoutStatus(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestPOReport(t, [][]interface{}{
+		{"Item Code", "", "", "", "", "Required Date", "Status", "", "Qty On PO"},
+		{"ABC123"},
+		{"0001001", "", "", "", "", "06/15/2026", "Open", "", 10},
+		{"0001002", "", "", "", "", "07/01/2026", "", "", 8},
+		{"XYZ999"},
+		{"0002001", "", "", "", "", "07/04/2026", "Open", "", 4},
+		{"Item Total"},
+		{"NOTINV1"},
+		{"0003001", "", "", "", "", "07/04/2026", "", "", 6},
+	})
+	inventory := map[string]*inventoryEntry{
+		"ABC123": {SKU: "ABC123", ProductLine: "BAS", OnPO: 18},
+		"XYZ999": {SKU: "XYZ999", ProductLine: "BAS", OnPO: 4},
+	}
+
+	result, err := mergePOData(context.Background(), path, "", inventory, nil, nil)
+	if err != nil {
+		t.Fatalf("mergePOData returned error: %v", err)
+	}
+	if got := inventory["ABC123"].POs; len(got) != 2 || got[1].Number != "1002" || got[1].Qty != 8 {
+		t.Fatalf("unexpected PO lines for ABC123: %+v", got)
+	}
+	if got := inventory["XYZ999"].POs; len(got) != 1 || got[0].Qty != 4 {
+		t.Fatalf("unexpected PO lines for XYZ999: %+v", got)
+	}
+	if len(result.POOnly) != 1 || result.POOnly[0].SKU != "NOTINV1" || poLinesTotal(result.POOnly[0].POs) != 6 {
+		t.Fatalf("unexpected PO-only items: %+v", result.POOnly)
+	}
+
+	var missing []ImportIssue
+	for _, issue := range result.Issues {
+		if issue.Kind == IssueMissingPOStatus {
+			missing = append(missing, issue)
+		}
+	}
+	if len(missing) != 2 || missing[0].Row != 4 || missing[0].SKU != "ABC123" || missing[1].Row != 9 || missing[1].SKU != "NOTINV1" {
+		t.Fatalf("missing status issues = %+v, want rows 4 and 9", missing)
+	}
+}
+
+// TestDetectPODateColumnPrefersRequiredDate verifies that the required date wins over a due date
+// to its left, and that receipt dates and bare "Expected" headers are not taken for it.
+func TestDetectPODateColumnPrefersRequiredDate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header []string
+		want   int
+	}{
+		{header: []string{"Item Code", "Receipt Date", "Due Date", "Required Date"}, want: 3},
+		{header: []string{"Item Code", "Receipt Date", "Expected", "Due Date"}, want: 3},
+		{header: []string{"Item Code", "Receipt Date", "Expected"}, want: -1},
+	}
+	for _, tt := range tests {
+		if got := detectPODateColumn([][]string{tt.header}); got != tt.want {
+			t.Fatalf("detectPODateColumn(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
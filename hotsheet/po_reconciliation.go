@@ -0,0 +1,142 @@
+package hotsheet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// poReconciliationSheetName is the tab that lists disagreements between the inventory report's
+// QTY on PO and the PO report.
+const poReconciliationSheetName = "PO Reconciliation"
+
+// Reconciliation issue labels written to the PO Reconciliation sheet.
+const (
+	poReconcileMismatch = "QTY on PO mismatch"
+	poReconcilePOOnly   = "Not in inventory report"
+)
+
+// poReconciliationHeaders are the column titles of the PO Reconciliation sheet.
+var poReconciliationHeaders = []string{"Item Code", "Description", "Status", "Issue", "Inventory QTY on PO", "PO Report QTY", "Difference", "PO Numbers"}
+
+// poReconciliationColumnWidths keeps the PO Reconciliation sheet readable without manual resizing.
+var poReconciliationColumnWidths = []float64{20, 35, 15, 24, 20, 15, 12, 30}
+
+// poOnlyItem is a SKU that appears in the PO report but not in the inventory report.
+type poOnlyItem struct {
+	SKU string
+	// Row is the 1-based PO report row of the item code.
+	Row int
+	POs []poLine
+}
+
+// poMergeResult is what mergePOData learned from the PO report besides the lines it merged into
+// the inventory entries.
+type poMergeResult struct {
+	Issues []ImportIssue
+	// POOnly lists the SKUs that had PO lines but no inventory entry, in report order.
+	POOnly []poOnlyItem
+}
+
+// poLinesTotal returns the summed quantity of the PO lines.
+func poLinesTotal(lines []poLine) int {
+	total := 0
+	for _, line := range lines {
+		total += line.Qty
+	}
+	return total
+}
+
+// hasPOMismatch reports whether the inventory report's QTY on PO disagrees with the total of the
+// PO lines merged from the PO report.
+func hasPOMismatch(e *inventoryEntry) bool {
+	return e.OnPO != poLinesTotal(e.POs)
+}
+
+// poNumbers joins the PO numbers of lines for display.
+func poNumbers(lines []poLine) string {
+	numbers := make([]string, 0, len(lines))
+	for _, line := range lines {
+		numbers = append(numbers, line.Number)
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// buildPOReconciliationRows returns the PO Reconciliation rows: mismatched entries ordered by
+// item code, followed by the PO-only SKUs in report order.
+func buildPOReconciliationRows(entries []*inventoryEntry, poOnly []poOnlyItem) [][]interface{} {
+	mismatched := make([]*inventoryEntry, 0)
+	for _, e := range entries {
+		if hasPOMismatch(e) {
+			mismatched = append(mismatched, e)
+		}
+	}
+	sort.SliceStable(mismatched, func(i, j int) bool { return mismatched[i].SKU < mismatched[j].SKU })
+
+	rows := make([][]interface{}, 0, len(mismatched)+len(poOnly))
+	for _, e := range mismatched {
+		poTotal := poLinesTotal(e.POs)
+		rows = append(rows, []interface{}{e.SKU, e.Description, e.Status, poReconcileMismatch, e.OnPO, poTotal, e.OnPO - poTotal, poNumbers(e.POs)})
+	}
+	for _, item := range poOnly {
+		poTotal := poLinesTotal(item.POs)
+		rows = append(rows, []interface{}{item.SKU, "", "", poReconcilePOOnly, "", poTotal, "", poNumbers(item.POs)})
+	}
+	return rows
+}
+
+// writePOReconciliationSheet creates the PO Reconciliation sheet in f and lists the product
+// line's QTY on PO mismatches and the run's PO-only SKUs on it.
+func writePOReconciliationSheet(f *excelize.File, entries []*inventoryEntry, poOnly []poOnlyItem) error {
+	sheetName := poReconciliationSheetName
+	if _, err := f.NewSheet(sheetName); err != nil {
+		return fmt.Errorf("failed to create %s sheet: %w", sheetName, err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(standardHeaderFill),
+		Font:      boldFont(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s header style: %w", sheetName, err)
+	}
+	dataStyle, err := f.NewStyle(&excelize.Style{Alignment: centeredAlignment(), Border: thinBlackBorder()})
+	if err != nil {
+		return fmt.Errorf("failed to create %s data style: %w", sheetName, err)
+	}
+
+	for c, h := range poReconciliationHeaders {
+		cell, _ := excelize.CoordinatesToCellName(c+1, 1)
+		if err := f.SetCellValue(sheetName, cell, h); err != nil {
+			return fmt.Errorf("failed to set %s header %s: %w", sheetName, cell, err)
+		}
+		col, _ := excelize.ColumnNumberToName(c + 1)
+		if err := f.SetColWidth(sheetName, col, col, poReconciliationColumnWidths[c]); err != nil {
+			return fmt.Errorf("failed to set width for %s column %s: %w", sheetName, col, err)
+		}
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(poReconciliationHeaders))
+	if err := f.SetCellStyle(sheetName, "A1", lastCol+"1", headerStyle); err != nil {
+		return fmt.Errorf("failed to style %s header row: %w", sheetName, err)
+	}
+
+	for i, values := range buildPOReconciliationRows(entries, poOnly) {
+		rowNum := i + 2
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
+			return fmt.Errorf("failed to write %s row %d: %w", sheetName, rowNum, err)
+		}
+		if err := f.SetCellStyle(sheetName, cell, fmt.Sprintf("%s%d", lastCol, rowNum), dataStyle); err != nil {
+			return fmt.Errorf("failed to style %s row %d: %w", sheetName, rowNum, err)
+		}
+	}
+
+	if err := f.AutoFilter(sheetName, fmt.Sprintf("A1:%s1", lastCol), nil); err != nil {
+		return fmt.Errorf("failed to set autofilter for %s: %w", sheetName, err)
+	}
+	return nil
+}
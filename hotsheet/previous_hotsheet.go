@@ -0,0 +1,277 @@
+package hotsheet
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// dateStampLayout is the layout of the {date} in output file names.
+const dateStampLayout = "20060102"
+
+// hotsheetItem holds the figures of one item row that the Changes sheet compares between runs.
+type hotsheetItem struct {
+	SKU         string
+	Description string
+	// Sheet is the standard sheet (Everyday, Winter, or Spring) the item is on.
+	Sheet  string
+	Status string
+	OnHand int
+	OnPO   int
+	MTOYTD float64
+	MTOPY  float64
+	// HasMTOYTD and HasMTOPY are false when an earlier hotsheet's MTO cell was blank or could not
+	// be read.
+	HasMTOYTD bool
+	HasMTOPY  bool
+}
+
+// previousHotsheet holds the item rows of an earlier hotsheet, keyed by item code and grouped by
+// the prefix of the tabs they were read from: "" for a product line's hotsheet, or the product
+// line's prefix, such as "BAS ", for a consolidated workbook.
+type previousHotsheet struct {
+	path  string
+	items map[string]map[string]hotsheetItem
+}
+
+// itemsFor returns the items read from the first of tabPrefixes the hotsheet has tabs for, or nil
+// when it has none of them. The unprefixed tabs of a product line's own hotsheet are only used
+// when the file is named like lineFileName, the line's hotsheet name with {date} in place of the
+// date; ok is false when they belong to another product line.
+func (p *previousHotsheet) itemsFor(lineFileName string, tabPrefixes ...string) (items map[string]hotsheetItem, ok bool) {
+	for _, prefix := range tabPrefixes {
+		items, found := p.items[prefix]
+		if !found {
+			continue
+		}
+		if prefix == "" {
+			if _, matches := hotsheetNameDate(filepath.Base(p.path), lineFileName); !matches {
+				return nil, false
+			}
+		}
+		return items, true
+	}
+	return nil, true
+}
+
+// previousHotsheetPath returns the earlier hotsheet a new one named from defaultTemplate is
+// compared with, following CompareWith, or "" when there is none.
+func (o GenerateOptions) previousHotsheetPath(defaultTemplate, productLine, dateStamp string) (string, error) {
+	dir := o.outputDir()
+	if o.CompareWith != "" {
+		info, err := os.Stat(o.CompareWith)
+		if err != nil {
+			return "", fmt.Errorf("failed to open the previous hotsheet: %w", err)
+		}
+		if !info.IsDir() {
+			return o.CompareWith, nil
+		}
+		dir = o.CompareWith
+	}
+	return findPreviousHotsheet(dir, o.fileName(defaultTemplate, productLine, "{date}"), dateStamp)
+}
+
+// findPreviousHotsheet returns the file in dir named like name, ignoring case, with the latest
+// YYYYMMDD date in place of {date} that is no later than dateStamp. A name without {date} only
+// matches itself. It returns "" when no file matches or dir does not exist.
+func findPreviousHotsheet(dir, name, dateStamp string) (string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to search %s for the previous hotsheet: %w", dir, err)
+	}
+
+	found, foundDate := "", ""
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		date, ok := hotsheetNameDate(dirEntry.Name(), name)
+		if !ok {
+			continue
+		}
+		if date == "" {
+			return filepath.Join(dir, dirEntry.Name()), nil
+		}
+		if date > dateStamp || date <= foundDate {
+			continue
+		}
+		found, foundDate = filepath.Join(dir, dirEntry.Name()), date
+	}
+	return found, nil
+}
+
+// hotsheetNameDate reports whether the file name candidate matches name, ignoring case, with a
+// valid YYYYMMDD date in place of {date}, and returns that date. A name without {date} only
+// matches itself and has no date.
+func hotsheetNameDate(candidate, name string) (string, bool) {
+	prefix, suffix, dated := strings.Cut(name, "{date}")
+	if !dated {
+		return "", strings.EqualFold(candidate, name)
+	}
+	if len(candidate) != len(prefix)+len(dateStampLayout)+len(suffix) ||
+		!strings.EqualFold(candidate[:len(prefix)], prefix) ||
+		!strings.EqualFold(candidate[len(candidate)-len(suffix):], suffix) {
+		return "", false
+	}
+	date := candidate[len(prefix) : len(prefix)+len(dateStampLayout)]
+	if _, err := time.Parse(dateStampLayout, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+// readPreviousHotsheet reads the item rows of every standard tab of the hotsheet at path. Tabs
+// are found by name, so a product line's hotsheet and a consolidated workbook can both be read,
+// and columns by their header labels, so hotsheets written with or without PO columns or
+// formulas work alike.
+func readPreviousHotsheet(path string) (*previousHotsheet, error) {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open previous hotsheet %s: %w", path, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	previous := &previousHotsheet{path: path, items: make(map[string]map[string]hotsheetItem)}
+	for _, sheetName := range f.GetSheetList() {
+		for _, season := range standardSheetNames {
+			prefix, ok := strings.CutSuffix(sheetName, season)
+			if !ok {
+				continue
+			}
+			if previous.items[prefix] == nil {
+				previous.items[prefix] = make(map[string]hotsheetItem)
+			}
+			if err := readPreviousSheet(f, sheetName, season, previous.items[prefix]); err != nil {
+				return nil, fmt.Errorf("failed to read previous hotsheet %s: %w", path, err)
+			}
+		}
+	}
+	if len(previous.items) == 0 {
+		return nil, fmt.Errorf("previous hotsheet %s has no Everyday, Winter, or Spring tabs", path)
+	}
+	return previous, nil
+}
+
+// readPreviousSheet adds the item rows of one standard tab to items.
+func readPreviousSheet(f *excelize.File, sheetName, season string, items map[string]hotsheetItem) error {
+	rows, err := f.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", sheetName, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	columns := make(map[string]int, len(rows[0]))
+	for c, header := range rows[0] {
+		if _, ok := columns[strings.TrimSpace(header)]; !ok {
+			columns[strings.TrimSpace(header)] = c
+		}
+	}
+	column := func(header string) int {
+		if c, ok := columns[header]; ok {
+			return c
+		}
+		return -1
+	}
+	skuCol := column("Item Code")
+	if skuCol < 0 {
+		return fmt.Errorf("%s has no Item Code column", sheetName)
+	}
+
+	for i, row := range rows[1:] {
+		sku := strings.TrimSpace(getCell(row, skuCol))
+		if sku == "" {
+			continue
+		}
+		rowNum := i + 2
+		item := hotsheetItem{
+			SKU:         sku,
+			Description: getCell(row, column("Description")),
+			Sheet:       season,
+			Status:      strings.TrimSpace(getCell(row, column("Status"))),
+			OnHand:      parseInt(getCell(row, column("QTY on Hand"))),
+			OnPO:        parseInt(getCell(row, column("Total QTY on PO"))),
+		}
+		item.MTOYTD, item.HasMTOYTD = previousCellNumber(f, sheetName, row, column("MTO YTD"), rowNum)
+		item.MTOPY, item.HasMTOPY = previousCellNumber(f, sheetName, row, column("MTO PY"), rowNum)
+		items[sku] = item
+	}
+	return nil
+}
+
+// previousCellNumber returns the number in column col of a row read from sheetName, which is
+// sheet row rowNum. Hotsheets written with formulas are saved without results, so a blank
+// formula cell is calculated.
+func previousCellNumber(f *excelize.File, sheetName string, row []string, col, rowNum int) (float64, bool) {
+	if col < 0 {
+		return 0, false
+	}
+	text := strings.TrimSpace(getCell(row, col))
+	if text == "" {
+		cell, _ := excelize.CoordinatesToCellName(col+1, rowNum)
+		if formula, err := f.GetCellFormula(sheetName, cell); err != nil || formula == "" {
+			return 0, false
+		}
+		calculated, err := f.CalcCellValue(sheetName, cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return 0, false
+		}
+		text = strings.TrimSpace(calculated)
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	return v, err == nil
+}
+
+// changesSincePrevious compares a new hotsheet, named from defaultTemplate and fileProductLine,
+// with its previous hotsheet. tabPrefixes returns the prefixes of the tabs a product line's
+// items may have been written to, and settings returns its MTO thresholds. A product line is
+// not compared with another line's hotsheet, which a chosen CompareWith file may be; each line
+// left out adds a warning. It returns nil changes when there is no previous hotsheet or no line
+// could be compared with it.
+func (o GenerateOptions) changesSincePrevious(defaultTemplate, fileProductLine, dateStamp string, entriesByProductLine map[string][]*inventoryEntry, tabPrefixes func(productLine string) []string, settings func(productLine string) LineSettings, now time.Time) (*hotsheetChanges, []string, error) {
+	path, err := o.previousHotsheetPath(defaultTemplate, fileProductLine, dateStamp)
+	if err != nil || path == "" {
+		return nil, nil, err
+	}
+	previous, err := readPreviousHotsheet(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes := &hotsheetChanges{comparedWith: path}
+	var warnings []string
+	compared := 0
+	for _, productLine := range slices.Sorted(maps.Keys(entriesByProductLine)) {
+		items, ok := previous.itemsFor(o.fileName(defaultFileNameTemplate, productLine, "{date}"), tabPrefixes(productLine)...)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s was not compared with %s because that hotsheet is for another product line", productLine, filepath.Base(path)))
+			continue
+		}
+		compared++
+		changes.rows = append(changes.rows, compareWithPrevious(productLine, entriesByProductLine[productLine], items, settings(productLine), now)...)
+	}
+	if compared == 0 {
+		return nil, warnings, nil
+	}
+	return changes, warnings, nil
+}
+
+// productLineTabPrefixes returns the tab prefixes a product line's items may have been written
+// to: none in the product line's own hotsheet, or the line's prefix in a consolidated workbook.
+func productLineTabPrefixes(productLine string) []string {
+	prefixes, _ := consolidatedTabPrefixes([]string{productLine})
+	return []string{"", prefixes[productLine]}
+}
@@ -0,0 +1,416 @@
+package hotsheet
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/xls"
+	"github.com/xuri/excelize/v2"
+)
+
+// defaultReportSheetName is the worksheet Sage writes both the inventory and PO reports to. It is
+// used when no sheet is chosen and the workbook has a sheet by that name.
+const defaultReportSheetName = "Sheet1"
+
+// delimitedReportSeparator returns the field separator for a CSV or TSV report path, and false
+// for any other extension.
+func delimitedReportSeparator(path string) (rune, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ',', true
+	case ".tsv", ".tab":
+		return '\t', true
+	default:
+		return 0, false
+	}
+}
+
+// reportRowReader streams the rows of a Sage report one at a time, so a company-wide export never
+// has to be held in memory as a whole.
+type reportRowReader interface {
+	// Next advances to the next row. It returns false at the end of the report or when reading
+	// fails; Err tells the two apart.
+	Next() bool
+	// Row returns the cells of the current row.
+	Row() []string
+	// Fraction estimates how much of the report has been read, from 0 to 1.
+	Fraction() float64
+	// Err returns the first error hit while reading.
+	Err() error
+	// Close releases the underlying file.
+	Close() error
+}
+
+// readProgressFunc receives the fraction of a report read so far, from 0 to 1.
+type readProgressFunc func(fraction float64)
+
+// reportRowBatch is how many report rows are read between cancellation checks.
+const reportRowBatch = 1000
+
+// contextRowReader stops reading once its context is cancelled, checking before every
+// reportRowBatch rows. Err then returns the context's error.
+type contextRowReader struct {
+	reportRowReader
+	ctx  context.Context
+	read int
+	err  error
+}
+
+// withReadContext returns r wrapped so it stops when ctx is cancelled.
+func withReadContext(ctx context.Context, r reportRowReader) reportRowReader {
+	return &contextRowReader{reportRowReader: r, ctx: ctx}
+}
+
+func (r *contextRowReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.read%reportRowBatch == 0 {
+		if err := r.ctx.Err(); err != nil {
+			r.err = err
+			return false
+		}
+	}
+	r.read++
+	return r.reportRowReader.Next()
+}
+
+func (r *contextRowReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.reportRowReader.Err()
+}
+
+// openReportRows opens a Sage report for streaming. CSV and TSV files are read directly; any other
+// path is opened as a workbook and the sheet chosen by sheet is read (see resolveReportSheet).
+// Legacy Excel 97-2003 workbooks are recognized by their file signature, whatever the
+// extension, and read with the internal xls reader.
+// The returned name is the sheet that was read, or the file name for a delimited report, for use
+// in import issue locations. Callers must Close the reader.
+func openReportRows(path, sheet string) (reportRowReader, string, error) {
+	if comma, ok := delimitedReportSeparator(path); ok {
+		rows, err := openDelimitedRows(path, comma)
+		if err != nil {
+			return nil, "", err
+		}
+		return rows, filepath.Base(path), nil
+	}
+
+	legacy, err := xls.IsLegacyFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open workbook: %w", err)
+	}
+	if legacy {
+		return openLegacyReportRows(path, sheet)
+	}
+
+	wb, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open workbook: %w", err)
+	}
+	rows, sheetName, err := openWorkbookRows(wb, sheet)
+	if err != nil {
+		_ = wb.Close()
+		return nil, "", err
+	}
+	return rows, sheetName, nil
+}
+
+// workbookRowReader streams a worksheet with excelize's row iterator. The row count used for
+// progress comes from the sheet's dimension record, which excelize reads without loading the
+// sheet.
+type workbookRowReader struct {
+	wb    *excelize.File
+	rows  *excelize.Rows
+	row   []string
+	read  int
+	total int
+	err   error
+}
+
+// openWorkbookRows starts streaming the chosen sheet of an open workbook. The reader takes
+// ownership of wb and closes it.
+func openWorkbookRows(wb *excelize.File, sheet string) (*workbookRowReader, string, error) {
+	sheetName, err := resolveReportSheet(wb.GetSheetList(), sheet)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := wb.Rows(sheetName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read sheet %q: %w", sheetName, err)
+	}
+	r := &workbookRowReader{wb: wb, rows: rows}
+	if dim, err := wb.GetSheetDimension(sheetName); err == nil {
+		r.total = dimensionRowCount(dim)
+	}
+	return r, sheetName, nil
+}
+
+// dimensionRowCount returns the last row of a sheet dimension such as "A1:T5000", or 0 when the
+// dimension is missing or unreadable.
+func dimensionRowCount(dim string) int {
+	last := dim
+	if i := strings.LastIndex(dim, ":"); i >= 0 {
+		last = dim[i+1:]
+	}
+	_, row, err := excelize.CellNameToCoordinates(last)
+	if err != nil {
+		return 0
+	}
+	return row
+}
+
+func (r *workbookRowReader) Next() bool {
+	if r.err != nil || !r.rows.Next() {
+		if r.err == nil {
+			r.err = r.rows.Error()
+		}
+		return false
+	}
+	r.read++
+	r.row, r.err = r.rows.Columns()
+	if r.err != nil {
+		r.err = fmt.Errorf("failed to read row %d: %w", r.read, r.err)
+		return false
+	}
+	return true
+}
+
+func (r *workbookRowReader) Row() []string { return r.row }
+
+func (r *workbookRowReader) Fraction() float64 {
+	return readFraction(int64(r.read), int64(r.total))
+}
+
+func (r *workbookRowReader) Err() error { return r.err }
+
+func (r *workbookRowReader) Close() error {
+	rowsErr := r.rows.Close()
+	if err := r.wb.Close(); err != nil {
+		return err
+	}
+	return rowsErr
+}
+
+// openLegacyReportRows reads the chosen sheet of an Excel 97-2003 (.xls) workbook. The BIFF
+// format keeps a sheet's cells scattered through one stream, so the sheet is decoded up front and
+// its rows replayed.
+func openLegacyReportRows(path, sheet string) (reportRowReader, string, error) {
+	wb, err := xls.Open(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open .xls workbook: %w", err)
+	}
+	sheetName, err := resolveReportSheet(wb.SheetNames(), sheet)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := wb.Rows(sheetName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read sheet %q: %w", sheetName, err)
+	}
+	return &sliceRowReader{rows: rows}, sheetName, nil
+}
+
+// sliceRowReader replays rows that are already in memory.
+type sliceRowReader struct {
+	rows [][]string
+	pos  int
+}
+
+func (r *sliceRowReader) Next() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *sliceRowReader) Row() []string { return r.rows[r.pos-1] }
+
+func (r *sliceRowReader) Fraction() float64 {
+	return readFraction(int64(r.pos), int64(len(r.rows)))
+}
+
+func (r *sliceRowReader) Err() error { return nil }
+
+func (r *sliceRowReader) Close() error { return nil }
+
+// delimitedRowReader streams a CSV or TSV report. Progress is measured in bytes, since the row
+// count is not known until the end of the file.
+//
+// encoding/csv skips blank lines, so the reader replays each one as an empty row before the
+// record that follows it. Every record is then counted as the row of the line it starts on, as
+// worksheet rows are in a workbook, and import issues point at the line to fix. The extra lines of
+// a quoted field that spans lines are replayed as empty rows too.
+type delimitedRowReader struct {
+	file   *os.File
+	name   string
+	reader *csv.Reader
+	skip   int64
+	size   int64
+	row    []string
+	err    error
+	// nextLine is the line after the one the last record started on.
+	nextLine int
+	// blanks counts the empty rows still to replay before held, the record read after them.
+	blanks int
+	held   []string
+}
+
+// openDelimitedRows opens a CSV or TSV report. Rows may have different field counts and stray
+// quotes are tolerated, since Sage does not always quote free-text fields.
+func openDelimitedRows(path string, comma rune) (*delimitedRowReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	r := &delimitedRowReader{file: file, name: filepath.Base(path), nextLine: 1}
+	if info, err := file.Stat(); err == nil {
+		r.size = info.Size()
+	}
+
+	br := bufio.NewReader(file)
+	// Excel adds a UTF-8 byte-order mark when saving "CSV UTF-8"; drop it so the first header
+	// label still matches.
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		_, _ = br.Discard(3)
+		r.skip = 3
+	}
+
+	r.reader = csv.NewReader(br)
+	r.reader.Comma = comma
+	r.reader.FieldsPerRecord = -1
+	r.reader.LazyQuotes = true
+	return r, nil
+}
+
+func (r *delimitedRowReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.blanks > 0 {
+		r.blanks--
+		return true
+	}
+	if r.held != nil {
+		r.row, r.held = r.held, nil
+		return true
+	}
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		r.err = fmt.Errorf("failed to read %s: %w", r.name, err)
+		return false
+	}
+
+	start, _ := r.reader.FieldPos(0)
+	blanks := start - r.nextLine
+	r.nextLine = start + 1
+	if blanks > 0 {
+		r.row, r.blanks, r.held = nil, blanks-1, record
+		return true
+	}
+	r.row = record
+	return true
+}
+
+func (r *delimitedRowReader) Row() []string { return r.row }
+
+func (r *delimitedRowReader) Fraction() float64 {
+	return readFraction(r.skip+r.reader.InputOffset(), r.size)
+}
+
+func (r *delimitedRowReader) Err() error { return r.err }
+
+func (r *delimitedRowReader) Close() error { return r.file.Close() }
+
+// readFraction returns done/total clamped to 0..1, or 0 when the total is unknown.
+func readFraction(done, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	if done >= total {
+		return 1
+	}
+	return float64(done) / float64(total)
+}
+
+// peekReportRows reads up to n rows from r so a header can be detected, and returns them with a
+// reader that replays them before continuing with the rest of r. Only the peeked rows are held
+// in memory.
+func peekReportRows(r reportRowReader, n int) ([][]string, reportRowReader, error) {
+	var head [][]string
+	for len(head) < n && r.Next() {
+		head = append(head, r.Row())
+	}
+	if err := r.Err(); err != nil {
+		return nil, nil, err
+	}
+	return head, &peekedRowReader{reportRowReader: r, head: head}, nil
+}
+
+// peekedRowReader replays the rows taken by peekReportRows before resuming the wrapped reader.
+type peekedRowReader struct {
+	reportRowReader
+	head [][]string
+	pos  int
+}
+
+func (r *peekedRowReader) Next() bool {
+	if r.pos < len(r.head) {
+		r.pos++
+		return true
+	}
+	// Step past the replayed rows once so Row switches to the wrapped reader.
+	r.pos = len(r.head) + 1
+	return r.reportRowReader.Next()
+}
+
+func (r *peekedRowReader) Row() []string {
+	if r.pos <= len(r.head) {
+		return r.head[r.pos-1]
+	}
+	return r.reportRowReader.Row()
+}
+
+// resolveReportSheet picks the worksheet to read from a workbook's sheets. An empty selector uses
+// Sheet1 when it exists and the first sheet otherwise. Other selectors match a sheet name,
+// ignoring case; a whole number that names no sheet selects a sheet by its 1-based position.
+func resolveReportSheet(sheets []string, selector string) (string, error) {
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("workbook has no sheets")
+	}
+
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		for _, name := range sheets {
+			if name == defaultReportSheetName {
+				return name, nil
+			}
+		}
+		return sheets[0], nil
+	}
+
+	for _, name := range sheets {
+		if strings.EqualFold(name, selector) {
+			return name, nil
+		}
+	}
+	if idx, err := strconv.Atoi(selector); err == nil {
+		if idx < 1 || idx > len(sheets) {
+			return "", fmt.Errorf("sheet %d is out of range; the workbook has %d sheet(s)", idx, len(sheets))
+		}
+		return sheets[idx-1], nil
+	}
+	return "", fmt.Errorf("sheet %q not found; available sheets: %s", selector, strings.Join(sheets, ", "))
+}
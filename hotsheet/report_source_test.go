@@ -0,0 +1,215 @@
+package hotsheet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestLoadInventoryEntriesReadsDelimitedReports verifies that CSV and TSV exports go through the
+// same header and block parsing as workbooks.
+func TestLoadInventoryEntriesReadsDelimitedReports(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		sep  string
+	}{
+		{name: "inventory.csv", sep: ","},
+		{name: "inventory.tsv", sep: "\t"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []string{
+				"\xef\xbb\xbf" + strings.Join(testinputs.InventoryHeader, tt.sep),
+				tt.sep + "ABC123",
+				"",
+				strings.Join(testinputs.InventoryValueRow("BAS", "Birthday", "25"), tt.sep),
+			}
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+				t.Fatalf("WriteFile returned error: %v", err)
+			}
+
+			entries, issues, err := loadInventoryEntries(context.Background(), path, "", nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("loadInventoryEntries returned error: %v", err)
+			}
+			if len(issues) != 0 {
+				t.Fatalf("expected no import issues, got %+v", issues)
+			}
+			item, ok := entries["ABC123"]
+			if !ok || item.OnHand != 25 || item.ProductLine != "BAS" {
+				t.Fatalf("unexpected entries: %+v", entries)
+			}
+		})
+	}
+}
+
+// TestResolveReportSheet verifies the default, index, and name sheet selectors.
+func TestResolveReportSheet(t *testing.T) {
+	t.Parallel()
+
+	wb := excelize.NewFile()
+	defer func() {
+		_ = wb.Close()
+	}()
+	_ = wb.SetSheetName("Sheet1", "Summary")
+	_, _ = wb.NewSheet("Inventory")
+
+	for selector, want := range map[string]string{"": "Summary", "2": "Inventory", "inventory": "Inventory"} {
+		got, err := resolveReportSheet(wb.GetSheetList(), selector)
+		if err != nil || got != want {
+			t.Fatalf("resolveReportSheet(%q) = %q, %v; want %q", selector, got, err, want)
+		}
+	}
+	for _, selector := range []string{"3", "Missing"} {
+		if _, err := resolveReportSheet(wb.GetSheetList(), selector); err == nil {
+			t.Fatalf("expected an error for selector %q", selector)
+		}
+	}
+}
+
+// TestOpenReportRowsStreamsWithProgress verifies that peeked header rows are replayed in order,
+// gaps in a worksheet come back as empty rows, and the read fraction climbs to 1 for both
+// workbooks and delimited files.
+func TestOpenReportRowsStreamsWithProgress(t *testing.T) {
+	t.Parallel()
+
+	workbookPath := writeTestPOReport(t, [][]interface{}{
+		{"Item Code"},
+		{"ABC123"},
+		{},
+		{"DEF456"},
+		{"Item Total"},
+	})
+	csvPath := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(csvPath, []byte("Item Code\nABC123\n\"\"\nDEF456\nItem Total\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	for _, path := range []string{workbookPath, csvPath} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			reader, _, err := openReportRows(path, "")
+			if err != nil {
+				t.Fatalf("openReportRows returned error: %v", err)
+			}
+			defer func() {
+				_ = reader.Close()
+			}()
+
+			head, rows, err := peekReportRows(reader, 2)
+			if err != nil || len(head) != 2 {
+				t.Fatalf("peekReportRows = %v, %v; want 2 rows", head, err)
+			}
+
+			var firstCells []string
+			last := 0.0
+			for rows.Next() {
+				if fraction := rows.Fraction(); fraction < last {
+					t.Fatalf("fraction went backwards: %v after %v", fraction, last)
+				} else {
+					last = fraction
+				}
+				firstCells = append(firstCells, getCell(rows.Row(), 0))
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			want := []string{"Item Code", "ABC123", "", "DEF456", "Item Total"}
+			if strings.Join(firstCells, "|") != strings.Join(want, "|") {
+				t.Fatalf("rows = %q, want %q", firstCells, want)
+			}
+			if last != 1 {
+				t.Fatalf("final fraction = %v, want 1", last)
+			}
+		})
+	}
+}
+
+// TestLoadInventoryEntriesStopsWhenCancelled verifies that a cancelled context stops the inventory
+// read with the context's error.
+func TestLoadInventoryEntriesStopsWhenCancelled(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{strings.Join(testinputs.InventoryHeader, ",")}
+	for i := range 3 * reportRowBatch {
+		lines = append(lines, fmt.Sprintf(",SKU%d", i), strings.Join(testinputs.InventoryValueRow("BAS", "Birthday", "25"), ","))
+	}
+	path := filepath.Join(t.TempDir(), "inventory.csv")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	read := 0.0
+	_, _, err := loadInventoryEntries(ctx, path, "", nil, nil, nil, func(fraction float64) {
+		read = fraction
+		if fraction > 0.25 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("loadInventoryEntries() error = %v, want context.Canceled", err)
+	}
+	if read > 0.75 {
+		t.Fatalf("read %.0f%% of the report after cancelling, want it to stop within a batch", read*100)
+	}
+}
+
+// TestDelimitedRowsFollowLineNumbers verifies that blank lines, which encoding/csv skips, and the
+// extra lines of a quoted field are replayed as empty rows, so every CSV row is numbered by the
+// line it starts on and import issues point at that line.
+func TestDelimitedRowsFollowLineNumbers(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(path, []byte("Item Code\n\nABC123\n\"two\nlines\",x\n\nDEF456\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	reader, _, err := openReportRows(path, "")
+	if err != nil {
+		t.Fatalf("openReportRows returned error: %v", err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	var firstCells []string
+	for reader.Next() {
+		firstCells = append(firstCells, getCell(reader.Row(), 0))
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	want := []string{"Item Code", "", "ABC123", "two\nlines", "", "", "DEF456"}
+	if strings.Join(firstCells, "|") != strings.Join(want, "|") {
+		t.Fatalf("rows = %q, want %q", firstCells, want)
+	}
+
+	values := testinputs.InventoryValueRow("BAS", "Birthday", "lots")
+	lines := []string{
+		strings.Join(testinputs.InventoryHeader, ","),
+		"",
+		",ABC123",
+		"",
+		"",
+		strings.Join(values, ","),
+	}
+	inventoryPath := filepath.Join(t.TempDir(), "inventory.csv")
+	if err := os.WriteFile(inventoryPath, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	_, issues, err := loadInventoryEntries(context.Background(), inventoryPath, "", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("loadInventoryEntries returned error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Kind != IssueUnparseableNumber || issues[0].Row != 6 {
+		t.Fatalf("issues = %+v, want one unparseable number on line 6", issues)
+	}
+}
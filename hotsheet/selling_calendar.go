@@ -0,0 +1,395 @@
+package hotsheet
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// calendarConfigFileName is the holiday and selling-window calendar looked up in the app's config
+// folder.
+const calendarConfigFileName = "calendar.json"
+
+// windowHoliday is the window endpoint that stands for the occasion's own date.
+const windowHoliday = "holiday"
+
+// sellingMeasure is the unit a selling window is measured in when projecting sales.
+type sellingMeasure string
+
+const (
+	// measureMonths measures windows in fractional calendar months.
+	measureMonths sellingMeasure = "months"
+	// measureDays measures windows in whole days, counting both ends.
+	measureDays sellingMeasure = "days"
+)
+
+// calendarConfig is the layout of the calendar file.
+type calendarConfig struct {
+	// SeasonStarts gives the "MM-DD" each season starts selling. Occasions without windows of
+	// their own sell from their season's start through the holiday.
+	SeasonStarts map[string]string       `json:"seasonStarts"`
+	Occasions    []calendarOccasionEntry `json:"occasions"`
+}
+
+// calendarOccasionEntry is one occasion in the calendar file.
+type calendarOccasionEntry struct {
+	// Names lists the occasion spellings the entry covers, matched ignoring case.
+	Names []string `json:"names"`
+	// Date is the occasion's date rule (see holidayDateRuleSyntax).
+	Date string `json:"date"`
+	// Display replaces the date shown on the Data Insights sheet.
+	Display string `json:"display,omitempty"`
+	// Windows lists the selling windows in calendar order. Endpoints are "MM-DD" or "holiday".
+	Windows []sellingWindowEntry `json:"windows,omitempty"`
+	// Measure is "months" (the default) or "days".
+	Measure string `json:"measure,omitempty"`
+}
+
+// sellingWindowEntry is one selling window in the calendar file.
+type sellingWindowEntry struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// defaultCalendarConfig returns the built-in calendar, used when no calendar file exists.
+func defaultCalendarConfig() calendarConfig {
+	return calendarConfig{
+		SeasonStarts: map[string]string{
+			seasonSpring: "01-01",
+			// Winter holidays don't really start selling until June 15.
+			seasonWinter: "06-15",
+		},
+		Occasions: []calendarOccasionEntry{
+			{
+				// Valentine's Day cards sell in an early-year run through Feb 14, then a late-year
+				// run from Nov 16 through Dec 31, so the occasion stays in progress until year end.
+				Names:   []string{"VALENTINE'S DAY", "VALENTINES DAY"},
+				Date:    "02-14",
+				Windows: []sellingWindowEntry{{Start: "01-01", End: "02-14"}, {Start: "11-16", End: "12-31"}},
+				Measure: string(measureDays),
+			},
+			{Names: []string{"ST PATRICKS DAY", "ST. PATRICK'S DAY"}, Date: "03-17"},
+			{Names: []string{"EASTER"}, Date: "easter"},
+			{Names: []string{"MOTHER'S DAY", "MOTHERS DAY"}, Date: "second Sunday of May"},
+			{Names: []string{"GRADUATION"}, Date: "06-15", Display: "mid-June"},
+			{Names: []string{"FATHER'S DAY", "FATHERS DAY"}, Date: "third Sunday of June"},
+			{Names: []string{"INDEPENDENCE DAY"}, Date: "07-04"},
+			{Names: []string{"HALLOWEEN"}, Date: "10-31"},
+			{Names: []string{"VETERAN'S DAY", "VETERANS DAY"}, Date: "11-11"},
+			{Names: []string{"THANKSGIVING"}, Date: "fourth Thursday of November"},
+			{Names: []string{"HANUKKAH"}, Date: "hanukkah"},
+			{Names: []string{"HOLIDAY", "CHRISTMAS"}, Date: "12-25"},
+		},
+	}
+}
+
+// calendarDay is a window endpoint: a fixed month and day, or the occasion's date.
+type calendarDay struct {
+	Month   time.Month
+	Day     int
+	Holiday bool
+}
+
+// sellingWindow is one parsed selling window.
+type sellingWindow struct {
+	Start, End calendarDay
+}
+
+// calendarOccasion is one parsed calendar entry.
+type calendarOccasion struct {
+	date    holidayDateRule
+	display string
+	windows []sellingWindow
+	measure sellingMeasure
+}
+
+// holidayCalendar holds the occasion dates and selling windows used by the Data Insights sheet.
+type holidayCalendar struct {
+	seasonStarts map[string]calendarDay
+	occasions    map[string]*calendarOccasion
+}
+
+// builtinHolidayCalendar is used when no calendar is supplied.
+var builtinHolidayCalendar = mustHolidayCalendar(defaultCalendarConfig())
+
+// newHolidayCalendar validates and parses a calendar config.
+func newHolidayCalendar(cfg calendarConfig) (*holidayCalendar, error) {
+	cal := &holidayCalendar{
+		// Seasons the file leaves out keep the built-in starts.
+		seasonStarts: map[string]calendarDay{
+			seasonSpring: {Month: time.January, Day: 1},
+			seasonWinter: {Month: time.June, Day: 15},
+		},
+		occasions: make(map[string]*calendarOccasion),
+	}
+	for name, text := range cfg.SeasonStarts {
+		season, ok := canonicalSeason(name)
+		if !ok || season == seasonEveryday {
+			return nil, fmt.Errorf("season start %q: season must be %s or %s", name, seasonSpring, seasonWinter)
+		}
+		day, err := parseCalendarDay(text, false)
+		if err != nil {
+			return nil, fmt.Errorf("season start %q: %w", name, err)
+		}
+		cal.seasonStarts[season] = day
+	}
+
+	for i, entry := range cfg.Occasions {
+		if len(entry.Names) == 0 {
+			return nil, fmt.Errorf("occasion %d: no names", i+1)
+		}
+		label := fmt.Sprintf("occasion %q", entry.Names[0])
+		occ, err := parseCalendarOccasion(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+		for _, name := range entry.Names {
+			key := strings.ToUpper(strings.TrimSpace(name))
+			if _, dup := cal.occasions[key]; dup {
+				return nil, fmt.Errorf("%s: %q is listed more than once", label, name)
+			}
+			cal.occasions[key] = occ
+		}
+	}
+	return cal, nil
+}
+
+// parseCalendarOccasion parses the date rule, windows, and measure of one entry.
+func parseCalendarOccasion(entry calendarOccasionEntry) (*calendarOccasion, error) {
+	date, err := parseHolidayDateRule(entry.Date)
+	if err != nil {
+		return nil, err
+	}
+	occ := &calendarOccasion{date: date, display: strings.TrimSpace(entry.Display), measure: measureMonths}
+	switch strings.ToLower(strings.TrimSpace(entry.Measure)) {
+	case "", string(measureMonths):
+	case string(measureDays):
+		occ.measure = measureDays
+	default:
+		return nil, fmt.Errorf("measure %q must be %q or %q", entry.Measure, measureMonths, measureDays)
+	}
+
+	for i, w := range entry.Windows {
+		start, err := parseCalendarDay(w.Start, true)
+		if err != nil {
+			return nil, fmt.Errorf("window %d start: %w", i+1, err)
+		}
+		end, err := parseCalendarDay(w.End, true)
+		if err != nil {
+			return nil, fmt.Errorf("window %d end: %w", i+1, err)
+		}
+		if !start.Holiday && !end.Holiday && dayOrder(end) < dayOrder(start) {
+			return nil, fmt.Errorf("window %d ends before it starts; split windows that wrap past December 31", i+1)
+		}
+		occ.windows = append(occ.windows, sellingWindow{Start: start, End: end})
+	}
+	return occ, nil
+}
+
+// parseCalendarDay parses a window endpoint. "holiday" is only accepted when allowHoliday is set.
+func parseCalendarDay(text string, allowHoliday bool) (calendarDay, error) {
+	s := strings.ToLower(strings.TrimSpace(text))
+	if allowHoliday && s == windowHoliday {
+		return calendarDay{Holiday: true}, nil
+	}
+	month, day, ok := parseMonthDay(s)
+	if !ok {
+		if allowHoliday {
+			return calendarDay{}, fmt.Errorf("date %q must be \"MM-DD\" or %q", text, windowHoliday)
+		}
+		return calendarDay{}, fmt.Errorf("date %q must be \"MM-DD\"", text)
+	}
+	return calendarDay{Month: month, Day: day}, nil
+}
+
+// dayOrder returns a sortable month-and-day key.
+func dayOrder(d calendarDay) int {
+	return int(d.Month)*100 + d.Day
+}
+
+// mustHolidayCalendar builds a calendar from a config known to be valid.
+func mustHolidayCalendar(cfg calendarConfig) *holidayCalendar {
+	cal, err := newHolidayCalendar(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cal
+}
+
+// loadHolidayCalendar reads the calendar file at path. When optional is set, a missing file is
+// not an error: the built-in calendar is returned with found=false.
+func loadHolidayCalendar(path string, optional bool) (cal *holidayCalendar, found bool, err error) {
+	var cfg calendarConfig
+	found, err = readJSONConfig(path, "calendar", optional, &cfg)
+	if err != nil {
+		return nil, found, err
+	}
+	if !found {
+		return builtinHolidayCalendar, false, nil
+	}
+	if len(cfg.Occasions) == 0 {
+		return nil, true, fmt.Errorf("invalid calendar %s: no occasions defined", path)
+	}
+	cal, err = newHolidayCalendar(cfg)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid calendar %s: %w", path, err)
+	}
+	return cal, true, nil
+}
+
+// resolveHolidayCalendar loads the calendar from path, or from the default location when path is
+// empty. Only the default file may be missing.
+func resolveHolidayCalendar(path string, logger *slog.Logger) (*holidayCalendar, error) {
+	optional := path == ""
+	if optional {
+		var err error
+		if path, err = userConfigFilePath(calendarConfigFileName); err != nil {
+			// Without a config directory there is no file to read, so the built-in calendar applies.
+			if logger != nil {
+				logger.Warn("using built-in calendar", "err", err)
+			}
+			return builtinHolidayCalendar, nil
+		}
+	}
+	cal, found, err := loadHolidayCalendar(path, optional)
+	if err != nil {
+		return nil, err
+	}
+	if logger != nil {
+		logger.Info("calendar loaded", "path", path, "fromFile", found, "occasions", len(cal.occasions))
+	}
+	return cal, nil
+}
+
+// occasionSchedule is a calendar occasion resolved for one year.
+type occasionSchedule struct {
+	// Known is false for seasonal occasions missing from the calendar.
+	Known   bool
+	Date    time.Time
+	Display string
+	// Windows are resolved selling windows; each End is the last moment of its final day.
+	Windows []resolvedWindow
+	Measure sellingMeasure
+}
+
+// resolvedWindow is a selling window for a specific year.
+type resolvedWindow struct {
+	Start, End time.Time
+}
+
+// schedule resolves a seasonal occasion for the year of now. Occasions missing from the calendar
+// sell from their season's start through the end of the year and have no date to show. A nil
+// calendar uses the built-in one.
+func (c *holidayCalendar) schedule(section, occasion string, now time.Time) occasionSchedule {
+	if c == nil {
+		c = builtinHolidayCalendar
+	}
+	year, loc := now.Year(), now.Location()
+	seasonStart := c.seasonStarts[section]
+	if seasonStart.Month == 0 {
+		seasonStart = calendarDay{Month: time.January, Day: 1}
+	}
+
+	occ, ok := c.occasions[strings.ToUpper(strings.TrimSpace(occasion))]
+	if !ok {
+		return occasionSchedule{
+			Display: "N/A",
+			Windows: []resolvedWindow{{
+				Start: time.Date(year, seasonStart.Month, seasonStart.Day, 0, 0, 0, 0, loc),
+				End:   time.Date(year, time.December, 31, 23, 59, 59, 0, loc),
+			}},
+			Measure: measureMonths,
+		}
+	}
+
+	month, day := occ.date(year)
+	s := occasionSchedule{
+		Known:   true,
+		Date:    time.Date(year, month, day, 0, 0, 0, 0, loc),
+		Display: occ.display,
+		Measure: occ.measure,
+	}
+	resolve := func(d calendarDay) time.Time {
+		if d.Holiday {
+			return s.Date
+		}
+		return time.Date(year, d.Month, d.Day, 0, 0, 0, 0, loc)
+	}
+	windows := occ.windows
+	if len(windows) == 0 {
+		windows = []sellingWindow{{Start: seasonStart, End: calendarDay{Holiday: true}}}
+	}
+	for _, w := range windows {
+		end := resolve(w.End)
+		s.Windows = append(s.Windows, resolvedWindow{
+			Start: resolve(w.Start),
+			End:   time.Date(end.Year(), end.Month(), end.Day(), 23, 59, 59, 0, loc),
+		})
+	}
+
+	if s.Display == "" {
+		if len(s.Windows) > 1 {
+			// Split windows are shown directly so the sheet explains the longer season.
+			parts := make([]string, 0, len(s.Windows))
+			for _, w := range s.Windows {
+				parts = append(parts, w.Start.Format("Jan 2")+" - "+w.End.Format("Jan 2"))
+			}
+			s.Display = strings.Join(parts, ", ")
+		} else {
+			s.Display = s.Date.Format("January 2")
+		}
+	}
+	return s
+}
+
+// progress reports how much of the selling season has passed at now, in the schedule's measure.
+// started is false before the first window opens and complete is true once the last window has
+// closed. Both counts are at least 1 so projections never divide by zero.
+func (s occasionSchedule) progress(now time.Time) (current, total float64, started, complete bool) {
+	for _, w := range s.Windows {
+		length := s.measureBetween(w.Start, w.End)
+		total += length
+		switch {
+		case now.Before(w.Start):
+		case now.After(w.End):
+			current += length
+		default:
+			current += s.measureBetween(w.Start, now)
+		}
+	}
+	if len(s.Windows) > 0 {
+		started = !now.Before(s.Windows[0].Start)
+		complete = !now.Before(s.Windows[len(s.Windows)-1].End)
+	}
+	if current <= 0 {
+		current = 1
+	}
+	if total <= 0 {
+		total = 1
+	}
+	return current, total, started, complete
+}
+
+// measureBetween measures from start through the day of end. Days count both ends; months follow
+// the month-progress math used across the workbook. A window opening on January 1 is measured
+// from the start of the year, so Spring projections keep the year-to-date month progress of the
+// whole workbook instead of losing the first day of January.
+func (s occasionSchedule) measureBetween(start, end time.Time) float64 {
+	if s.Measure == measureDays {
+		days := float64(end.YearDay() - start.YearDay() + 1)
+		if days < 0 {
+			return 0
+		}
+		return days
+	}
+	var startMonths float64
+	if start.YearDay() > 1 {
+		startMonths = monthsThroughForDate(start.Year(), start.Month(), start.Day(), start.Location())
+	}
+	months := monthsThroughForDate(end.Year(), end.Month(), end.Day(), end.Location()) - startMonths
+	if months < 0 {
+		return 0
+	}
+	return months
+}
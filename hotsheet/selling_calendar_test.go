@@ -0,0 +1,79 @@
+package hotsheet
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadHolidayCalendarAppliesFileWindows verifies that a calendar file can add occasions,
+// move a season start, and turn Valentine's Day into an ordinary single-window occasion.
+func TestLoadHolidayCalendarAppliesFileWindows(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), calendarConfigFileName)
+	config := `{
+		"seasonStarts": {"winter": "09-01"},
+		"occasions": [
+			{"names": ["Diwali"], "date": "11-01"},
+			{"names": ["Valentine's Day"], "date": "02-14"},
+			{"names": ["Lunar New Year"], "date": "02-17", "windows": [{"start": "01-15", "end": "holiday"}], "measure": "days"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	cal, found, err := loadHolidayCalendar(path, false)
+	if err != nil || !found {
+		t.Fatalf("loadHolidayCalendar = %v, %v; want the file calendar", found, err)
+	}
+
+	before := time.Date(2026, time.August, 15, 12, 0, 0, 0, time.UTC)
+	if info := dataInsightsDateInfo(cal, "Winter", "DIWALI", before); info.Started || info.Display != "November 1" {
+		t.Fatalf("expected Diwali to wait for the September 1 season start, got %+v", info)
+	}
+	during := time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+	_, projected, status := projectDataInsightsRow(cal, "Winter", "Diwali", 100, 0, currentMonthsThrough(during), during)
+	want := 100 * (monthsThroughSinceDate(2026, time.September, 1, time.November, 1, time.UTC) / monthsThroughSinceDate(2026, time.September, 1, time.October, 1, time.UTC))
+	if math.Abs(projected-want) > 1e-9 || !strings.HasPrefix(status, "IN PROGRESS:") {
+		t.Fatalf("Diwali projection = %.9f %q, want %.9f in progress", projected, status, want)
+	}
+
+	march := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	if info := dataInsightsDateInfo(cal, "Spring", "Valentine's Day", march); !info.Complete || info.Display != "February 14" {
+		t.Fatalf("expected a single-window Valentine's Day to be complete in March, got %+v", info)
+	}
+
+	feb := time.Date(2026, time.February, 1, 12, 0, 0, 0, time.UTC)
+	if info := dataInsightsDateInfo(cal, "Spring", "Lunar New Year", feb); info.CurrentSelling != 18 || info.TotalSelling != 34 {
+		t.Fatalf("expected 18 of 34 selling days for Lunar New Year, got %+v", info)
+	}
+	if info := dataInsightsDateInfo(cal, "Spring", "Easter", feb); info.SortKey != 999999 || info.Display != "N/A" {
+		t.Fatalf("expected occasions left out of the file to be unknown, got %+v", info)
+	}
+}
+
+// TestLoadHolidayCalendarRejectsBadEntries verifies that mistakes in the calendar file are
+// reported instead of silently ignored.
+func TestLoadHolidayCalendarRejectsBadEntries(t *testing.T) {
+	t.Parallel()
+
+	for name, config := range map[string]string{
+		"bad date":     `{"occasions": [{"names": ["Arbor Day"], "date": "last Funday of April"}]}`,
+		"wrap window":  `{"occasions": [{"names": ["Valentine's Day"], "date": "02-14", "windows": [{"start": "11-16", "end": "02-14"}]}]}`,
+		"unknown key":  `{"occasions": [{"names": ["Easter"], "date": "easter", "window": []}]}`,
+		"bad measure":  `{"occasions": [{"names": ["Easter"], "date": "easter", "measure": "weeks"}]}`,
+		"no occasions": `{"seasonStarts": {"Spring": "02-01"}}`,
+	} {
+		path := filepath.Join(t.TempDir(), calendarConfigFileName)
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+		if _, _, err := loadHolidayCalendar(path, false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package hotsheet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// settingsConfigFileName is the hotsheet settings file looked up in the app's config folder.
+const settingsConfigFileName = "settings.json"
+
+// Settings are the user-editable hotsheet settings: the values every product line uses, plus
+// optional per-product-line overrides keyed by product line code.
+type Settings struct {
+	// Formulas writes the standard sheets' derived columns as Excel formulas with conditional
+	// formatting, so they recalculate and recolor when the hotsheet is edited.
+	Formulas bool `json:"formulas,omitempty"`
+	// Workers is how many product-line workbooks are built at once; zero uses one per CPU.
+	Workers int `json:"workers,omitempty"`
+	// Statuses lists the item statuses the inventory report uses besides Rundown and
+	// Discontinued. When set, any other status is reported as an import issue; when empty, only
+	// near misspellings of Rundown and Discontinued are.
+	Statuses     []string                `json:"statuses,omitempty"`
+	Default      LineSettings            `json:"default"`
+	ProductLines map[string]LineSettings `json:"productLines,omitempty"`
+}
+
+// LineSettings controls the MTO shading and sales-season lengths of one product line's standard
+// sheets. Zero fields inherit the default settings, and the defaults inherit the built-in values.
+type LineSettings struct {
+	// RedMonths and YellowMonths are the MTO thresholds: a value at or below RedMonths is red, at
+	// or below YellowMonths yellow, and anything higher green.
+	RedMonths    float64 `json:"redMonths,omitempty"`
+	YellowMonths float64 `json:"yellowMonths,omitempty"`
+	// YTDFills shade the MTO YTD column and PYFills the MTO PY column.
+	YTDFills MTOFills `json:"ytdFills"`
+	PYFills  MTOFills `json:"pyFills"`
+	// SeasonMonths is the sales-season length MTO PY spreads prior-year sales across.
+	SeasonMonths SeasonMonths `json:"seasonMonths"`
+}
+
+// MTOFills are the "#RRGGBB" fill colors of one MTO column's three bands.
+type MTOFills struct {
+	Red    string `json:"red,omitempty"`
+	Yellow string `json:"yellow,omitempty"`
+	Green  string `json:"green,omitempty"`
+}
+
+// SeasonMonths holds the sales-season length of each standard sheet, in months.
+type SeasonMonths struct {
+	Everyday float64 `json:"everyday,omitempty"`
+	Winter   float64 `json:"winter,omitempty"`
+	Spring   float64 `json:"spring,omitempty"`
+}
+
+// builtinLineSettings are the thresholds, colors, and season lengths the hotsheet has always used.
+// MTO YTD uses lighter shades than MTO PY to keep the two columns visually distinct.
+var builtinLineSettings = LineSettings{
+	RedMonths:    1,
+	YellowMonths: 3,
+	YTDFills:     MTOFills{Red: "#FFCCCC", Yellow: "#FFFFCC", Green: "#CCFFCC"},
+	PYFills:      MTOFills{Red: "#FF6666", Yellow: "#FFCC33", Green: "#66FF66"},
+	SeasonMonths: SeasonMonths{Everyday: 12, Winter: 6.5, Spring: 5},
+}
+
+// fillColorPattern matches the "#RRGGBB" colors accepted for fills.
+var fillColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// DefaultSettings returns the built-in settings with no product-line overrides.
+func DefaultSettings() Settings {
+	return Settings{Default: builtinLineSettings}
+}
+
+// ForProductLine returns the effective settings for a product line: its override when there is
+// one, with any unset values taken from the defaults. Product line codes ignore case.
+func (s Settings) ForProductLine(productLine string) LineSettings {
+	base := s.Default.withDefaults(builtinLineSettings)
+	if key, ok := s.productLineKey(productLine); ok {
+		return s.ProductLines[key].withDefaults(base)
+	}
+	return base
+}
+
+// productLineKey returns the ProductLines key matching productLine, ignoring case and spacing.
+func (s Settings) productLineKey(productLine string) (string, bool) {
+	productLine = strings.TrimSpace(productLine)
+	for key := range s.ProductLines {
+		if strings.EqualFold(strings.TrimSpace(key), productLine) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// SetProductLine stores settings for a product line, replacing any override with a different
+// case. An empty product line sets the defaults.
+func (s *Settings) SetProductLine(productLine string, line LineSettings) {
+	productLine = strings.TrimSpace(productLine)
+	if productLine == "" {
+		s.Default = line
+		return
+	}
+	if key, ok := s.productLineKey(productLine); ok {
+		delete(s.ProductLines, key)
+	}
+	if s.ProductLines == nil {
+		s.ProductLines = make(map[string]LineSettings)
+	}
+	s.ProductLines[productLine] = line
+}
+
+// WorkerCount returns how many product-line workbooks to build at once.
+func (s Settings) WorkerCount() int {
+	if s.Workers > 0 {
+		return s.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// Validate reports the first problem with the worker count, the statuses, the defaults, or any
+// product line's effective settings.
+func (s Settings) Validate() error {
+	if s.Workers < 0 {
+		return fmt.Errorf("workers %d must not be negative", s.Workers)
+	}
+	for _, status := range s.Statuses {
+		if strings.TrimSpace(status) == "" {
+			return errors.New("statuses must not be blank")
+		}
+	}
+	if err := s.ForProductLine("").validate(); err != nil {
+		return fmt.Errorf("default settings: %w", err)
+	}
+
+	keys := make([]string, 0, len(s.ProductLines))
+	for key := range s.ProductLines {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		name := strings.ToUpper(strings.TrimSpace(key))
+		if name == "" {
+			return errors.New("product line settings need a product line code")
+		}
+		if seen[name] {
+			return fmt.Errorf("product line %q has more than one entry", key)
+		}
+		seen[name] = true
+		if err := s.ProductLines[key].withDefaults(s.ForProductLine("")).validate(); err != nil {
+			return fmt.Errorf("product line %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills l's unset values from base.
+func (l LineSettings) withDefaults(base LineSettings) LineSettings {
+	l.RedMonths = orDefault(l.RedMonths, base.RedMonths)
+	l.YellowMonths = orDefault(l.YellowMonths, base.YellowMonths)
+	l.YTDFills = l.YTDFills.withDefaults(base.YTDFills)
+	l.PYFills = l.PYFills.withDefaults(base.PYFills)
+	l.SeasonMonths.Everyday = orDefault(l.SeasonMonths.Everyday, base.SeasonMonths.Everyday)
+	l.SeasonMonths.Winter = orDefault(l.SeasonMonths.Winter, base.SeasonMonths.Winter)
+	l.SeasonMonths.Spring = orDefault(l.SeasonMonths.Spring, base.SeasonMonths.Spring)
+	return l
+}
+
+// withDefaults fills f's unset colors from base.
+func (f MTOFills) withDefaults(base MTOFills) MTOFills {
+	if f.Red == "" {
+		f.Red = base.Red
+	}
+	if f.Yellow == "" {
+		f.Yellow = base.Yellow
+	}
+	if f.Green == "" {
+		f.Green = base.Green
+	}
+	return f
+}
+
+// orDefault returns v, or def when v is unset.
+func orDefault(v, def float64) float64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// validate checks fully resolved settings.
+func (l LineSettings) validate() error {
+	if l.RedMonths <= 0 {
+		return fmt.Errorf("red threshold %s must be above 0 months", formatMonths(l.RedMonths))
+	}
+	if l.YellowMonths < l.RedMonths {
+		return fmt.Errorf("yellow threshold %s must not be below the red threshold %s", formatMonths(l.YellowMonths), formatMonths(l.RedMonths))
+	}
+	for _, fill := range []struct{ name, color string }{
+		{"MTO YTD red", l.YTDFills.Red}, {"MTO YTD yellow", l.YTDFills.Yellow}, {"MTO YTD green", l.YTDFills.Green},
+		{"MTO PY red", l.PYFills.Red}, {"MTO PY yellow", l.PYFills.Yellow}, {"MTO PY green", l.PYFills.Green},
+	} {
+		if !fillColorPattern.MatchString(fill.color) {
+			return fmt.Errorf("%s fill %q is not a #RRGGBB color", fill.name, fill.color)
+		}
+	}
+	for _, season := range []struct {
+		name   string
+		months float64
+	}{
+		{seasonEveryday, l.SeasonMonths.Everyday}, {seasonWinter, l.SeasonMonths.Winter}, {seasonSpring, l.SeasonMonths.Spring},
+	} {
+		if season.months <= 0 || season.months > 12 {
+			return fmt.Errorf("%s season length %s must be above 0 and at most 12 months", season.name, formatMonths(season.months))
+		}
+	}
+	return nil
+}
+
+// forSeason returns the sales-season length of a standard sheet; other names use Everyday's.
+func (m SeasonMonths) forSeason(season string) float64 {
+	switch season {
+	case seasonWinter:
+		return m.Winter
+	case seasonSpring:
+		return m.Spring
+	default:
+		return m.Everyday
+	}
+}
+
+// MTO bands returned by mtoBand.
+const (
+	mtoBandRed    = "red"
+	mtoBandYellow = "yellow"
+	mtoBandGreen  = "green"
+)
+
+// mtoBand returns the band an MTO value falls in under the thresholds.
+func (l LineSettings) mtoBand(mto float64) string {
+	switch {
+	case mto <= l.RedMonths:
+		return mtoBandRed
+	case mto <= l.YellowMonths:
+		return mtoBandYellow
+	default:
+		return mtoBandGreen
+	}
+}
+
+// mtoFill returns the fill for an MTO value under the thresholds.
+func (l LineSettings) mtoFill(fills MTOFills, mto float64) string {
+	switch l.mtoBand(mto) {
+	case mtoBandRed:
+		return fills.Red
+	case mtoBandYellow:
+		return fills.Yellow
+	default:
+		return fills.Green
+	}
+}
+
+// formatMonths formats a month count without trailing zeros, such as 6.5 or 12.
+func formatMonths(months float64) string {
+	return strconv.FormatFloat(months, 'f', -1, 64)
+}
+
+// SettingsPath returns the location of the settings file in the user config directory.
+func SettingsPath() (string, error) {
+	return userConfigFilePath(settingsConfigFileName)
+}
+
+// LoadSettings reads the settings file at path. A missing file is not an error: the built-in
+// settings are returned, as for the SettingsPath file before it is first saved.
+func LoadSettings(path string) (Settings, error) {
+	return loadSettings(path, true)
+}
+
+// loadSettings reads the settings file at path. When optional is set, a missing file is not an
+// error: the built-in settings are returned.
+func loadSettings(path string, optional bool) (Settings, error) {
+	var s Settings
+	found, err := readJSONConfig(path, "settings", optional, &s)
+	if err != nil {
+		return Settings{}, err
+	}
+	if !found {
+		return DefaultSettings(), nil
+	}
+	if err := s.Validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid settings %s: %w", path, err)
+	}
+	s.Default = s.Default.withDefaults(builtinLineSettings)
+	return s, nil
+}
+
+// SaveSettings validates s and writes it to path, creating the folder when needed.
+func SaveSettings(path string, s Settings) error {
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settings folder: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write settings %s: %w", path, err)
+	}
+	return nil
+}
+
+// resolveSettings loads the settings from path, or from the default location when path is empty.
+// Only the default file may be missing.
+func resolveSettings(path string, logger *slog.Logger) (Settings, error) {
+	optional := path == ""
+	if optional {
+		var err error
+		if path, err = SettingsPath(); err != nil {
+			// Without a config directory there is no file to read, so the built-in settings apply.
+			if logger != nil {
+				logger.Warn("using built-in settings", "err", err)
+			}
+			return DefaultSettings(), nil
+		}
+	}
+	s, err := loadSettings(path, optional)
+	if err != nil {
+		return Settings{}, err
+	}
+	if logger != nil {
+		logger.Info("settings loaded", "path", path, "productLineOverrides", len(s.ProductLines))
+	}
+	return s, nil
+}
@@ -0,0 +1,137 @@
+package hotsheet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// TestLoadSettingsAppliesProductLineOverrides verifies that product-line overrides inherit unset
+// values from the defaults and that the standard sheets follow the resolved settings.
+func TestLoadSettingsAppliesProductLineOverrides(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), settingsConfigFileName)
+	config := `{
+		"default": {"seasonMonths": {"winter": 8}},
+		"productLines": {"bas": {"redMonths": 2, "ytdFills": {"red": "#112233"}}}
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	settings, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings returned error: %v", err)
+	}
+
+	bas := settings.ForProductLine(" BAS ")
+	if bas.RedMonths != 2 || bas.YellowMonths != 3 || bas.SeasonMonths.Winter != 8 || bas.SeasonMonths.Spring != 5 {
+		t.Fatalf("unexpected BAS settings %+v", bas)
+	}
+	if bas.YTDFills.Red != "#112233" || bas.YTDFills.Yellow != "#FFFFCC" || bas.PYFills.Red != "#FF6666" {
+		t.Fatalf("unexpected BAS fills %+v / %+v", bas.YTDFills, bas.PYFills)
+	}
+	if other := settings.ForProductLine("OAT"); other.RedMonths != 1 || other.SeasonMonths.Winter != 8 {
+		t.Fatalf("expected OAT to use the defaults, got %+v", other)
+	}
+	if got := bas.mtoFill(bas.YTDFills, 1.5); got != "#112233" {
+		t.Fatalf("MTO YTD fill at 1.5 months = %q, want the configured red", got)
+	}
+
+	f := newProductLineWorkbook()
+	defer func() {
+		_ = f.Close()
+	}()
+	if err := writeStandardSheets(context.Background(), f, nil, false, standardSheetOptions{Settings: bas}); err != nil {
+		t.Fatalf("writeStandardSheets returned error: %v", err)
+	}
+	comments, err := f.GetComments("Winter")
+	if err != nil {
+		t.Fatalf("GetComments returned error: %v", err)
+	}
+	var pyComment string
+	for _, c := range comments {
+		if cell, _ := excelize.CoordinatesToCellName(7, 1); c.Cell == cell {
+			pyComment = c.Text
+		}
+	}
+	if !strings.Contains(pyComment, "Winter=8, Spring=5, Everyday=12") || !strings.Contains(pyComment, "red at or below 2 month(s), yellow at or below 3") {
+		t.Fatalf("MTO PY comment does not match the settings: %q", pyComment)
+	}
+
+	// Saving and reloading keeps the override without duplicating product lines that differ by case.
+	settings.SetProductLine("BAS", bas)
+	if err := SaveSettings(path, settings); err != nil {
+		t.Fatalf("SaveSettings returned error: %v", err)
+	}
+	reloaded, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings after save returned error: %v", err)
+	}
+	if len(reloaded.ProductLines) != 1 || reloaded.ForProductLine("bas") != bas {
+		t.Fatalf("reloaded settings = %+v, want the saved BAS override", reloaded)
+	}
+}
+
+// TestSettingsValidateRejectsBadValues verifies that unusable thresholds, colors, and season
+// lengths are reported.
+func TestSettingsValidateRejectsBadValues(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		line LineSettings
+		want string
+	}{
+		{"negative red", LineSettings{RedMonths: -1}, "red threshold"},
+		{"yellow below red", LineSettings{RedMonths: 4}, "yellow threshold 3"},
+		{"bad color", LineSettings{PYFills: MTOFills{Green: "green"}}, "MTO PY green fill"},
+		{"long season", LineSettings{SeasonMonths: SeasonMonths{Spring: 13}}, "Spring season length"},
+	}
+	for _, tt := range tests {
+		settings := DefaultSettings()
+		settings.SetProductLine("BAS", tt.line)
+		err := settings.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "product line BAS") {
+			t.Errorf("%s: Validate() = %v, want an error mentioning %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestSettingsWorkerCount verifies that an unset worker count uses one worker per CPU and that a
+// negative count is rejected.
+func TestSettingsWorkerCount(t *testing.T) {
+	t.Parallel()
+
+	settings := DefaultSettings()
+	if got := settings.WorkerCount(); got != runtime.NumCPU() {
+		t.Fatalf("default WorkerCount() = %d, want %d", got, runtime.NumCPU())
+	}
+	settings.Workers = 2
+	if got := settings.WorkerCount(); got != 2 {
+		t.Fatalf("WorkerCount() = %d, want 2", got)
+	}
+	settings.Workers = -1
+	if err := settings.Validate(); err == nil || !strings.Contains(err.Error(), "workers -1") {
+		t.Fatalf("Validate() = %v, want a negative worker count error", err)
+	}
+}
+
+// TestResolveSettingsRejectsMissingChosenFile verifies that a settings file chosen explicitly must
+// exist, while a missing default file falls back to the built-in settings.
+func TestResolveSettingsRejectsMissingChosenFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "setings.json")
+	if _, err := resolveSettings(path, nil); err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("resolveSettings(%s) error = %v, want one naming the missing file", path, err)
+	}
+	if s, err := loadSettings(path, true); err != nil || s.Default.RedMonths != DefaultSettings().Default.RedMonths {
+		t.Fatalf("loadSettings of a missing default file = %+v, %v; want the built-in settings", s, err)
+	}
+}
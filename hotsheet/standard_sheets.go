@@ -1,8 +1,10 @@
 package hotsheet
 
 import (
+	"context"
 	"fmt"
-	"strings"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/xuri/excelize/v2"
@@ -11,45 +13,143 @@ import (
 // standardSheetNames preserves the original workbook tab order used by the hotsheet.
 var standardSheetNames = []string{"Everyday", "Winter", "Spring"}
 
+// standardSheetPOSlots is how many PO lines the standard sheets show per item. Every line is
+// still listed on the Open POs sheet.
+const standardSheetPOSlots = 2
+
+// standardSheetFormulaInputHeaders are the raw input columns added after the report columns when
+// the derived columns are written as formulas, so every formula can reference its own row.
+var standardSheetFormulaInputHeaders = []string{
+	"QTY on SO",
+	"QTY on BO",
+	"QTY Sold YTD",
+	"QTY Issued YTD",
+	"QTY Sold PY",
+	"QTY Issued PY",
+}
+
+// standardSheetTrendHeaders are the columns measured from the snapshot history, added when the
+// product line has a trend baseline.
+var standardSheetTrendHeaders = []string{
+	"On Hand Velocity (4 Wk)",
+	"Weeks of Supply",
+	"Weeks of Supply Trend",
+}
+
+// standardSheetOptions carries the per-product-line choices that shape the standard sheets.
+type standardSheetOptions struct {
+	// Settings supplies the MTO thresholds, fills, and season lengths.
+	Settings LineSettings
+	// ClassRules supply the Class column prefixes; nil uses the built-in rules.
+	ClassRules *classPrefixRuleSet
+	// Formulas writes QTY on SO+BO, QTY Available, MTO YTD, MTO PY, and the Sold+Issued totals as
+	// Excel formulas over the row's own cells, so edits made in the hotsheet recalculate.
+	Formulas bool
+	// Now is the time the sales pace and stockout projections are measured from; zero uses the
+	// current time.
+	Now time.Time
+	// TabPrefix is put in front of each season's tab name, such as "BAS " for the product line's
+	// tabs in a consolidated workbook. The tabs must already exist.
+	TabPrefix string
+	// Trends adds the trend columns measured from the product line's snapshot history; nil
+	// leaves them out.
+	Trends *productLineTrends
+}
+
 // writeStandardSheets writes the Everyday, Winter, and Spring tabs, their headers, their rows,
 // and the shared widths and filters used by the standard hotsheet layout.
-func writeStandardSheets(f *excelize.File, entries []*inventoryEntry, hasPO bool) error {
-	headers, mtoYtdIdx, mtoPyIdx := buildStandardSheetHeaders(hasPO)
+func writeStandardSheets(ctx context.Context, f *excelize.File, entries []*inventoryEntry, hasPO bool, opts standardSheetOptions) error {
+	headers, cols := buildStandardSheetHeaders(hasPO, opts.Formulas, opts.Trends != nil)
+	styles := newStyleCache(f)
 
-	for _, sheetName := range standardSheetNames {
-		if err := writeStandardSheetHeaders(f, sheetName, headers, hasPO); err != nil {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	monthsThrough := currentMonthsThrough(now)
+	for _, season := range standardSheetNames {
+		if err := writeStandardSheet(ctx, f, season, entries, hasPO, now, monthsThrough, headers, cols, styles, opts); err != nil {
 			return err
 		}
 	}
+	if opts.Formulas {
+		// The formulas are written without cached results, so Excel has to calculate on open.
+		fullCalc := true
+		if err := f.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &fullCalc}); err != nil {
+			return fmt.Errorf("failed to enable recalculation on load: %w", err)
+		}
+	}
 
-	monthsThrough := currentMonthsThrough(time.Now())
-	for _, sheetName := range standardSheetNames {
-		if err := writeStandardSheetRows(f, sheetName, entries, hasPO, monthsThrough, mtoYtdIdx, mtoPyIdx); err != nil {
-			return err
+	return nil
+}
+
+// writeStandardSheet writes one season's standard tab with a StreamWriter. The header comments,
+// conditional formats, and autofilter live outside the sheet data, so they are set on the
+// worksheet first and written out with it when the stream is flushed.
+func writeStandardSheet(ctx context.Context, f *excelize.File, season string, entries []*inventoryEntry, hasPO bool, now time.Time, monthsThrough float64, headers []string, cols standardSheetColumns, styles *styleCache, opts standardSheetOptions) error {
+	var sheetEntries []*inventoryEntry
+	for _, e := range entries {
+		if entrySeason(e) == season {
+			sheetEntries = append(sheetEntries, e)
 		}
 	}
 
-	if err := applyStandardSheetWidths(f, headers); err != nil {
+	sheetName := opts.TabPrefix + season
+	addStandardSheetHeaderComments(f, sheetName, headers, opts)
+	if err := applyStandardSheetConditionalFormats(f, sheetName, len(sheetEntries)+1, len(headers), cols, opts.Settings); err != nil {
 		return err
 	}
-	if err := applyStandardSheetFilters(f, headers); err != nil {
+	if err := applyStandardSheetFilter(f, sheetName, headers); err != nil {
 		return err
 	}
 
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for writing: %w", sheetName, err)
+	}
+	// Column widths have to be set before the first row is streamed.
+	for i, h := range headers {
+		if err := sw.SetColWidth(i+1, i+1, standardSheetWidthForHeader(h)); err != nil {
+			return fmt.Errorf("failed to set width for %s column %d: %w", sheetName, i+1, err)
+		}
+	}
+	if err := writeStandardSheetHeaderRow(sw, sheetName, headers, styles); err != nil {
+		return err
+	}
+	rows := newStandardSheetRowBuilder(sheetName, season, hasPO, now, monthsThrough, headers, cols, styles, opts)
+	if err := writeStandardSheetRows(ctx, sw, rows, sheetEntries); err != nil {
+		return err
+	}
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sheetName, err)
+	}
 	return nil
 }
 
+// standardSheetColumns holds the zero-based indexes of the standard-sheet columns that get
+// value-dependent shading. Columns absent from the current layout are -1.
+type standardSheetColumns struct {
+	TotalOnPO   int
+	MTOYTD      int
+	MTOPY       int
+	StockoutGap int
+	Status      int
+}
+
 // buildStandardSheetHeaders returns the header row used by the three standard report sheets and
-// the indexes of the MTO columns used for conditional formatting.
-func buildStandardSheetHeaders(hasPO bool) ([]string, int, int) {
+// the indexes of the columns used for conditional formatting. The PO-only columns are -1 when no
+// PO report was supplied, trends adds the trend columns, and formulas appends the raw input
+// columns the formulas reference.
+func buildStandardSheetHeaders(hasPO, formulas, trends bool) ([]string, standardSheetColumns) {
 	headers := []string{"Item Code", "QTY on Hand"}
 	if hasPO {
-		headers = append(headers,
-			"PO Num 1",
-			"QTY on PO 1",
-			"PO Num 2",
-			"QTY on PO 2",
-		)
+		for slot := 1; slot <= standardSheetPOSlots; slot++ {
+			headers = append(headers,
+				fmt.Sprintf("PO Num %d", slot),
+				fmt.Sprintf("QTY on PO %d", slot),
+				fmt.Sprintf("PO %d Date", slot),
+			)
+		}
 	}
 	headers = append(headers,
 		"Total QTY on PO",
@@ -57,6 +157,18 @@ func buildStandardSheetHeaders(hasPO bool) ([]string, int, int) {
 		"QTY Available",
 		"MTO YTD",
 		"MTO PY",
+	)
+	if hasPO {
+		headers = append(headers,
+			"Projected Stockout",
+			"Next PO Arrival",
+			"Stockout Gap (Days)",
+		)
+	}
+	if trends {
+		headers = append(headers, standardSheetTrendHeaders...)
+	}
+	headers = append(headers,
 		"QTY Sold+Issued YTD",
 		"QTY Sold+Issued PY",
 		"Class",
@@ -69,25 +181,33 @@ func buildStandardSheetHeaders(hasPO bool) ([]string, int, int) {
 		"Dollar Sold YTD",
 		"Dollar Sold PY",
 	)
+	if formulas {
+		headers = append(headers, standardSheetFormulaInputHeaders...)
+	}
 
-	mtoYtdIdx, mtoPyIdx := -1, -1
+	cols := standardSheetColumns{TotalOnPO: -1, MTOYTD: -1, MTOPY: -1, StockoutGap: -1, Status: -1}
 	for i, h := range headers {
 		switch h {
+		case "Total QTY on PO":
+			if hasPO {
+				cols.TotalOnPO = i
+			}
 		case "MTO YTD":
-			mtoYtdIdx = i
+			cols.MTOYTD = i
 		case "MTO PY":
-			mtoPyIdx = i
+			cols.MTOPY = i
+		case "Stockout Gap (Days)":
+			cols.StockoutGap = i
+		case "Status":
+			cols.Status = i
 		}
 	}
-	return headers, mtoYtdIdx, mtoPyIdx
+	return headers, cols
 }
 
-// writeStandardSheetHeaders writes the standard header row, applies the existing header style,
-// and keeps the explanatory MTO comments attached to the corresponding columns.
-func writeStandardSheetHeaders(f *excelize.File, sheetName string, headers []string, hasPO bool) error {
-	_ = hasPO // The header layout already captures whether PO columns should be present.
-
-	headerStyle, err := f.NewStyle(&excelize.Style{
+// writeStandardSheetHeaderRow streams the standard header row with the existing header style.
+func writeStandardSheetHeaderRow(sw *excelize.StreamWriter, sheetName string, headers []string, styles *styleCache) error {
+	headerStyle, err := styles.id(&excelize.Style{
 		Alignment: centeredAlignment(),
 		Border:    thinBlackBorder(),
 		Fill:      patternFill(standardHeaderFill),
@@ -97,22 +217,31 @@ func writeStandardSheetHeaders(f *excelize.File, sheetName string, headers []str
 		return fmt.Errorf("failed to create standard header style: %w", err)
 	}
 
+	row := make([]interface{}, len(headers))
+	for c, h := range headers {
+		row[c] = excelize.Cell{StyleID: headerStyle, Value: h}
+	}
+	if err := sw.SetRow("A1", row); err != nil {
+		return fmt.Errorf("failed to write header row on %s: %w", sheetName, err)
+	}
+	return nil
+}
+
+// addStandardSheetHeaderComments keeps the explanatory MTO, stockout, and trend comments attached
+// to the corresponding header cells. The comments quote the configured season lengths and color
+// thresholds and the date of the trend baseline.
+func addStandardSheetHeaderComments(f *excelize.File, sheetName string, headers []string, opts standardSheetOptions) {
+	settings := opts.Settings
 	for c, h := range headers {
 		cell, _ := excelize.CoordinatesToCellName(c+1, 1)
-		if err := f.SetCellValue(sheetName, cell, h); err != nil {
-			return fmt.Errorf("failed to set header cell %s on %s: %w", cell, sheetName, err)
-		}
-		if err := f.SetCellStyle(sheetName, cell, cell, headerStyle); err != nil {
-			return fmt.Errorf("failed to style header cell %s on %s: %w", cell, sheetName, err)
-		}
 
 		// Keep the original worksheet guidance available directly in the header row.
 		if h == "MTO YTD" {
 			cmt := excelize.Comment{
 				Cell:   cell,
 				Author: "Shane DuPrey",
-				Text:   "MTO YTD = QTY Available / ((QTY Sold+Issued YTD + QTY on SO+BO) / (monthsThrough + 1)). monthsThrough is the number of months completed in the current year (fractional). This shows months till out using year-to-date sales pace including current sales orders/backorders.",
-				Height: 190,
+				Text:   "MTO YTD = QTY Available / ((QTY Sold+Issued YTD + QTY on SO+BO) / (monthsThrough + 1)). monthsThrough is the number of months completed in the current year (fractional). This shows months till out using year-to-date sales pace including current sales orders/backorders. " + mtoThresholdNote(settings),
+				Height: 210,
 				Width:  200,
 			}
 			_ = f.AddComment(sheetName, cmt)
@@ -121,211 +250,424 @@ func writeStandardSheetHeaders(f *excelize.File, sheetName string, headers []str
 			cmt := excelize.Comment{
 				Cell:   cell,
 				Author: "Shane DuPrey",
-				Text:   "MTO PY = QTY Available / ((QTY Sold+Issued PY) / (salesSeason + 1)). salesSeason used: Winter=6.5, Spring=5, Everyday=12. This shows months till out using prior-year sales scaled to the season length.",
-				Height: 180,
+				Text: fmt.Sprintf("MTO PY = QTY Available / ((QTY Sold+Issued PY) / (salesSeason + 1)). salesSeason used: Winter=%s, Spring=%s, Everyday=%s. This shows months till out using prior-year sales scaled to the season length. %s",
+					formatMonths(settings.SeasonMonths.Winter), formatMonths(settings.SeasonMonths.Spring), formatMonths(settings.SeasonMonths.Everyday), mtoThresholdNote(settings)),
+				Height: 200,
 				Width:  180,
 			}
 			_ = f.AddComment(sheetName, cmt)
 		}
+		if h == "Projected Stockout" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   "Projected Stockout = today + (QTY on Hand - QTY on SO+BO) / ((QTY Sold+Issued YTD + QTY on SO+BO) / (monthsThrough + 1)) months. This uses the MTO YTD sales pace but leaves open PO quantity out because it has not arrived yet.",
+				Height: 190,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+		if h == "Stockout Gap (Days)" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   "Stockout Gap = days between Projected Stockout and Next PO Arrival when the item runs out before the PO lands (highlighted), otherwise 0. Blank when no open PO has an expected date.",
+				Height: 150,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+		if opts.Trends == nil {
+			continue
+		}
+		since := opts.Trends.since.Format("01/02/2006")
+		if h == "On Hand Velocity (4 Wk)" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   fmt.Sprintf("On Hand Velocity = change in QTY on Hand per week since the %s snapshot, the oldest saved run from the last four weeks that is at least a week old. Negative while stock is falling. Blank for items that were not in that snapshot.", since),
+				Height: 170,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+		if h == "Weeks of Supply" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   fmt.Sprintf("Weeks of Supply = QTY Available / observed weekly sales, where observed weekly sales is the growth in QTY Sold+Issued YTD since the %s snapshot per week. Blank without sales since then or when the snapshot is from last year.", since),
+				Height: 170,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+		if h == "Weeks of Supply Trend" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   fmt.Sprintf("Weeks of Supply Trend = change in QTY Available since the %s snapshot / observed weekly sales: how many weeks of supply were gained (positive) or used up (negative) since then.", since),
+				Height: 150,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
 	}
+}
 
-	return nil
+// mtoThresholdNote describes the MTO color bands for the header comments.
+func mtoThresholdNote(settings LineSettings) string {
+	return fmt.Sprintf("Shaded red at or below %s month(s), yellow at or below %s, and green above.",
+		formatMonths(settings.RedMonths), formatMonths(settings.YellowMonths))
 }
 
-// writeStandardSheetRows writes the report rows for one standard worksheet, preserving the
-// current derived values, class-prefix behavior, and conditional coloring rules.
-func writeStandardSheetRows(f *excelize.File, sheetName string, entries []*inventoryEntry, hasPO bool, monthsThrough float64, mtoYtdIdx, mtoPyIdx int) error {
-	rowIdx := 2
-	for _, e := range entries {
-		sh := mapOccasion(e.Occasion)
-		if sh != sheetName {
-			continue
+// sheetRowBatch is how many sheet rows are written between cancellation checks.
+const sheetRowBatch = 1000
+
+// writeStandardSheetRows streams the report rows of one standard worksheet from row 2 down. Every
+// entry must belong to the builder's sheet. Writing stops with ctx's error, checked every
+// sheetRowBatch rows, once ctx is cancelled.
+func writeStandardSheetRows(ctx context.Context, sw *excelize.StreamWriter, rows *standardSheetRowBuilder, entries []*inventoryEntry) error {
+	for i, e := range entries {
+		if i%sheetRowBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 		}
-
-		// Determine the sales-season window used for MTO PY calculations.
-		// Winter and Spring use their shorter merchandising seasons, while Everyday uses
-		// the full year so the historical sales pace stays consistent with the workbook notes.
-		salesSeason := 12.0
-		switch sh {
-		case "Winter":
-			salesSeason = 6.5
-		case "Spring":
-			salesSeason = 5.0
-		default:
-			salesSeason = 12.0
+		rowIdx := i + 2
+		row, err := rows.cells(e, rowIdx)
+		if err != nil {
+			return err
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowIdx)
+		if err := sw.SetRow(cell, row); err != nil {
+			return fmt.Errorf("failed to write %s row %d: %w", rows.sheetName, rowIdx, err)
 		}
+	}
+	return nil
+}
 
-		// Calculate the derived values used by the standard report layout.
-		onSOBO := e.OnSO + e.OnBO
-		totalInventory := e.OnHand + e.OnPO
-		totalAvail := totalInventory - onSOBO
+// standardSheetRowBuilder turns inventory entries into the styled cells of one standard sheet's
+// rows, preserving the current derived values and class-prefix behavior. The MTO, status, and
+// stockout gap shading comes from the sheet's conditional formats, so it follows sorting,
+// filtering, and edits.
+type standardSheetRowBuilder struct {
+	sheetName     string
+	hasPO         bool
+	now           time.Time
+	monthsThrough float64
+	salesSeason   float64
+	headers       []string
+	cols          standardSheetColumns
+	dollarYTDCol  int
+	dollarPYCol   int
+	// trendCol is the first trend column, or -1 without them.
+	trendCol int
+	styles   *styleCache
+	// cellStyles memoizes the style IDs of the few fill and number format pairs data cells use.
+	cellStyles map[standardCellStyle]int
+	opts       standardSheetOptions
+}
 
-		totalSoldYTD := e.YTDSold + max(e.YTDIssued, 0)
-		totalSoldPY := e.SoldPY + max(e.IssuedPY, 0)
-		soldPerMonthYTD := (float64(totalSoldYTD) + float64(onSOBO)) / monthsThrough
-		soldPerMonthPY := float64(totalSoldPY) / salesSeason
+// standardCellStyle is the part of a standard sheet data cell's style that varies by cell.
+type standardCellStyle struct {
+	fill   string
+	numFmt string
+}
 
-		mtoYTD := float64(totalAvail) / (soldPerMonthYTD + 1)
-		mtoPY := float64(totalAvail) / (soldPerMonthPY + 1)
+// newStandardSheetRowBuilder returns the row builder for season's standard sheet, the tab named
+// sheetName.
+func newStandardSheetRowBuilder(sheetName, season string, hasPO bool, now time.Time, monthsThrough float64, headers []string, cols standardSheetColumns, styles *styleCache, opts standardSheetOptions) *standardSheetRowBuilder {
+	return &standardSheetRowBuilder{
+		sheetName:     sheetName,
+		hasPO:         hasPO,
+		now:           now,
+		monthsThrough: monthsThrough,
+		// Determine the sales-season window used for MTO PY calculations. Winter and Spring
+		// normally use their shorter merchandising seasons, while Everyday uses the full year, so
+		// the historical sales pace stays consistent with the workbook notes.
+		salesSeason:  opts.Settings.SeasonMonths.forSeason(season),
+		headers:      headers,
+		cols:         cols,
+		dollarYTDCol: slices.Index(headers, "Dollar Sold YTD"),
+		dollarPYCol:  slices.Index(headers, "Dollar Sold PY"),
+		trendCol:     slices.Index(headers, standardSheetTrendHeaders[0]),
+		styles:       styles,
+		cellStyles:   make(map[standardCellStyle]int),
+		opts:         opts,
+	}
+}
 
-		classDesc := applyStandardDisplayClassPrefix(e)
+// cellStyle returns the ID of the bordered, centered data cell style with the given fill and
+// number format; an empty numFmt keeps the general format.
+func (b *standardSheetRowBuilder) cellStyle(key standardCellStyle) (int, error) {
+	if id, ok := b.cellStyles[key]; ok {
+		return id, nil
+	}
+	styleDef := &excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(key.fill),
+	}
+	if key.numFmt != "" {
+		numFmt := key.numFmt
+		styleDef.CustomNumFmt = &numFmt
+	}
+	id, err := b.styles.id(styleDef)
+	if err != nil {
+		return 0, err
+	}
+	b.cellStyles[key] = id
+	return id, nil
+}
+
+// standardRowFigures are the derived quantities of one standard sheet row.
+type standardRowFigures struct {
+	onSOBO          int
+	totalAvail      int
+	totalSoldYTD    int
+	totalSoldPY     int
+	soldPerMonthYTD float64
+	mtoYTD          float64
+	mtoPY           float64
+}
+
+// computeStandardRowFigures derives e's standard sheet figures, with the YTD sales pace spread
+// over monthsThrough and the prior-year pace over salesSeason.
+func computeStandardRowFigures(e *inventoryEntry, monthsThrough, salesSeason float64) standardRowFigures {
+	onSOBO := e.OnSO + e.OnBO
+	totalInventory := e.OnHand + e.OnPO
+	totalAvail := totalInventory - onSOBO
+
+	totalSoldYTD := e.YTDSold + max(e.YTDIssued, 0)
+	totalSoldPY := e.SoldPY + max(e.IssuedPY, 0)
+	soldPerMonthYTD := (float64(totalSoldYTD) + float64(onSOBO)) / monthsThrough
+	soldPerMonthPY := float64(totalSoldPY) / salesSeason
+
+	return standardRowFigures{
+		onSOBO:          onSOBO,
+		totalAvail:      totalAvail,
+		totalSoldYTD:    totalSoldYTD,
+		totalSoldPY:     totalSoldPY,
+		soldPerMonthYTD: soldPerMonthYTD,
+		mtoYTD:          float64(totalAvail) / (soldPerMonthYTD + 1),
+		mtoPY:           float64(totalAvail) / (soldPerMonthPY + 1),
+	}
+}
 
-		vals := []interface{}{
-			e.SKU,
-			e.OnHand,
+// cells returns the excelize.Cell values of e's row, which sits at sheet row rowIdx.
+func (b *standardSheetRowBuilder) cells(e *inventoryEntry, rowIdx int) ([]interface{}, error) {
+	// Calculate the derived values used by the standard report layout.
+	figures := computeStandardRowFigures(e, b.monthsThrough, b.salesSeason)
+
+	classDesc := applyStandardDisplayClassPrefix(b.opts.ClassRules, e)
+
+	vals := []interface{}{
+		e.SKU,
+		e.OnHand,
+	}
+	if b.hasPO {
+		vals = append(vals, standardSheetPOValues(e)...)
+	}
+	vals = append(vals,
+		e.OnPO,
+		figures.onSOBO,
+		figures.totalAvail,
+		figures.mtoYTD,
+		figures.mtoPY,
+	)
+	if b.hasPO {
+		stockout := projectStockout(e, figures.soldPerMonthYTD, b.now)
+		vals = append(vals, standardSheetStockoutValues(stockout)...)
+	}
+	if b.opts.Trends != nil {
+		vals = append(vals, standardSheetTrendValues(b.opts.Trends.item(e, figures.totalAvail))...)
+	}
+	vals = append(vals,
+		figures.totalSoldYTD,
+		figures.totalSoldPY,
+		classDesc,
+		e.Status,
+		e.Occasion,
+		e.Description,
+		e.UPC,
+		e.Foil,
+		e.RoyaltyCode,
+		e.DollarSoldYTD,
+		e.DollarSoldPY,
+	)
+	var formulas map[int]string
+	if b.opts.Formulas {
+		vals = append(vals, e.OnSO, e.OnBO, e.YTDSold, e.YTDIssued, e.SoldPY, e.IssuedPY)
+		formulas = standardSheetFormulas(b.headers, rowIdx, b.monthsThrough, b.salesSeason)
+	}
+
+	row := make([]interface{}, len(vals))
+	for c, v := range vals {
+		// A PO mismatch depends on the PO report rather than the row's cells, so it stays a fixed
+		// fill; the value-dependent shading comes from the conditional formats.
+		key := standardCellStyle{fill: "#FFFFFF"}
+		if c == b.cols.TotalOnPO && hasPOMismatch(e) {
+			key.fill = poMismatchFill
 		}
-		if hasPO {
-			vals = append(vals, e.PONum1, e.OnPO1, e.PONum2, e.OnPO2)
+		if c == b.dollarYTDCol || c == b.dollarPYCol {
+			key.numFmt = currencyFormat
 		}
-		vals = append(vals,
-			e.OnPO,
-			onSOBO,
-			totalAvail,
-			mtoYTD,
-			mtoPY,
-			totalSoldYTD,
-			totalSoldPY,
-			classDesc,
-			e.Status,
-			e.Occasion,
-			e.Description,
-			e.UPC,
-			e.Foil,
-			e.RoyaltyCode,
-			e.DollarSoldYTD,
-			e.DollarSoldPY,
-		)
+		if b.trendCol >= 0 && c >= b.trendCol && c < b.trendCol+len(standardSheetTrendHeaders) {
+			key.numFmt = weeksFormat
+		}
+		if _, isDate := v.(time.Time); isDate {
+			key.numFmt = dateFormat
+		}
+		style, err := b.cellStyle(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s cell style for row %d: %w", b.sheetName, rowIdx, err)
+		}
+		if formula, ok := formulas[c]; ok {
+			// Formula cells carry no cached value, matching what SetCellFormula writes.
+			row[c] = excelize.Cell{StyleID: style, Formula: formula}
+		} else {
+			row[c] = excelize.Cell{StyleID: style, Value: v}
+		}
+	}
+	return row, nil
+}
 
-		dollarYTDCol := len(vals) - 2
-		dollarPYCol := len(vals) - 1
+// standardSheetFormulas returns the formulas of the derived columns of one row, keyed by column
+// index. monthsThrough and salesSeason are written as constants so the formulas give the same
+// results as the static values would on the day the hotsheet was generated.
+func standardSheetFormulas(headers []string, row int, monthsThrough, salesSeason float64) map[int]string {
+	ref := func(header string) string {
+		cell, _ := excelize.CoordinatesToCellName(slices.Index(headers, header)+1, row)
+		return cell
+	}
+	sobo, avail := ref("QTY on SO+BO"), ref("QTY Available")
+	soldYTD, soldPY := ref("QTY Sold+Issued YTD"), ref("QTY Sold+Issued PY")
+	byHeader := map[string]string{
+		"QTY on SO+BO":        fmt.Sprintf("%s+%s", ref("QTY on SO"), ref("QTY on BO")),
+		"QTY Available":       fmt.Sprintf("%s+%s-%s", ref("QTY on Hand"), ref("Total QTY on PO"), sobo),
+		"QTY Sold+Issued YTD": fmt.Sprintf("%s+MAX(%s,0)", ref("QTY Sold YTD"), ref("QTY Issued YTD")),
+		"QTY Sold+Issued PY":  fmt.Sprintf("%s+MAX(%s,0)", ref("QTY Sold PY"), ref("QTY Issued PY")),
+		"MTO YTD":             fmt.Sprintf("%s/((%s+%s)/%s+1)", avail, soldYTD, sobo, strconv.FormatFloat(monthsThrough, 'f', -1, 64)),
+		"MTO PY":              fmt.Sprintf("%s/(%s/%s+1)", avail, soldPY, strconv.FormatFloat(salesSeason, 'f', -1, 64)),
+	}
+	formulas := make(map[int]string, len(byHeader))
+	for header, formula := range byHeader {
+		formulas[slices.Index(headers, header)] = formula
+	}
+	return formulas
+}
 
-		for c, v := range vals {
-			cell, _ := excelize.CoordinatesToCellName(c+1, rowIdx)
-			if err := f.SetCellValue(sheetName, cell, v); err != nil {
-				return fmt.Errorf("failed to write %s cell %s: %w", sheetName, cell, err)
-			}
+// applyStandardSheetConditionalFormats shades the data rows of a standard sheet with conditional
+// formats: Rundown and Discontinued rows first, so status shading still wins, then the MTO bands
+// and, with a PO report, stockout gaps.
+func applyStandardSheetConditionalFormats(f *excelize.File, sheetName string, lastRow, columnCount int, cols standardSheetColumns, settings LineSettings) error {
+	if lastRow < 2 {
+		return nil
+	}
+	lastCol, _ := excelize.ColumnNumberToName(columnCount)
+	statusCol, _ := excelize.ColumnNumberToName(cols.Status + 1)
+	statusRules := []fillConditionalFormat{
+		{Fill: "#D3D3D3", Rule: excelize.ConditionalFormatOptions{Type: "formula", Criteria: fmt.Sprintf(`$%s2="%s"`, statusCol, statusRundown)}},
+		{Fill: "#A9A9A9", Rule: excelize.ConditionalFormatOptions{Type: "formula", Criteria: fmt.Sprintf(`$%s2="%s"`, statusCol, statusDiscontinued)}},
+	}
+	if err := setFillConditionalFormats(f, sheetName, fmt.Sprintf("A2:%s%d", lastCol, lastRow), statusRules); err != nil {
+		return err
+	}
 
-			fillColor := standardSheetCellFillColor(e.Status, c, mtoYtdIdx, mtoPyIdx, mtoYTD, mtoPY, v)
-			styleDef := &excelize.Style{
-				Alignment: centeredAlignment(),
-				Border:    thinBlackBorder(),
-				Fill:      patternFill(fillColor),
-			}
-			if c == dollarYTDCol || c == dollarPYCol {
-				styleDef.CustomNumFmt = currencyNumFmt()
-			}
-			style, err := f.NewStyle(styleDef)
-			if err != nil {
-				return fmt.Errorf("failed to create %s cell style for %s: %w", sheetName, cell, err)
-			}
-			if err := f.SetCellStyle(sheetName, cell, cell, style); err != nil {
-				return fmt.Errorf("failed to style %s cell %s: %w", sheetName, cell, err)
-			}
+	for _, mto := range []struct {
+		col   int
+		fills MTOFills
+	}{{cols.MTOYTD, settings.YTDFills}, {cols.MTOPY, settings.PYFills}} {
+		col, _ := excelize.ColumnNumberToName(mto.col + 1)
+		bands := []fillConditionalFormat{
+			{Fill: mto.fills.Red, Rule: excelize.ConditionalFormatOptions{Type: "cell", Criteria: "<=", Value: formatMonths(settings.RedMonths)}},
+			{Fill: mto.fills.Yellow, Rule: excelize.ConditionalFormatOptions{Type: "cell", Criteria: "<=", Value: formatMonths(settings.YellowMonths)}},
+			{Fill: mto.fills.Green, Rule: excelize.ConditionalFormatOptions{Type: "cell", Criteria: ">", Value: formatMonths(settings.YellowMonths)}},
+		}
+		if err := setFillConditionalFormats(f, sheetName, fmt.Sprintf("%s2:%s%d", col, col, lastRow), bands); err != nil {
+			return err
 		}
-
-		rowIdx++
 	}
 
+	if cols.StockoutGap >= 0 {
+		// The gap is only positive when the item runs out before its next PO lands. It is blank
+		// text without a dated PO, which a plain "> 0" cell rule treats as greater than zero.
+		col, _ := excelize.ColumnNumberToName(cols.StockoutGap + 1)
+		gap := []fillConditionalFormat{{Fill: stockoutGapFill, Rule: excelize.ConditionalFormatOptions{Type: "formula", Criteria: fmt.Sprintf("AND(ISNUMBER(%s2),%s2>0)", col, col)}}}
+		if err := setFillConditionalFormats(f, sheetName, fmt.Sprintf("%s2:%s%d", col, col, lastRow), gap); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// applyStandardDisplayClassPrefix applies the current display-time class prefix rules while keeping the
-// original inventory class available through RawClassDesc for downstream reuse.
-func applyStandardDisplayClassPrefix(e *inventoryEntry) string {
-	classDesc := strings.TrimSpace(e.ClassDesc)
-	skuUpper := strings.ToUpper(strings.TrimSpace(e.SKU))
-	prefix := ""
-
-	// Check longer or more specific suffixes first so the display prefix is deterministic.
-	switch {
-	case strings.HasSuffix(skuUpper, "-LLB") || strings.HasSuffix(skuUpper, "LLB"):
-		prefix = "LLB - "
-	case strings.HasSuffix(skuUpper, "-TB") || strings.HasSuffix(skuUpper, "TB") || strings.HasPrefix(skuUpper, "TB"):
-		prefix = "TB - "
-	case strings.HasSuffix(skuUpper, "-WM") || strings.HasSuffix(skuUpper, "WM"):
-		prefix = "WM - "
-	case strings.HasSuffix(skuUpper, "-AN") || strings.HasSuffix(skuUpper, "AN"):
-		prefix = "AN - "
-	case strings.HasSuffix(skuUpper, "-BN") || strings.HasSuffix(skuUpper, "BN"):
-		prefix = "BN - "
-	case strings.HasSuffix(skuUpper, "BX"):
-		prefix = "BX - "
-	case strings.HasSuffix(skuUpper, "C"):
-		prefix = "Custom - "
-	}
-
-	// Product-line specific rules for SKUs ending in "B" mirror the current workbook behavior.
-	if prefix == "" && strings.HasSuffix(skuUpper, "B") {
-		pl := strings.TrimSpace(e.ProductLine)
-		switch pl {
-		case "2021":
-			if strings.HasPrefix(strings.ToUpper(e.SKU), "FC") {
-				prefix = "Bulk - "
-			} else {
-				prefix = "BX - "
-			}
-		case "BAS":
-			prefix = "Bulk - "
-		case "OAT":
-			prefix = "BX - "
+// fillConditionalFormat is a conditional format rule that fills matching cells with Fill.
+type fillConditionalFormat struct {
+	Fill string
+	Rule excelize.ConditionalFormatOptions
+}
+
+// setFillConditionalFormats adds fill rules over rangeRef. Each rule stops evaluation when it
+// matches, and rules added earlier, in this call or before it, take priority.
+func setFillConditionalFormats(f *excelize.File, sheetName, rangeRef string, formats []fillConditionalFormat) error {
+	rules := make([]excelize.ConditionalFormatOptions, 0, len(formats))
+	for _, cf := range formats {
+		format, err := f.NewConditionalStyle(&excelize.Style{Fill: patternFill(cf.Fill)})
+		if err != nil {
+			return fmt.Errorf("failed to create conditional style on %s: %w", sheetName, err)
 		}
+		rule := cf.Rule
+		rule.Format = &format
+		rule.StopIfTrue = true
+		rules = append(rules, rule)
 	}
-
-	if prefix != "" && !strings.HasPrefix(classDesc, prefix) {
-		classDesc = prefix + classDesc
+	if err := f.SetConditionalFormat(sheetName, rangeRef, rules); err != nil {
+		return fmt.Errorf("failed to set conditional formats %s on %s: %w", rangeRef, sheetName, err)
 	}
-	e.ClassDesc = classDesc
-	return classDesc
+	return nil
 }
 
-// standardSheetCellFillColor calculates the current fill color for a standard-sheet cell based on
-// MTO thresholds and the entry's status overrides.
-func standardSheetCellFillColor(status string, columnIdx, mtoYtdIdx, mtoPyIdx int, mtoYTD, mtoPY float64, value interface{}) string {
-	fillColor := "#FFFFFF"
-	if (columnIdx == mtoYtdIdx || columnIdx == mtoPyIdx) && value != nil {
-		if columnIdx == mtoYtdIdx {
-			// MTO YTD uses lighter shades than MTO PY to keep the two columns visually distinct.
-			if mtoYTD <= 1 {
-				fillColor = "#FFCCCC"
-			} else if mtoYTD <= 3 {
-				fillColor = "#FFFFCC"
-			} else {
-				fillColor = "#CCFFCC"
-			}
-		} else {
-			// MTO PY uses darker shades to match the historical-sales comparison column.
-			if mtoPY <= 1 {
-				fillColor = "#FF6666"
-			} else if mtoPY <= 3 {
-				fillColor = "#FFCC33"
-			} else {
-				fillColor = "#66FF66"
-			}
+// standardSheetPOValues returns the PO number, quantity, and expected date of the item's next
+// standardSheetPOSlots PO lines by date. Unused slots keep the historical blank number and zero
+// quantity, and unknown dates are left blank.
+func standardSheetPOValues(e *inventoryEntry) []interface{} {
+	lines := sortedPOLines(e)
+	vals := make([]interface{}, 0, standardSheetPOSlots*3)
+	for slot := 0; slot < standardSheetPOSlots; slot++ {
+		if slot >= len(lines) {
+			vals = append(vals, "", 0, "")
+			continue
+		}
+		line := lines[slot]
+		var date interface{} = ""
+		if !line.ExpectedDate.IsZero() {
+			date = line.ExpectedDate
 		}
+		vals = append(vals, line.Number, line.Qty, date)
 	}
+	return vals
+}
 
-	// Status-based shading still wins so rundown and discontinued items remain easy to spot.
-	switch status {
-	case "Rundown":
-		fillColor = "#D3D3D3"
-	case "Discontinued":
-		fillColor = "#A9A9A9"
+// standardSheetStockoutValues returns the Projected Stockout, Next PO Arrival, and Stockout Gap
+// cells. The arrival and gap stay blank when no open PO has an expected date.
+func standardSheetStockoutValues(p stockoutProjection) []interface{} {
+	if !p.HasArrival() {
+		return []interface{}{p.Stockout, "", ""}
 	}
-
-	return fillColor
+	return []interface{}{p.Stockout, p.NextArrival, p.GapDays}
 }
 
-// applyStandardSheetWidths sets the column widths used by the standard report tabs.
-func applyStandardSheetWidths(f *excelize.File, headers []string) error {
-	for _, sheetName := range standardSheetNames {
-		for i, h := range headers {
-			col, _ := excelize.ColumnNumberToName(i + 1)
-			if err := f.SetColWidth(sheetName, col, col, standardSheetWidthForHeader(h)); err != nil {
-				return fmt.Errorf("failed to set width for %s column %s: %w", sheetName, col, err)
-			}
-		}
+// standardSheetTrendValues returns the On Hand Velocity, Weeks of Supply, and Weeks of Supply
+// Trend cells, leaving unknown figures blank.
+func standardSheetTrendValues(t itemTrend) []interface{} {
+	vals := []interface{}{"", "", ""}
+	if t.hasOnHandVelocity {
+		vals[0] = t.onHandVelocity
 	}
-	return nil
+	if t.hasWeeksOfSupply {
+		vals[1], vals[2] = t.weeksOfSupply, t.weeksOfSupplyTrend
+	}
+	return vals
 }
 
 // standardSheetWidthForHeader returns the width used for one standard-sheet column header.
@@ -339,6 +681,16 @@ func standardSheetWidthForHeader(header string) float64 {
 		return 12
 	case "QTY on PO 1", "QTY on PO 2":
 		return 12
+	case "PO 1 Date", "PO 2 Date":
+		return 12
+	case "Projected Stockout", "Next PO Arrival":
+		return 18
+	case "Stockout Gap (Days)":
+		return 20
+	case "On Hand Velocity (4 Wk)", "Weeks of Supply Trend":
+		return 22
+	case "Weeks of Supply":
+		return 16
 	case "Total QTY on PO":
 		return 15
 	case "QTY on SO+BO":
@@ -370,16 +722,14 @@ func standardSheetWidthForHeader(header string) float64 {
 	}
 }
 
-// applyStandardSheetFilters applies the autofilter range used by the standard report tabs.
-func applyStandardSheetFilters(f *excelize.File, headers []string) error {
+// applyStandardSheetFilter applies the autofilter range used by the standard report tabs.
+func applyStandardSheetFilter(f *excelize.File, sheetName string, headers []string) error {
 	if len(headers) == 0 {
 		return fmt.Errorf("cannot apply autofilter to empty standard header set")
 	}
 	lastCol, _ := excelize.ColumnNumberToName(len(headers))
-	for _, sheetName := range standardSheetNames {
-		if err := f.AutoFilter(sheetName, fmt.Sprintf("A1:%s1", lastCol), nil); err != nil {
-			return fmt.Errorf("failed to set autofilter for %s: %w", sheetName, err)
-		}
+	if err := f.AutoFilter(sheetName, fmt.Sprintf("A1:%s1", lastCol), nil); err != nil {
+		return fmt.Errorf("failed to set autofilter for %s: %w", sheetName, err)
 	}
 	return nil
 }
@@ -0,0 +1,268 @@
+package hotsheet
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// This file keeps the standard sheet writer as it was before the sheets were streamed, copied
+// verbatim apart from the names, an unused context, and taking the clock from opts.Now, as the reference that
+// TestWriteStandardSheetsMatchesCellByCellOutput compares the streamed writer with. It shares
+// only the value helpers with the production code, not the row builder, the header comments, or
+// the style cache.
+
+// writeStandardSheetsCellByCell writes the standard sheets the way they were written before
+// streaming: every value, formula, width, and style is set through the worksheet API, and each
+// cell's style is created for that cell.
+func writeStandardSheetsCellByCell(_ context.Context, f *excelize.File, entries []*inventoryEntry, hasPO bool, opts standardSheetOptions) error {
+	headers, cols := buildStandardSheetHeaders(hasPO, opts.Formulas, false)
+
+	for _, sheetName := range standardSheetNames {
+		if err := writeReferenceStandardSheetHeaders(f, sheetName, headers, hasPO, opts.Settings); err != nil {
+			return err
+		}
+	}
+
+	now := opts.Now
+	monthsThrough := currentMonthsThrough(now)
+	for _, sheetName := range standardSheetNames {
+		if err := writeReferenceStandardSheetRows(f, sheetName, entries, hasPO, now, monthsThrough, headers, cols, opts); err != nil {
+			return err
+		}
+	}
+	if opts.Formulas {
+		// The formulas are written without cached results, so Excel has to calculate on open.
+		fullCalc := true
+		if err := f.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &fullCalc}); err != nil {
+			return fmt.Errorf("failed to enable recalculation on load: %w", err)
+		}
+	}
+
+	if err := applyReferenceStandardSheetWidths(f, headers); err != nil {
+		return err
+	}
+	if err := applyReferenceStandardSheetFilters(f, headers); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeReferenceStandardSheetHeaders writes the standard header row, applies the existing header
+// style, and keeps the explanatory MTO comments attached to the corresponding columns. The
+// comments quote the configured season lengths and color thresholds.
+func writeReferenceStandardSheetHeaders(f *excelize.File, sheetName string, headers []string, hasPO bool, settings LineSettings) error {
+	_ = hasPO // The header layout already captures whether PO columns should be present.
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Alignment: centeredAlignment(),
+		Border:    thinBlackBorder(),
+		Fill:      patternFill(standardHeaderFill),
+		Font:      boldFont(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create standard header style: %w", err)
+	}
+
+	for c, h := range headers {
+		cell, _ := excelize.CoordinatesToCellName(c+1, 1)
+		if err := f.SetCellValue(sheetName, cell, h); err != nil {
+			return fmt.Errorf("failed to set header cell %s on %s: %w", cell, sheetName, err)
+		}
+		if err := f.SetCellStyle(sheetName, cell, cell, headerStyle); err != nil {
+			return fmt.Errorf("failed to style header cell %s on %s: %w", cell, sheetName, err)
+		}
+
+		// Keep the original worksheet guidance available directly in the header row.
+		if h == "MTO YTD" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   "MTO YTD = QTY Available / ((QTY Sold+Issued YTD + QTY on SO+BO) / (monthsThrough + 1)). monthsThrough is the number of months completed in the current year (fractional). This shows months till out using year-to-date sales pace including current sales orders/backorders. " + mtoThresholdNote(settings),
+				Height: 210,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+		if h == "MTO PY" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text: fmt.Sprintf("MTO PY = QTY Available / ((QTY Sold+Issued PY) / (salesSeason + 1)). salesSeason used: Winter=%s, Spring=%s, Everyday=%s. This shows months till out using prior-year sales scaled to the season length. %s",
+					formatMonths(settings.SeasonMonths.Winter), formatMonths(settings.SeasonMonths.Spring), formatMonths(settings.SeasonMonths.Everyday), mtoThresholdNote(settings)),
+				Height: 200,
+				Width:  180,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+		if h == "Projected Stockout" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   "Projected Stockout = today + (QTY on Hand - QTY on SO+BO) / ((QTY Sold+Issued YTD + QTY on SO+BO) / (monthsThrough + 1)) months. This uses the MTO YTD sales pace but leaves open PO quantity out because it has not arrived yet.",
+				Height: 190,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+		if h == "Stockout Gap (Days)" {
+			cmt := excelize.Comment{
+				Cell:   cell,
+				Author: "Shane DuPrey",
+				Text:   "Stockout Gap = days between Projected Stockout and Next PO Arrival when the item runs out before the PO lands (highlighted), otherwise 0. Blank when no open PO has an expected date.",
+				Height: 150,
+				Width:  200,
+			}
+			_ = f.AddComment(sheetName, cmt)
+		}
+	}
+
+	return nil
+}
+
+// writeReferenceStandardSheetRows writes the report rows for one standard worksheet, preserving
+// the current derived values and class-prefix behavior. The MTO, status, and stockout gap shading
+// is added as conditional formats over the written rows, so it follows sorting, filtering, and
+// edits.
+func writeReferenceStandardSheetRows(f *excelize.File, sheetName string, entries []*inventoryEntry, hasPO bool, now time.Time, monthsThrough float64, headers []string, cols standardSheetColumns, opts standardSheetOptions) error {
+	settings := opts.Settings
+	dollarYTDCol := slices.Index(headers, "Dollar Sold YTD")
+	dollarPYCol := slices.Index(headers, "Dollar Sold PY")
+	rowIdx := 2
+	for _, e := range entries {
+		sh := entrySeason(e)
+		if sh != sheetName {
+			continue
+		}
+
+		// Determine the sales-season window used for MTO PY calculations. Winter and Spring
+		// normally use their shorter merchandising seasons, while Everyday uses the full year, so
+		// the historical sales pace stays consistent with the workbook notes.
+		salesSeason := settings.SeasonMonths.forSeason(sh)
+
+		// Calculate the derived values used by the standard report layout.
+		onSOBO := e.OnSO + e.OnBO
+		totalInventory := e.OnHand + e.OnPO
+		totalAvail := totalInventory - onSOBO
+
+		totalSoldYTD := e.YTDSold + max(e.YTDIssued, 0)
+		totalSoldPY := e.SoldPY + max(e.IssuedPY, 0)
+		soldPerMonthYTD := (float64(totalSoldYTD) + float64(onSOBO)) / monthsThrough
+		soldPerMonthPY := float64(totalSoldPY) / salesSeason
+
+		mtoYTD := float64(totalAvail) / (soldPerMonthYTD + 1)
+		mtoPY := float64(totalAvail) / (soldPerMonthPY + 1)
+
+		classDesc := applyStandardDisplayClassPrefix(opts.ClassRules, e)
+
+		vals := []interface{}{
+			e.SKU,
+			e.OnHand,
+		}
+		if hasPO {
+			vals = append(vals, standardSheetPOValues(e)...)
+		}
+		vals = append(vals,
+			e.OnPO,
+			onSOBO,
+			totalAvail,
+			mtoYTD,
+			mtoPY,
+		)
+		var stockout stockoutProjection
+		if hasPO {
+			stockout = projectStockout(e, soldPerMonthYTD, now)
+			vals = append(vals, standardSheetStockoutValues(stockout)...)
+		}
+		vals = append(vals,
+			totalSoldYTD,
+			totalSoldPY,
+			classDesc,
+			e.Status,
+			e.Occasion,
+			e.Description,
+			e.UPC,
+			e.Foil,
+			e.RoyaltyCode,
+			e.DollarSoldYTD,
+			e.DollarSoldPY,
+		)
+		var formulas map[int]string
+		if opts.Formulas {
+			vals = append(vals, e.OnSO, e.OnBO, e.YTDSold, e.YTDIssued, e.SoldPY, e.IssuedPY)
+			formulas = standardSheetFormulas(headers, rowIdx, monthsThrough, salesSeason)
+		}
+
+		for c, v := range vals {
+			cell, _ := excelize.CoordinatesToCellName(c+1, rowIdx)
+			if formula, ok := formulas[c]; ok {
+				if err := f.SetCellFormula(sheetName, cell, formula); err != nil {
+					return fmt.Errorf("failed to write %s formula %s: %w", sheetName, cell, err)
+				}
+			} else if err := f.SetCellValue(sheetName, cell, v); err != nil {
+				return fmt.Errorf("failed to write %s cell %s: %w", sheetName, cell, err)
+			}
+
+			// A PO mismatch depends on the PO report rather than the row's cells, so it stays a
+			// fixed fill; the value-dependent shading comes from the conditional formats.
+			fillColor := "#FFFFFF"
+			if c == cols.TotalOnPO && hasPOMismatch(e) {
+				fillColor = poMismatchFill
+			}
+			styleDef := &excelize.Style{
+				Alignment: centeredAlignment(),
+				Border:    thinBlackBorder(),
+				Fill:      patternFill(fillColor),
+			}
+			if c == dollarYTDCol || c == dollarPYCol {
+				styleDef.CustomNumFmt = currencyNumFmt()
+			}
+			if _, isDate := v.(time.Time); isDate {
+				styleDef.CustomNumFmt = dateNumFmt()
+			}
+			style, err := f.NewStyle(styleDef)
+			if err != nil {
+				return fmt.Errorf("failed to create %s cell style for %s: %w", sheetName, cell, err)
+			}
+			if err := f.SetCellStyle(sheetName, cell, cell, style); err != nil {
+				return fmt.Errorf("failed to style %s cell %s: %w", sheetName, cell, err)
+			}
+		}
+
+		rowIdx++
+	}
+
+	return applyStandardSheetConditionalFormats(f, sheetName, rowIdx-1, len(headers), cols, opts.Settings)
+}
+
+// applyReferenceStandardSheetWidths sets the column widths used by the standard report tabs.
+func applyReferenceStandardSheetWidths(f *excelize.File, headers []string) error {
+	for _, sheetName := range standardSheetNames {
+		for i, h := range headers {
+			col, _ := excelize.ColumnNumberToName(i + 1)
+			if err := f.SetColWidth(sheetName, col, col, standardSheetWidthForHeader(h)); err != nil {
+				return fmt.Errorf("failed to set width for %s column %s: %w", sheetName, col, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyReferenceStandardSheetFilters applies the autofilter range used by the standard report
+// tabs.
+func applyReferenceStandardSheetFilters(f *excelize.File, headers []string) error {
+	if len(headers) == 0 {
+		return fmt.Errorf("cannot apply autofilter to empty standard header set")
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(headers))
+	for _, sheetName := range standardSheetNames {
+		if err := f.AutoFilter(sheetName, fmt.Sprintf("A1:%s1", lastCol), nil); err != nil {
+			return fmt.Errorf("failed to set autofilter for %s: %w", sheetName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package hotsheet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// TestWriteStandardSheetsWithFormulas verifies that the formula option writes derived columns
+// that recalculate from the row's own cells and shades MTO and status with conditional formats.
+func TestWriteStandardSheetsWithFormulas(t *testing.T) {
+	t.Parallel()
+
+	entries := []*inventoryEntry{{
+		SKU: "AB100", ProductLine: "BAS", ClassDesc: "Cards", Status: "Active", Occasion: "BIRTHDAY", Season: seasonEveryday,
+		OnHand: 120, OnPO: 30, OnSO: 5, OnBO: 5, YTDSold: 40, YTDIssued: -3, SoldPY: 60, IssuedPY: 4,
+	}}
+	f := newProductLineWorkbook()
+	defer func() {
+		_ = f.Close()
+	}()
+	opts := standardSheetOptions{Settings: builtinLineSettings, Formulas: true}
+	if err := writeStandardSheets(context.Background(), f, entries, false, opts); err != nil {
+		t.Fatalf("writeStandardSheets returned error: %v", err)
+	}
+
+	headers, _ := buildStandardSheetHeaders(false, true, false)
+	if !slices.Equal(headers[len(headers)-len(standardSheetFormulaInputHeaders):], standardSheetFormulaInputHeaders) {
+		t.Fatalf("expected the raw input columns at the end, got %q", headers)
+	}
+	cell := func(header string) string {
+		name, _ := excelize.CoordinatesToCellName(slices.Index(headers, header)+1, 2)
+		return name
+	}
+	calc := func(header string) float64 {
+		t.Helper()
+		v, err := f.CalcCellValue("Everyday", cell(header), excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatalf("CalcCellValue(%s) returned error: %v", header, err)
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			t.Fatalf("%s calculated to %q, want a number", header, v)
+		}
+		return n
+	}
+
+	if formula, _ := f.GetCellFormula("Everyday", cell("MTO PY")); !strings.HasSuffix(formula, "/12+1)") {
+		t.Fatalf("MTO PY formula %q does not use the Everyday season length", formula)
+	}
+	monthsThrough := currentMonthsThrough(time.Now())
+	wantYTD := 140.0 / ((40.0+10.0)/monthsThrough + 1)
+	if got := calc("MTO YTD"); math.Abs(got-wantYTD) > 1e-9 {
+		t.Fatalf("MTO YTD = %v, want %v", got, wantYTD)
+	}
+	if got := calc("QTY Sold+Issued PY"); got != 64 {
+		t.Fatalf("QTY Sold+Issued PY = %v, want 64", got)
+	}
+
+	// A scenario edit to the row's inputs flows through the derived columns.
+	if err := f.SetCellValue("Everyday", cell("QTY on Hand"), 20); err != nil {
+		t.Fatalf("SetCellValue returned error: %v", err)
+	}
+	if got := calc("QTY Available"); got != 40 {
+		t.Fatalf("QTY Available after editing QTY on Hand = %v, want 40", got)
+	}
+
+	formats, err := f.GetConditionalFormats("Everyday")
+	if err != nil {
+		t.Fatalf("GetConditionalFormats returned error: %v", err)
+	}
+	rowRange := "A2:" + strings.TrimSuffix(cell(headers[len(headers)-1]), "2") + "2"
+	if rules := formats[rowRange]; len(rules) != 2 || !rules[0].StopIfTrue || !strings.Contains(rules[0].Criteria, `="Rundown"`) {
+		t.Fatalf("expected the status rules over %s, got %+v", rowRange, formats)
+	}
+	mtoRange := cell("MTO YTD") + ":" + cell("MTO YTD")
+	if rules := formats[mtoRange]; len(rules) != 3 || rules[0].Value != "1" || rules[2].Criteria != "greater than" {
+		t.Fatalf("expected three MTO YTD bands over %s, got %+v", mtoRange, formats)
+	}
+}
+
+// TestWriteStandardSheetsShadesWithConditionalFormats verifies that MTO, status, and stockout gap
+// shading are worksheet conditional formats rather than per-cell fills.
+func TestWriteStandardSheetsShadesWithConditionalFormats(t *testing.T) {
+	t.Parallel()
+
+	arrival := time.Now().AddDate(1, 0, 0)
+	entries := []*inventoryEntry{
+		{SKU: "AB100", Status: "Active", Season: seasonWinter, OnHand: 1, OnPO: 50, YTDSold: 90,
+			POs: []poLine{{Number: "1", Qty: 50, ExpectedDate: arrival}}},
+		{SKU: "AB200", Status: "Rundown", Season: seasonWinter, OnHand: 500},
+	}
+	f := newProductLineWorkbook()
+	defer func() {
+		_ = f.Close()
+	}()
+	if err := writeStandardSheets(context.Background(), f, entries, true, standardSheetOptions{Settings: builtinLineSettings}); err != nil {
+		t.Fatalf("writeStandardSheets returned error: %v", err)
+	}
+
+	headers, cols := buildStandardSheetHeaders(true, false, false)
+	column := func(idx int) string {
+		name, _ := excelize.ColumnNumberToName(idx + 1)
+		return name
+	}
+	formats, err := f.GetConditionalFormats("Winter")
+	if err != nil {
+		t.Fatalf("GetConditionalFormats returned error: %v", err)
+	}
+	status := formats["A2:"+column(len(headers)-1)+"3"]
+	if len(status) != 2 || status[0].Criteria != `$`+column(cols.Status)+`2="Rundown"` || !status[0].StopIfTrue {
+		t.Fatalf("expected the status rules first over every data row, got %+v", formats)
+	}
+	if bands := formats[column(cols.MTOPY)+"2:"+column(cols.MTOPY)+"3"]; len(bands) != 3 {
+		t.Fatalf("expected three MTO PY bands, got %+v", formats)
+	}
+	gapCol := column(cols.StockoutGap)
+	gap := formats[gapCol+"2:"+gapCol+"3"]
+	if len(gap) != 1 || gap[0].Type != "formula" || gap[0].Criteria != "AND(ISNUMBER("+gapCol+"2),"+gapCol+"2>0)" {
+		t.Fatalf("expected a stockout gap rule limited to numeric gaps, got %+v", formats)
+	}
+	// AB200 has no dated PO, so its gap is blank text and the ISNUMBER guard leaves it unshaded;
+	// Excel would compare the text as greater than zero under a plain cell rule.
+	if cellType, err := f.GetCellType("Winter", gapCol+"3"); err != nil || cellType == excelize.CellTypeNumber {
+		t.Fatalf("undated PO gap cell type = %v, %v; want blank text", cellType, err)
+	}
+	if value, err := f.GetCellValue("Winter", gapCol+"3"); err != nil || value != "" {
+		t.Fatalf("undated PO gap = %q, %v; want blank", value, err)
+	}
+
+	// The MTO cell itself keeps the plain white fill; its color comes from the rules above.
+	styleID, err := f.GetCellStyle("Winter", column(cols.MTOYTD)+"2")
+	if err != nil {
+		t.Fatalf("GetCellStyle returned error: %v", err)
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		t.Fatalf("GetStyle returned error: %v", err)
+	}
+	if fill := style.Fill.Color; len(fill) != 1 || !strings.EqualFold(strings.TrimPrefix(fill[0], "#"), "FFFFFF") {
+		t.Fatalf("MTO YTD cell fill = %q, want plain white", fill)
+	}
+}
+
+// TestWriteStandardSheetsMatchesCellByCellOutput verifies that the streamed standard sheets hold
+// the same cells, styles, widths, comments, conditional formats, and filters as writing every cell
+// through the worksheet API.
+func TestWriteStandardSheetsMatchesCellByCellOutput(t *testing.T) {
+	t.Parallel()
+
+	for _, formulas := range []bool{false, true} {
+		opts := standardSheetOptions{
+			Settings: builtinLineSettings,
+			Formulas: formulas,
+			Now:      time.Date(2026, time.June, 15, 9, 0, 0, 0, time.UTC),
+		}
+		streamed := savedStandardSheetWorkbook(t, writeStandardSheets, opts)
+		reference := savedStandardSheetWorkbook(t, writeStandardSheetsCellByCell, opts)
+
+		if got, want := streamed.GetDefinedName(), reference.GetDefinedName(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("formulas=%v: defined names = %+v, want %+v", formulas, got, want)
+		}
+		headers, _ := buildStandardSheetHeaders(true, formulas, false)
+		for _, sheetName := range standardSheetNames {
+			compareStandardSheets(t, fmt.Sprintf("formulas=%v %s", formulas, sheetName), streamed, reference, sheetName, len(headers))
+		}
+	}
+}
+
+// TestWriteStandardSheetsStopsWhenCancelled verifies that the row loop checks the context.
+func TestWriteStandardSheetsStopsWhenCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f := newProductLineWorkbook()
+	defer func() {
+		_ = f.Close()
+	}()
+	err := writeStandardSheets(ctx, f, sampleStandardSheetEntries(10), true, standardSheetOptions{Settings: builtinLineSettings})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("writeStandardSheets error = %v, want context.Canceled", err)
+	}
+}
+
+// BenchmarkWriteStandardSheets measures the streamed standard sheet writer.
+func BenchmarkWriteStandardSheets(b *testing.B) {
+	benchmarkStandardSheetWriter(b, writeStandardSheets)
+}
+
+// BenchmarkWriteStandardSheetsCellByCell measures the cell-by-cell writer the streamed one
+// replaced, as the baseline for BenchmarkWriteStandardSheets.
+func BenchmarkWriteStandardSheetsCellByCell(b *testing.B) {
+	benchmarkStandardSheetWriter(b, writeStandardSheetsCellByCell)
+}
+
+// standardSheetWriter is the signature shared by the streamed and cell-by-cell writers.
+type standardSheetWriter func(ctx context.Context, f *excelize.File, entries []*inventoryEntry, hasPO bool, opts standardSheetOptions) error
+
+func benchmarkStandardSheetWriter(b *testing.B, write standardSheetWriter) {
+	opts := standardSheetOptions{Settings: builtinLineSettings}
+	for b.Loop() {
+		f := newProductLineWorkbook()
+		if err := write(context.Background(), f, sampleStandardSheetEntries(3000), true, opts); err != nil {
+			b.Fatalf("write returned error: %v", err)
+		}
+		if _, err := f.WriteToBuffer(); err != nil {
+			b.Fatalf("WriteToBuffer returned error: %v", err)
+		}
+		_ = f.Close()
+	}
+}
+
+// sampleStandardSheetEntries returns n entries spread over the three sheets with a mix of
+// statuses, PO lines, PO mismatches, and blank dates.
+func sampleStandardSheetEntries(n int) []*inventoryEntry {
+	seasons := []string{seasonEveryday, seasonWinter, seasonSpring}
+	statuses := []string{"Active", "Rundown", "Discontinued"}
+	arrival := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	entries := make([]*inventoryEntry, 0, n)
+	for i := range n {
+		e := &inventoryEntry{
+			SKU:           fmt.Sprintf("AB%04dB", i),
+			ProductLine:   "BAS",
+			ClassDesc:     "Cards",
+			Status:        statuses[i%len(statuses)],
+			Season:        seasons[i%len(seasons)],
+			Occasion:      "BIRTHDAY",
+			Description:   fmt.Sprintf("Card %d", i),
+			UPC:           fmt.Sprintf("0123%08d", i),
+			OnHand:        i % 400,
+			OnSO:          i % 7,
+			OnBO:          i % 3,
+			YTDSold:       i % 250,
+			YTDIssued:     i%5 - 2,
+			SoldPY:        i % 300,
+			IssuedPY:      i % 4,
+			DollarSoldYTD: float64(i%250) * 1.25,
+			DollarSoldPY:  float64(i%300) * 1.25,
+		}
+		switch i % 4 {
+		case 1:
+			e.POs = []poLine{{Number: fmt.Sprintf("PO%d", i), Qty: 100, ExpectedDate: arrival.AddDate(0, 0, i%60)}}
+			e.OnPO = 100
+		case 2:
+			// An undated line, and an OnPO that disagrees with the PO report.
+			e.POs = []poLine{{Number: fmt.Sprintf("PO%d", i), Qty: 40}}
+			e.OnPO = 60
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// savedStandardSheetWorkbook writes the sample entries with write, saves the workbook, and opens
+// the saved copy.
+func savedStandardSheetWorkbook(t *testing.T, write standardSheetWriter, opts standardSheetOptions) *excelize.File {
+	t.Helper()
+	f := newProductLineWorkbook()
+	defer func() {
+		_ = f.Close()
+	}()
+	if err := write(context.Background(), f, sampleStandardSheetEntries(60), true, opts); err != nil {
+		t.Fatalf("write returned error: %v", err)
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatalf("WriteToBuffer returned error: %v", err)
+	}
+	saved, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatalf("OpenReader returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = saved.Close()
+	})
+	return saved
+}
+
+// compareStandardSheets fails the test when sheetName differs between got and want.
+func compareStandardSheets(t *testing.T, label string, got, want *excelize.File, sheetName string, columnCount int) {
+	t.Helper()
+	gotRows, err := got.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatalf("%s: GetRows returned error: %v", label, err)
+	}
+	wantRows, err := want.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatalf("%s: GetRows returned error: %v", label, err)
+	}
+	if !reflect.DeepEqual(gotRows, wantRows) {
+		t.Fatalf("%s: rows differ:\n got %q\nwant %q", label, gotRows, wantRows)
+	}
+
+	for r := range len(wantRows) {
+		for c := range columnCount {
+			cell, _ := excelize.CoordinatesToCellName(c+1, r+1)
+			gotFormula, _ := got.GetCellFormula(sheetName, cell)
+			wantFormula, _ := want.GetCellFormula(sheetName, cell)
+			if gotFormula != wantFormula {
+				t.Fatalf("%s: %s formula = %q, want %q", label, cell, gotFormula, wantFormula)
+			}
+			if gotStyle, wantStyle := cellStyle(t, got, sheetName, cell), cellStyle(t, want, sheetName, cell); !reflect.DeepEqual(gotStyle, wantStyle) {
+				t.Fatalf("%s: %s style = %+v, want %+v", label, cell, gotStyle, wantStyle)
+			}
+		}
+	}
+	for c := range columnCount {
+		col, _ := excelize.ColumnNumberToName(c + 1)
+		gotWidth, _ := got.GetColWidth(sheetName, col)
+		wantWidth, _ := want.GetColWidth(sheetName, col)
+		if gotWidth != wantWidth {
+			t.Fatalf("%s: column %s width = %v, want %v", label, col, gotWidth, wantWidth)
+		}
+	}
+
+	gotComments, _ := got.GetComments(sheetName)
+	wantComments, _ := want.GetComments(sheetName)
+	if len(wantComments) == 0 || !reflect.DeepEqual(gotComments, wantComments) {
+		t.Fatalf("%s: comments = %+v, want %+v", label, gotComments, wantComments)
+	}
+	gotFormats, _ := got.GetConditionalFormats(sheetName)
+	wantFormats, _ := want.GetConditionalFormats(sheetName)
+	if len(wantFormats) == 0 || !reflect.DeepEqual(gotFormats, wantFormats) {
+		t.Fatalf("%s: conditional formats = %+v, want %+v", label, gotFormats, wantFormats)
+	}
+}
+
+// cellStyle returns the style definition of a cell.
+func cellStyle(t *testing.T, f *excelize.File, sheetName, cell string) *excelize.Style {
+	t.Helper()
+	styleID, err := f.GetCellStyle(sheetName, cell)
+	if err != nil {
+		t.Fatalf("GetCellStyle(%s) returned error: %v", cell, err)
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		t.Fatalf("GetStyle(%s) returned error: %v", cell, err)
+	}
+	return style
+}
@@ -0,0 +1,62 @@
+package hotsheet
+
+import (
+	"math"
+	"time"
+)
+
+// averageDaysPerMonth converts a months-till-out figure into calendar days.
+const averageDaysPerMonth = 365.25 / 12
+
+// stockoutProjection compares when an item is expected to run out of stock with when its next
+// PO is expected to arrive.
+type stockoutProjection struct {
+	// Stockout is the projected date on-hand stock, less open sales orders and backorders,
+	// runs out.
+	Stockout time.Time
+	// NextArrival is the expected date of the earliest dated PO line, or the zero time when no
+	// PO line has a date.
+	NextArrival time.Time
+	// GapDays is how many days the item is projected to be out of stock before NextArrival
+	// lands. It is 0 when the PO arrives in time and only meaningful when NextArrival is set.
+	GapDays int
+}
+
+// HasArrival reports whether the item has a dated PO to compare against.
+func (p stockoutProjection) HasArrival() bool {
+	return !p.NextArrival.IsZero()
+}
+
+// StocksOutBeforeArrival reports whether the item runs out before its next PO lands.
+func (p stockoutProjection) StocksOutBeforeArrival() bool {
+	return p.HasArrival() && p.GapDays > 0
+}
+
+// projectStockout projects the stockout date of e at soldPerMonth, the same sales pace used by
+// MTO YTD, and measures the gap to the next dated PO. Unlike MTO YTD it leaves open PO quantity
+// out of the available stock, since that stock has not arrived yet.
+func projectStockout(e *inventoryEntry, soldPerMonth float64, now time.Time) stockoutProjection {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	available := e.OnHand - e.OnSO - e.OnBO
+
+	projection := stockoutProjection{Stockout: today}
+	if available > 0 {
+		// The +1 matches the MTO YTD denominator so a SKU without sales stays finite.
+		monthsLeft := float64(available) / (soldPerMonth + 1)
+		projection.Stockout = today.AddDate(0, 0, int(math.Floor(monthsLeft*averageDaysPerMonth)))
+	}
+
+	for _, line := range sortedPOLines(e) {
+		if line.ExpectedDate.IsZero() {
+			continue
+		}
+		projection.NextArrival = line.ExpectedDate
+		break
+	}
+	if projection.HasArrival() {
+		arrival := time.Date(projection.NextArrival.Year(), projection.NextArrival.Month(), projection.NextArrival.Day(), 0, 0, 0, 0, today.Location())
+		gap := int(math.Round(arrival.Sub(projection.Stockout).Hours() / 24))
+		projection.GapDays = max(gap, 0)
+	}
+	return projection
+}
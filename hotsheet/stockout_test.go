@@ -0,0 +1,60 @@
+package hotsheet
+
+import (
+	"testing"
+	"time"
+)
+
+// TestProjectStockoutMeasuresGapToNextPO verifies the projected stockout date and the gap to
+// the earliest dated PO line.
+func TestProjectStockoutMeasuresGapToNextPO(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.March, 1, 14, 30, 0, 0, time.Local)
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name      string
+		pos       []poLine
+		wantGap   int
+		wantAlert bool
+		wantPO    bool
+	}{
+		{
+			name:      "po lands after stockout",
+			pos:       []poLine{{Number: "2", Qty: 5}, {Number: "1", Qty: 50, ExpectedDate: date(time.June, 15)}},
+			wantGap:   15,
+			wantAlert: true,
+			wantPO:    true,
+		},
+		{
+			name:    "po lands in time",
+			pos:     []poLine{{Number: "1", Qty: 50, ExpectedDate: date(time.May, 1)}},
+			wantPO:  true,
+			wantGap: 0,
+		},
+		{
+			name: "no dated po",
+			pos:  []poLine{{Number: "1", Qty: 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 90 available units at 29 per month (plus the MTO +1) last three months, or 91 days.
+			e := &inventoryEntry{SKU: "ABC123", OnHand: 100, OnSO: 10, OnPO: 100, POs: tt.pos}
+			got := projectStockout(e, 29, now)
+			if want := date(time.May, 31); !got.Stockout.Equal(want) {
+				t.Fatalf("expected stockout %v, got %v", want, got.Stockout)
+			}
+			if got.HasArrival() != tt.wantPO {
+				t.Fatalf("expected HasArrival=%v, got %+v", tt.wantPO, got)
+			}
+			if got.GapDays != tt.wantGap || got.StocksOutBeforeArrival() != tt.wantAlert {
+				t.Fatalf("expected gap %d (alert=%v), got %+v", tt.wantGap, tt.wantAlert, got)
+			}
+		})
+	}
+}
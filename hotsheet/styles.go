@@ -1,16 +1,29 @@
 package hotsheet
 
-import "github.com/xuri/excelize/v2"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
 
 const (
 	// currencyFormat is the shared Excel number format used for dollar-value columns.
 	currencyFormat = "$#,##0.00;[Red]($#,##0.00)"
+	// dateFormat is the shared Excel number format used for date columns.
+	dateFormat = "mm/dd/yyyy"
+	// weeksFormat is the Excel number format used for the weekly trend columns.
+	weeksFormat = "0.0"
 	// Shared fill colors keep the workbook styling consistent across sheets.
 	dataInsightsSectionFill = "#D9EAF7"
 	standardHeaderFill      = "#E6E6FA"
 	// dataInsightsTotalFill stays darker than the section/header fills so total rows remain the
 	// strongest visual endpoint in each table.
 	dataInsightsTotalFill = "#E2E2E2"
+	// stockoutGapFill flags items projected to run out before their next PO arrives.
+	stockoutGapFill = "#FF9999"
+	// poMismatchFill flags a Total QTY on PO that disagrees with the PO report's lines.
+	poMismatchFill = "#F4B183"
 )
 
 // currencyNumFmt returns a pointer suitable for excelize.Style.CustomNumFmt.
@@ -19,6 +32,12 @@ func currencyNumFmt() *string {
 	return &format
 }
 
+// dateNumFmt returns a pointer suitable for excelize.Style.CustomNumFmt on date cells.
+func dateNumFmt() *string {
+	format := dateFormat
+	return &format
+}
+
 // centeredAlignment returns the standard centered alignment fragment.
 func centeredAlignment() *excelize.Alignment {
 	return &excelize.Alignment{Horizontal: "center", Vertical: "center"}
@@ -47,3 +66,33 @@ func boldFont(size ...float64) *excelize.Font {
 func patternFill(fillColor string) excelize.Fill {
 	return excelize.Fill{Type: "pattern", Color: []string{fillColor}, Pattern: 1}
 }
+
+// styleCache hands out one style ID per distinct style definition, so sheets styled cell by cell
+// do not ask excelize to create, and search its style table for, a style for every cell.
+type styleCache struct {
+	f   *excelize.File
+	ids map[string]int
+}
+
+// newStyleCache returns an empty style cache for f.
+func newStyleCache(f *excelize.File) *styleCache {
+	return &styleCache{f: f, ids: make(map[string]int)}
+}
+
+// id returns the style ID for style, creating the style the first time its definition is seen.
+func (c *styleCache) id(style *excelize.Style) (int, error) {
+	// The JSON encoding follows the pointer fields, so equal definitions share a key.
+	key, err := json.Marshal(style)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode style: %w", err)
+	}
+	if id, ok := c.ids[string(key)]; ok {
+		return id, nil
+	}
+	id, err := c.f.NewStyle(style)
+	if err != nil {
+		return 0, err
+	}
+	c.ids[string(key)] = id
+	return id, nil
+}
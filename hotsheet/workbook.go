@@ -1,45 +1,115 @@
 package hotsheet
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 )
 
+// productLineWorkbookOptions carries the run-wide inputs every product-line workbook is built
+// from.
+type productLineWorkbookOptions struct {
+	// hasPO adds the PO columns to the standard sheets.
+	hasPO bool
+	// poOnly lists the run's PO-only SKUs. They belong to no product line, so only the
+	// consolidated workbook reports them; per-line runs list them in the issues workbook.
+	poOnly []poOnlyItem
+	// issues are the product line's import issues.
+	issues []ImportIssue
+	// calendar supplies the Data Insights holiday dates and selling windows.
+	calendar *holidayCalendar
+	// sheets shape the product line's standard sheets.
+	sheets   standardSheetOptions
+	features GenerateFeatures
+	// changes fill the Changes sheet; nil leaves it out.
+	changes *hotsheetChanges
+	// now is the run's current time.
+	now time.Time
+}
+
 // buildProductLineWorkbook creates one workbook for a product line, writes the standard report
-// sheets and Data Insights sheet, and saves the result to disk.
-func buildProductLineWorkbook(productLine string, entries []*inventoryEntry, outputDir, dateStamp string, hasPO bool, logger *slog.Logger) (string, error) {
+// sheets, the Data Insights sheet, the Changes sheet when there was a previous hotsheet to
+// compare with, the Open POs and PO Reconciliation sheets when a PO report was supplied, and an
+// Import Issues sheet when the line has issues, and saves the result to
+// outPath. opts.features can leave the optional sheets out. Building stops with ctx's error,
+// before the next sheet or within the standard sheets' rows, once ctx is cancelled.
+func buildProductLineWorkbook(ctx context.Context, productLine string, entries []*inventoryEntry, outPath string, opts productLineWorkbookOptions, logger *slog.Logger) error {
 	f := newProductLineWorkbook()
 	defer func() {
 		_ = f.Close()
 	}()
 
-	if err := writeStandardSheets(f, entries, hasPO); err != nil {
+	if err := writeStandardSheets(ctx, f, entries, opts.hasPO, opts.sheets); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if logger != nil {
 			logger.Error("failed to write standard sheets", "productLine", productLine, "err", err)
 		}
-		return "", fmt.Errorf("failed to write standard sheets for %s: %w", productLine, err)
+		return fmt.Errorf("failed to write standard sheets for %s: %w", productLine, err)
 	}
 
-	if err := writeDataInsightsSheet(f, entries); err != nil {
-		if logger != nil {
-			logger.Error("failed to create Data Insights sheet", "productLine", productLine, "err", err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !opts.features.NoDataInsights {
+		if err := writeDataInsightsSheet(f, dataInsightsSheetName, entries, opts.calendar, opts.sheets.Trends, opts.now); err != nil {
+			if logger != nil {
+				logger.Error("failed to create Data Insights sheet", "productLine", productLine, "err", err)
+			}
+			return fmt.Errorf("failed to create Data Insights sheet for %s: %w", productLine, err)
+		}
+	}
+	if opts.changes != nil {
+		if err := writeChangesSheet(f, opts.changes, false); err != nil {
+			if logger != nil {
+				logger.Error("failed to create Changes sheet", "productLine", productLine, "err", err)
+			}
+			return fmt.Errorf("failed to create Changes sheet for %s: %w", productLine, err)
 		}
-		return "", fmt.Errorf("failed to create Data Insights sheet for %s: %w", productLine, err)
 	}
 
-	outPath, err := saveWorkbook(f, outputDir, productLine, dateStamp)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.hasPO && !opts.features.NoPOSheets {
+		if err := writeOpenPOsSheet(f, entries); err != nil {
+			if logger != nil {
+				logger.Error("failed to create Open POs sheet", "productLine", productLine, "err", err)
+			}
+			return fmt.Errorf("failed to create Open POs sheet for %s: %w", productLine, err)
+		}
+		if err := writePOReconciliationSheet(f, entries, opts.poOnly); err != nil {
+			if logger != nil {
+				logger.Error("failed to create PO Reconciliation sheet", "productLine", productLine, "err", err)
+			}
+			return fmt.Errorf("failed to create PO Reconciliation sheet for %s: %w", productLine, err)
+		}
+	}
+
+	if len(opts.issues) > 0 && !opts.features.NoImportIssues {
+		if err := writeImportIssuesSheet(f, opts.issues); err != nil {
+			if logger != nil {
+				logger.Error("failed to create Import Issues sheet", "productLine", productLine, "err", err)
+			}
+			return fmt.Errorf("failed to create Import Issues sheet for %s: %w", productLine, err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := saveWorkbook(f, outPath); err != nil {
 		if logger != nil {
 			logger.Error("failed to save hotsheet for product line", "productLine", productLine, "err", err)
 		}
-		return "", err
+		return err
 	}
 
-	return outPath, nil
+	return nil
 }
 
 // newProductLineWorkbook creates the workbook shell used for each product-line export.
@@ -56,17 +126,10 @@ func newProductLineWorkbook() *excelize.File {
 	return f
 }
 
-// saveWorkbook builds the final output path, sanitizes the product line name, and writes the
-// workbook to disk.
-func saveWorkbook(f *excelize.File, outputDir, productLine, dateStr string) (string, error) {
-	outDir := outputDir
-	if strings.TrimSpace(outDir) == "" {
-		outDir = "."
-	}
-	fileName := fmt.Sprintf("%s_hotsheet_%s.xlsx", sanitizeFileName(productLine), dateStr)
-	outPath := filepath.Join(outDir, fileName)
+// saveWorkbook writes the workbook to outPath.
+func saveWorkbook(f *excelize.File, outPath string) error {
 	if err := f.SaveAs(outPath); err != nil {
-		return "", fmt.Errorf("failed to save hotsheet %s: %w", outPath, err)
+		return fmt.Errorf("failed to save hotsheet %s: %w", outPath, err)
 	}
-	return outPath, nil
+	return nil
 }
@@ -0,0 +1,368 @@
+// Package cli runs the hotsheet pipeline from the command line, for scripts, scheduled tasks, and
+// servers where the GUI cannot run.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/version"
+)
+
+// Exit codes returned by Run.
+const (
+	// ExitOK means the command succeeded.
+	ExitOK = 0
+	// ExitFailure means the command ran and failed, for example because a report could not be read
+	// or a hotsheet could not be saved.
+	ExitFailure = 1
+	// ExitUsage means the command line itself was wrong.
+	ExitUsage = 2
+	// ExitIssues means the run finished but found import issues or warnings: always for validate,
+	// and for generate with --strict.
+	ExitIssues = 3
+	// ExitCancelled means the run was interrupted.
+	ExitCancelled = 130
+)
+
+// usage is printed for help and for an unknown command.
+const usage = `Usage: hotsheet <command> [flags]
+
+Commands:
+  generate   build the hotsheets from an inventory report and an optional PO report
+  validate   check the config files and reports without writing anything
+  watch      regenerate the hotsheets whenever new reports land in a folder
+  version    print the version
+
+Run "hotsheet <command> -h" for a command's flags. Without a command the GUI starts.
+`
+
+// Run runs the command named by args[0] with the remaining args as its flags and returns the
+// process exit code. Results go to stdout; progress, messages, and errors go to stderr.
+// Cancelling ctx stops a running generate or validate.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return ExitUsage
+	}
+	switch args[0] {
+	case "generate":
+		return runGenerate(ctx, args[1:], stdout, stderr)
+	case "validate":
+		return runValidate(ctx, args[1:], stdout, stderr)
+	case "watch":
+		return runWatch(ctx, args[1:], stdout, stderr)
+	case "version", "-v", "--version":
+		fmt.Fprintf(stdout, "hotsheet %s\n", version.Version)
+		return ExitOK
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	default:
+		fmt.Fprintf(stderr, "hotsheet: unknown command %q\n\n%s", args[0], usage)
+		return ExitUsage
+	}
+}
+
+// runFlags are the flags shared by generate and validate.
+type runFlags struct {
+	inventory      string
+	po             string
+	out            string
+	inventorySheet string
+	poSheet        string
+	occasions      string
+	calendar       string
+	settings       string
+	classRules     string
+	history        string
+	productLines   stringList
+	nameTemplate   string
+	consolidated   bool
+	overwrite      string
+	noDataInsights bool
+	noPOSheets     bool
+	noImportIssues bool
+	noChanges      bool
+	noHistory      bool
+	compareWith    string
+	logLevel       string
+	json           bool
+	quiet          bool
+	strict         bool
+}
+
+// newRunFlagSet registers the flags shared by generate and validate on a new flag set.
+func newRunFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *runFlags) {
+	fs, f := newOptionFlagSet(name, stderr)
+	fs.StringVar(&f.inventory, "inventory", "", "inventory report (.xlsx, .xls, .csv, or .tsv)")
+	fs.StringVar(&f.po, "po", "", "optional PO report")
+	fs.StringVar(&f.out, "out", "", "output folder (default: the current folder)")
+	fs.BoolVar(&f.quiet, "quiet", false, "do not print progress")
+	return fs, f
+}
+
+// newOptionFlagSet registers the flags that shape a run, shared by every command that generates,
+// on a new flag set.
+func newOptionFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *runFlags) {
+	fs := flag.NewFlagSet("hotsheet "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	f := &runFlags{}
+	fs.StringVar(&f.inventorySheet, "inventory-sheet", "", "inventory worksheet name or 1-based index")
+	fs.StringVar(&f.poSheet, "po-sheet", "", "PO worksheet name or 1-based index")
+	fs.StringVar(&f.occasions, "occasions", "", "occasion mapping file (default: occasions.json in the config folder)")
+	fs.StringVar(&f.calendar, "calendar", "", "holiday calendar file (default: calendar.json in the config folder)")
+	fs.StringVar(&f.settings, "settings", "", "settings file (default: settings.json in the config folder)")
+	fs.StringVar(&f.classRules, "class-rules", "", "class prefix rules file (default: class_rules.json in the config folder)")
+	fs.StringVar(&f.history, "history", "", "snapshot history folder the trend columns are measured from (default: history in the config folder)")
+	fs.Var(&f.productLines, "product-line", "only build this product line; repeat or separate with commas for several")
+	fs.StringVar(&f.nameTemplate, "name", "", "hotsheet file name template with {productLine} and {date} (default: {productLine}_hotsheet_{date}.xlsx, or hotsheet_{date}.xlsx with --consolidated)")
+	fs.BoolVar(&f.consolidated, "consolidated", false, "write every product line into one workbook with a Summary sheet")
+	fs.StringVar(&f.overwrite, "overwrite", "replace", "what to do with existing output files: replace, skip, or fail")
+	fs.BoolVar(&f.noDataInsights, "no-data-insights", false, "leave out the Data Insights sheet")
+	fs.BoolVar(&f.noPOSheets, "no-po-sheets", false, "leave out the Open POs and PO Reconciliation sheets")
+	fs.BoolVar(&f.noImportIssues, "no-import-issues", false, "leave out the Import Issues sheets and workbook")
+	fs.BoolVar(&f.noChanges, "no-changes", false, "leave out the Changes sheet that compares with the previous hotsheet")
+	fs.BoolVar(&f.noHistory, "no-history", false, "do not save the run to the snapshot history or add the trend columns")
+	fs.StringVar(&f.compareWith, "compare", "", "previous hotsheet, or folder of hotsheets, to compare with (default: the output folder)")
+	fs.StringVar(&f.logLevel, "log-level", "DEBUG", "log file level: DEBUG, INFO, WARN, or ERROR")
+	fs.BoolVar(&f.json, "json", false, "print the result as JSON on stdout")
+	return fs, f
+}
+
+// options converts the flags into generation options, reporting progress to stderr unless quiet.
+func (f *runFlags) options(stderr io.Writer) (hotsheet.GenerateOptions, error) {
+	overwrite, err := parseOverwritePolicy(f.overwrite)
+	if err != nil {
+		return hotsheet.GenerateOptions{}, err
+	}
+	opts := hotsheet.GenerateOptions{
+		InventoryPath: f.inventory,
+		POPath:        f.po,
+		OutputDir:     f.out,
+		Input: hotsheet.InputOptions{
+			InventorySheet:   f.inventorySheet,
+			POSheet:          f.poSheet,
+			OccasionConfig:   f.occasions,
+			CalendarConfig:   f.calendar,
+			SettingsConfig:   f.settings,
+			ClassRulesConfig: f.classRules,
+			HistoryDir:       f.history,
+		},
+		LogLevel:         f.logLevel,
+		ProductLines:     f.productLines,
+		FileNameTemplate: f.nameTemplate,
+		Overwrite:        overwrite,
+		Features: hotsheet.GenerateFeatures{
+			NoDataInsights: f.noDataInsights,
+			NoPOSheets:     f.noPOSheets,
+			NoImportIssues: f.noImportIssues,
+			NoChanges:      f.noChanges,
+			NoHistory:      f.noHistory,
+		},
+		CompareWith: f.compareWith,
+	}
+	if f.consolidated {
+		opts.Layout = hotsheet.LayoutConsolidated
+	}
+	if !f.quiet {
+		opts.Progress = progressPrinter(stderr)
+	}
+	return opts, nil
+}
+
+// runGenerate implements the generate command.
+func runGenerate(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs, f := newRunFlagSet("generate", stderr)
+	fs.BoolVar(&f.strict, "strict", false, "exit with code 3 when the run finds import issues or warnings")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if strings.TrimSpace(f.inventory) == "" {
+		fmt.Fprintln(stderr, "hotsheet generate: --inventory is required")
+		return ExitUsage
+	}
+	opts, err := f.options(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "hotsheet generate: %v\n", err)
+		return ExitUsage
+	}
+
+	result, err := hotsheet.GenerateWithOptions(ctx, opts)
+	if f.json {
+		if err := writeJSON(stdout, result, err); err != nil {
+			fmt.Fprintf(stderr, "hotsheet generate: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		for _, path := range result.Paths() {
+			fmt.Fprintln(stdout, path)
+		}
+	}
+	printSummary(stderr, result, "created")
+	return exitCode(stderr, "generate", result, err, f.strict)
+}
+
+// runValidate implements the validate command.
+func runValidate(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs, f := newRunFlagSet("validate", stderr)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	opts, err := f.options(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "hotsheet validate: %v\n", err)
+		return ExitUsage
+	}
+
+	result, err := hotsheet.Validate(ctx, opts)
+	if f.json {
+		if err := writeJSON(stdout, result, err); err != nil {
+			fmt.Fprintf(stderr, "hotsheet validate: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		for _, issue := range result.Issues {
+			fmt.Fprintln(stdout, formatIssue(issue))
+		}
+		for _, file := range result.Files {
+			if file.Skipped {
+				continue
+			}
+			line := fmt.Sprintf("would write %s (%d items)", file.Path, file.Items)
+			if file.ComparedWith != "" {
+				line += ", compared with " + file.ComparedWith
+			}
+			fmt.Fprintln(stdout, line)
+		}
+	}
+	printSummary(stderr, result, "to write")
+	return exitCode(stderr, "validate", result, err, true)
+}
+
+// parseFlags parses args and reports whether the command should run. When it should not, the
+// returned code is ExitOK for -h and ExitUsage for bad flags or stray arguments.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK, false
+		}
+		return ExitUsage, false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "%s: unexpected argument %q\n", fs.Name(), fs.Arg(0))
+		return ExitUsage, false
+	}
+	return ExitOK, true
+}
+
+// exitCode prints the run's warnings and error to stderr and returns the exit code for it.
+// Import issues and warnings only change the code when strict is set.
+func exitCode(stderr io.Writer, command string, result *hotsheet.GenerateResult, err error, strict bool) int {
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", warning)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintf(stderr, "hotsheet %s: cancelled\n", command)
+		return ExitCancelled
+	case err != nil:
+		fmt.Fprintf(stderr, "hotsheet %s: %v\n", command, err)
+		return ExitFailure
+	case strict && (len(result.Issues) > 0 || len(result.Warnings) > 0):
+		return ExitIssues
+	default:
+		return ExitOK
+	}
+}
+
+// printSummary prints a one-line count of the run's files and import issues to stderr.
+func printSummary(stderr io.Writer, result *hotsheet.GenerateResult, verb string) {
+	files := len(result.Paths())
+	fmt.Fprintf(stderr, "%d file(s) %s, %d import issue(s), in %s\n", files, verb, len(result.Issues),
+		result.Timings.Total.Round(time.Millisecond))
+}
+
+// jsonOutput is the document printed by --json.
+type jsonOutput struct {
+	*hotsheet.GenerateResult
+	// Error is the run's error message, when it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// writeJSON prints result and err as one indented JSON document.
+func writeJSON(w io.Writer, result *hotsheet.GenerateResult, err error) error {
+	out := jsonOutput{GenerateResult: result}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("failed to write JSON output: %w", err)
+	}
+	return nil
+}
+
+// progressPrinter prints each progress update on its own stderr line.
+func progressPrinter(stderr io.Writer) hotsheet.ProgressCallback {
+	return func(p hotsheet.Progress) {
+		fmt.Fprintf(stderr, "[%3d%%] %s\n", p.Percent, p.Message)
+	}
+}
+
+// formatIssue renders an import issue on one line, located the way the Import Issues sheet
+// locates it.
+func formatIssue(issue hotsheet.ImportIssue) string {
+	location := fmt.Sprintf("%s %s row %d", issue.Report, issue.Sheet, issue.Row)
+	if issue.Column != "" {
+		location += " column " + issue.Column
+	}
+	line := fmt.Sprintf("%s: %s", location, issue.Kind)
+	if issue.SKU != "" {
+		line += " (" + issue.SKU + ")"
+	}
+	if issue.Message != "" {
+		line += ": " + issue.Message
+	}
+	return line
+}
+
+// parseOverwritePolicy maps the --overwrite value to a policy.
+func parseOverwritePolicy(value string) (hotsheet.OverwritePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "replace":
+		return hotsheet.OverwriteReplace, nil
+	case "skip":
+		return hotsheet.OverwriteSkip, nil
+	case "fail":
+		return hotsheet.OverwriteFail, nil
+	default:
+		return 0, fmt.Errorf("--overwrite must be replace, skip, or fail, not %q", value)
+	}
+}
+
+// stringList is a repeatable flag whose values may also be separated by commas.
+type stringList []string
+
+// String returns the values joined by commas.
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds each non-blank comma-separated value.
+func (l *stringList) Set(value string) error {
+	for part := range strings.SplitSeq(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
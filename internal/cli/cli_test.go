@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+)
+
+// TestMain runs the package's tests with a config folder of their own.
+func TestMain(m *testing.M) {
+	os.Exit(testinputs.Main(m))
+}
+
+// TestRunGenerateJSON verifies that generate builds the filtered product lines and prints them as
+// JSON.
+func TestRunGenerateJSON(t *testing.T) {
+	args := writeTestInputs(t)
+	outputDir := t.TempDir()
+	args = append([]string{"generate", "--out", outputDir, "--product-line", "bas", "--json", "--quiet"}, args...)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run(context.Background(), args, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("Run() = %d, want %d; stderr:\n%s", code, ExitOK, stderr.String())
+	}
+	var out struct {
+		Files []struct {
+			Path        string `json:"path"`
+			ProductLine string `json:"productLine"`
+		} `json:"files"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, stdout.String())
+	}
+	if len(out.Files) != 1 || out.Files[0].ProductLine != "BAS" || out.Error != "" {
+		t.Fatalf("JSON output = %+v, want only the BAS hotsheet", out)
+	}
+	if _, err := os.Stat(out.Files[0].Path); err != nil {
+		t.Fatalf("hotsheet was not written: %v", err)
+	}
+}
+
+// TestRunValidateWritesNothing verifies that validate lists the files it would write, reports
+// issues with exit code 3, and leaves the output folder empty.
+func TestRunValidateWritesNothing(t *testing.T) {
+	args := writeTestInputs(t)
+	outputDir := t.TempDir()
+	args = append([]string{"validate", "--out", outputDir, "--product-line", "BAS,NOPE"}, args...)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run(context.Background(), args, &stdout, &stderr); code != ExitIssues {
+		t.Fatalf("Run() = %d, want %d for the missing product line; stderr:\n%s", code, ExitIssues, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "would write "+filepath.Join(outputDir, "BAS_hotsheet_")) {
+		t.Fatalf("stdout = %q, want the planned BAS hotsheet", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "warning: Product line NOPE") {
+		t.Fatalf("stderr = %q, want the missing product line warning", stderr.String())
+	}
+	left, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	if len(left) != 0 {
+		t.Fatalf("validate wrote %d file(s)", len(left))
+	}
+}
+
+// TestRunUsageErrors verifies the exit codes for bad command lines.
+func TestRunUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "no command", args: nil, want: ExitUsage},
+		{name: "unknown command", args: []string{"build"}, want: ExitUsage},
+		{name: "missing inventory", args: []string{"generate"}, want: ExitUsage},
+		{name: "bad flag", args: []string{"generate", "--nope"}, want: ExitUsage},
+		{name: "bad overwrite", args: []string{"generate", "--inventory", "x.csv", "--overwrite", "never"}, want: ExitUsage},
+		{name: "watch without folders", args: []string{"watch"}, want: ExitUsage},
+		{name: "watch missing folder", args: []string{"watch", "--dir", filepath.Join(t.TempDir(), "missing"), "--out", t.TempDir()}, want: ExitUsage},
+		{name: "help", args: []string{"generate", "-h"}, want: ExitOK},
+		{name: "version", args: []string{"version"}, want: ExitOK},
+		{name: "unreadable report", args: []string{"generate", "--quiet", "--inventory", filepath.Join(t.TempDir(), "missing.csv")}, want: ExitFailure},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if got := Run(context.Background(), tt.args, &stdout, &stderr); got != tt.want {
+			t.Errorf("%s: Run() = %d, want %d; stderr:\n%s", tt.name, got, tt.want, stderr.String())
+		}
+	}
+}
+
+// writeTestInputs writes an inventory report with one BAS and one OAT item and returns the flags
+// that point the run at it and at a snapshot history of the test's own. The config files come
+// from the test binary's config folder set up by TestMain. Log files go to a temporary folder.
+func writeTestInputs(t *testing.T) []string {
+	t.Helper()
+	t.Setenv("TMPDIR", t.TempDir())
+
+	inputs := testinputs.Write(t, "BAS", "OAT")
+	return []string{"--inventory", inputs.InventoryPath, "--history", inputs.HistoryDir}
+}
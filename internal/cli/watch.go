@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/watch"
+)
+
+// runWatch implements the watch command. It runs until ctx is cancelled, printing one line per
+// generation to stdout.
+func runWatch(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs, f := newOptionFlagSet("watch", stderr)
+	var cfg watch.Config
+	fs.StringVar(&cfg.Dir, "dir", "", "folder the Sage exports land in")
+	fs.StringVar(&cfg.OutputRoot, "out", "", "folder that gets one dated subfolder of hotsheets per day")
+	fs.StringVar(&cfg.RunLog, "run-log", "", "JSON Lines file every run is appended to (default: "+watch.DefaultRunLogName+" in --out)")
+	fs.StringVar(&cfg.InventoryPattern, "inventory-pattern", watch.DefaultInventoryPattern, "file name glob of inventory reports, ignoring case")
+	fs.StringVar(&cfg.POPattern, "po-pattern", watch.DefaultPOPattern, "file name glob of PO reports, ignoring case")
+	fs.DurationVar(&cfg.Interval, "interval", watch.DefaultInterval, "how often to scan the folder")
+	fs.DurationVar(&cfg.StableFor, "stable", watch.DefaultStableFor, "how long a report must stay unchanged before it is used")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if strings.TrimSpace(cfg.Dir) == "" || strings.TrimSpace(cfg.OutputRoot) == "" {
+		fmt.Fprintln(stderr, "hotsheet watch: --dir and --out are required")
+		return ExitUsage
+	}
+	opts, err := f.options(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "hotsheet watch: %v\n", err)
+		return ExitUsage
+	}
+	opts.Progress = nil
+	cfg.Options = opts
+
+	w, err := watch.New(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "hotsheet watch: %v\n", err)
+		return ExitUsage
+	}
+	fmt.Fprintf(stderr, "watching %s every %s; press Ctrl+C to stop\n", cfg.Dir, cfg.Interval)
+	err = w.Run(ctx, func(record watch.RunRecord) {
+		if f.json {
+			line, err := json.Marshal(record)
+			if err == nil {
+				fmt.Fprintln(stdout, string(line))
+			}
+			return
+		}
+		fmt.Fprintln(stdout, formatRunRecord(record))
+		for _, warning := range record.Warnings {
+			fmt.Fprintf(stderr, "warning: %s\n", warning)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "hotsheet watch: %v\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// formatRunRecord summarizes a watch run on one line.
+func formatRunRecord(record watch.RunRecord) string {
+	sources := record.Inventory.Path
+	if record.PO != nil {
+		sources += " and " + record.PO.Path
+	}
+	if sources == "" {
+		// A failed scan of the watched folder has no reports.
+		sources = "watched folder"
+	}
+	line := fmt.Sprintf("%s %s: ", record.Started.Format(time.DateTime), sources)
+	if record.Error != "" {
+		return line + "failed: " + record.Error
+	}
+	return line + fmt.Sprintf("created %d file(s) in %s, %d import issue(s)", len(record.Files), record.OutputDir, record.Issues)
+}
@@ -1,6 +1,7 @@
 package gui
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -92,6 +93,21 @@ func (s *AppState) browseOutputDir() {
 	s.requestRedraw()
 }
 
+// browseCompare opens the native file picker and stores the chosen previous
+// hotsheet path.
+func (s *AppState) browseCompare() {
+	path, err := pickFile()
+	if err != nil {
+		if errors.Is(err, errDialogCancelled) {
+			return
+		}
+		s.openErrorPopup("Browse Error", err.Error())
+		return
+	}
+	setEditorText(&s.compareEditor, path)
+	s.requestRedraw()
+}
+
 // startGenerate validates the required inputs, shows the progress popup, and
 // launches hotsheet generation in a background goroutine.
 func (s *AppState) startGenerate() {
@@ -107,21 +123,52 @@ func (s *AppState) startGenerate() {
 
 	poPath := editorText(&s.poEditor)
 	outputDir := editorText(&s.outputEditor)
+	input := hotsheet.InputOptions{
+		InventorySheet: editorText(&s.inventorySheetEditor),
+		POSheet:        editorText(&s.poSheetEditor),
+	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	s.generateInProgress = true
 	s.generateProgress = 0
 	s.generateProgressMessage = "Starting generation..."
+	s.generateCancel = cancel
+	s.generateCancelling = false
 	s.openGenerateProgressPopup()
 	s.requestRedraw()
 
-	go func(inv, po, outdir string) {
-		outputs, err := hotsheet.Generate(inv, po, outdir, func(progress hotsheet.Progress) {
+	opts := hotsheet.GenerateOptions{
+		InventoryPath: inventoryPath,
+		POPath:        poPath,
+		OutputDir:     outputDir,
+		Input:         input,
+		CompareWith:   editorText(&s.compareEditor),
+		Progress: func(progress hotsheet.Progress) {
 			// Generate invokes this callback from the worker goroutine, so route the
 			// update through the UI event channel before touching AppState-owned UI data.
 			s.queueEvent(generateProgressEvent{Progress: progress})
-		})
-		s.queueEvent(generateCompletedEvent{Outputs: outputs, Err: err})
-	}(inventoryPath, poPath, outputDir)
+		},
+	}
+	if s.consolidated {
+		opts.Layout = hotsheet.LayoutConsolidated
+	}
+	go func() {
+		defer cancel()
+		result, err := hotsheet.GenerateWithOptions(ctx, opts)
+		s.queueEvent(generateCompletedEvent{Result: result, Err: err})
+	}()
+}
+
+// cancelGenerate asks the running generation to stop. The progress popup stays open until the
+// run reports back, which happens once it has removed the files it already wrote.
+func (s *AppState) cancelGenerate() {
+	if !s.generateInProgress || s.generateCancel == nil || s.generateCancelling {
+		return
+	}
+	s.generateCancel()
+	s.generateCancelling = true
+	s.generateProgressMessage = "Cancelling..."
+	s.requestRedraw()
 }
 
 // handleGenerateProgress applies a background generation progress update to the
@@ -134,7 +181,10 @@ func (s *AppState) handleGenerateProgress(progress hotsheet.Progress) {
 		return
 	}
 	s.generateProgress = progress.Percent
-	s.generateProgressMessage = progress.Message
+	if !s.generateCancelling {
+		// Keep "Cancelling..." visible while the run winds down.
+		s.generateProgressMessage = progress.Message
+	}
 	s.requestRedraw()
 }
 
@@ -142,17 +192,34 @@ func (s *AppState) handleGenerateProgress(progress hotsheet.Progress) {
 // the UI state.
 //
 // Successful runs open the results popup; failed runs surface the error in a
-// modal popup and leave the main form intact.
-func (s *AppState) handleGenerateResult(outputs []string, err error) {
+// modal popup and leave the main form intact. When only some product lines
+// failed, the popup also says how many files were still created. A cancelled
+// run only confirms the cancellation.
+func (s *AppState) handleGenerateResult(result *hotsheet.GenerateResult, err error) {
+	outputs := result.Paths()
 	s.generateInProgress = false
 	s.generateProgress = 100
+	s.generateCancel = nil
+	s.generateCancelling = false
+	if errors.Is(err, context.Canceled) {
+		s.openInfoPopup("Generation Cancelled", "Hotsheet generation was cancelled, and the files it had already written were removed.")
+		s.requestRedraw()
+		return
+	}
 	if err != nil {
-		s.openErrorPopup("Generation Failed", err.Error())
+		message := err.Error()
+		if len(outputs) > 0 {
+			message += fmt.Sprintf("\n\n%d other file(s) were still created in the output folder.", len(outputs))
+		}
+		s.openErrorPopup("Generation Failed", message)
 		s.requestRedraw()
 		return
 	}
 
 	s.outputs = outputs
+	s.importIssueCount = len(result.Issues)
+	s.unmatchedOccasions = hotsheet.UnmatchedOccasions(result.Issues)
+	s.generateWarnings = result.Warnings
 	if len(outputs) > 0 {
 		s.selectedOutput = 0
 		s.selectedOutputNeedsScroll = true
@@ -166,12 +233,15 @@ func (s *AppState) handleGenerateResult(outputs []string, err error) {
 	s.requestRedraw()
 }
 
-// resetInputs clears the three main path fields and resets any result-list
+// resetInputs clears the main path fields and the sheet selectors, and resets any result-list
 // selection state so the user can start a fresh run.
 func (s *AppState) resetInputs() {
 	setEditorText(&s.inventoryEditor, "")
 	setEditorText(&s.poEditor, "")
 	setEditorText(&s.outputEditor, "")
+	setEditorText(&s.compareEditor, "")
+	setEditorText(&s.inventorySheetEditor, "")
+	setEditorText(&s.poSheetEditor, "")
 	s.selectedOutput = -1
 	s.selectedOutputNeedsScroll = false
 	s.lastClickedOutput = -1
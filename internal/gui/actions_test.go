@@ -0,0 +1,37 @@
+package gui
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+)
+
+// TestCancelGenerateCancelsOnce verifies that Cancel stops the running generation, shows that it
+// is stopping, and keeps that message while later progress arrives.
+func TestCancelGenerateCancelsOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	s := &AppState{
+		generateInProgress: true,
+		generateCancel: func() {
+			calls++
+			cancel()
+		},
+	}
+
+	s.cancelGenerate()
+	s.cancelGenerate()
+	if calls != 1 || ctx.Err() == nil {
+		t.Fatalf("cancel was called %d time(s), want once", calls)
+	}
+	if !s.generateCancelling || s.generateProgressMessage != "Cancelling..." {
+		t.Fatalf("unexpected cancelling state %v / %q", s.generateCancelling, s.generateProgressMessage)
+	}
+
+	s.handleGenerateProgress(hotsheet.Progress{Percent: 60, Message: "Created 1 of 3 hotsheets."})
+	if s.generateProgress != 60 || s.generateProgressMessage != "Cancelling..." {
+		t.Fatalf("progress after cancelling = %d%% %q, want 60%% and the cancelling message", s.generateProgress, s.generateProgressMessage)
+	}
+}
@@ -0,0 +1,45 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+	"github.com/aarzilli/nucular"
+)
+
+// openClassRulePopup opens the Test Class Rule popup, keeping the last SKU and product line.
+func (s *AppState) openClassRulePopup() {
+	s.currentPopup = popupClassRule
+	s.mw.PopupOpen("Test Class Rule", nucular.WindowMovable|nucular.WindowTitle|nucular.WindowDynamic|nucular.WindowNoScrollbar, s.centeredPopupRect(560, 300), true, s.renderClassRulePopup)
+}
+
+// testClassRule checks which class prefix rule applies to the SKU and product line typed in the
+// popup. The rules file is re-read on every check so edits show up without restarting.
+func (s *AppState) testClassRule() {
+	sku := editorText(&s.classRuleSKUEditor)
+	if sku == "" {
+		s.classRuleResult = "Enter an item code to test."
+		s.requestRedraw()
+		return
+	}
+	match, err := hotsheet.MatchClassRule("", sku, editorText(&s.classRuleLineEditor))
+	if err != nil {
+		s.classRuleResult = err.Error()
+	} else {
+		s.classRuleResult = describeClassRuleMatch(sku, match)
+	}
+	s.requestRedraw()
+}
+
+// describeClassRuleMatch explains a MatchClassRule result for the popup.
+func describeClassRuleMatch(sku string, match hotsheet.ClassRuleMatch) string {
+	if !match.Matched {
+		return fmt.Sprintf("No rule matches %s (%s rules), so its class is shown unchanged.", sku, match.Source)
+	}
+	return fmt.Sprintf("%s matches rule %d, %q (%s rules), and its class is shown as %q.", sku, match.Position, match.Name, match.Source, match.Prefix+"<class>")
+}
+
+// anyClassRuleEditorActive reports whether one of the Test Class Rule fields owns keyboard focus.
+func (s *AppState) anyClassRuleEditorActive() bool {
+	return s.classRuleSKUEditor.Active || s.classRuleLineEditor.Active
+}
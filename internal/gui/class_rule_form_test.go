@@ -0,0 +1,23 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+)
+
+// TestDescribeClassRuleMatch verifies that the Test Class Rule popup names the matching rule and
+// shows how the class will read.
+func TestDescribeClassRuleMatch(t *testing.T) {
+	got := describeClassRuleMatch("AB100BX", hotsheet.ClassRuleMatch{Matched: true, Position: 7, Name: "BX suffix", Prefix: "BX - ", Source: "built-in"})
+	want := `AB100BX matches rule 7, "BX suffix" (built-in rules), and its class is shown as "BX - <class>".`
+	if got != want {
+		t.Fatalf("describeClassRuleMatch() = %q, want %q", got, want)
+	}
+
+	got = describeClassRuleMatch("AB100", hotsheet.ClassRuleMatch{Source: "built-in"})
+	want = "No rule matches AB100 (built-in rules), so its class is shown unchanged."
+	if got != want {
+		t.Fatalf("describeClassRuleMatch() = %q, want %q", got, want)
+	}
+}
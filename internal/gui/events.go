@@ -26,8 +26,8 @@ func (generateProgressEvent) isUIEvent() {}
 // generateCompletedEvent reports the outcome of a background hotsheet
 // generation run.
 type generateCompletedEvent struct {
-	Outputs []string
-	Err     error
+	Result *hotsheet.GenerateResult
+	Err    error
 }
 
 // isUIEvent is a marker method to satisfy the UIEvent interface.
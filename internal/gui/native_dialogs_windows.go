@@ -160,7 +160,8 @@ func pickFile() (string, error) {
 	// COM receives pointers into the UTF-16 backing slices rather than copying
 	// the strings into Go-managed memory.
 	filters, err := prepareDialogFilters([]fileDialogFilter{
-		{DisplayName: "Excel Files (*.xlsx)", Pattern: "*.xlsx"},
+		{DisplayName: "Excel Files (*.xlsx;*.xls)", Pattern: "*.xlsx;*.xls"},
+		{DisplayName: "Delimited Text (*.csv;*.tsv)", Pattern: "*.csv;*.tsv"},
 		{DisplayName: "All files (*.*)", Pattern: "*.*"},
 	})
 	if err != nil {
@@ -11,20 +11,22 @@ import (
 // renderMainForm draws the main application window contents.
 //
 // The layout is intentionally kept close to the original Fyne-based UI: a title,
-// three labeled path pickers, a status line, and the bottom action row.
+// the labeled path pickers, a status line, and the bottom action row.
 func (s *AppState) renderMainForm(w *nucular.Window) {
 	w.Row(30).Dynamic(1)
 	w.Label("Create Unified Hotsheets from Reports", "CC")
 
 	w.Row(18).Dynamic(1)
-	w.LabelColored("Inventory report is required. PO report and output directory are optional.", "CC", color.RGBA{R: 95, G: 95, B: 95, A: 255})
+	w.LabelColored("Inventory report is required. The other fields are optional.", "CC", color.RGBA{R: 95, G: 95, B: 95, A: 255})
 
 	s.renderSpacer(w, 6)
-	s.renderPathField(w, shortcutLabel("Inventory Report:", "I"), "Path to inventory report (.xlsx)", &s.inventoryEditor, s.browseInventory)
+	s.renderPathField(w, shortcutLabel("Inventory Report:", "I"), "Path to inventory report (.xlsx, .xls, .csv, or .tsv)", &s.inventoryEditor, &s.inventorySheetEditor, s.browseInventory)
 	s.renderSpacer(w, 6)
-	s.renderPathField(w, shortcutLabel("PO Report (optional):", "P"), "Path to PO report (.xlsx)", &s.poEditor, s.browsePO)
+	s.renderPathField(w, shortcutLabel("PO Report (optional):", "P"), "Path to PO report (.xlsx, .xls, .csv, or .tsv)", &s.poEditor, &s.poSheetEditor, s.browsePO)
 	s.renderSpacer(w, 6)
-	s.renderPathField(w, shortcutLabel("Output Directory (optional):", "O"), "Directory for generated files", &s.outputEditor, s.browseOutputDir)
+	s.renderPathField(w, shortcutLabel("Output Directory (optional):", "O"), "Directory for generated files", &s.outputEditor, nil, s.browseOutputDir)
+	s.renderSpacer(w, 6)
+	s.renderPathField(w, shortcutLabel("Previous Hotsheet (optional):", "H"), "Hotsheet to list changes against; blank uses the latest one in the output directory", &s.compareEditor, nil, s.browseCompare)
 	s.renderSpacer(w, 8)
 	s.renderStatusLine(w)
 	s.renderSpacer(w, 8)
@@ -33,15 +35,29 @@ func (s *AppState) renderMainForm(w *nucular.Window) {
 }
 
 // renderPathField draws a single labeled path editor with its Browse button and
-// hint text.
-func (s *AppState) renderPathField(w *nucular.Window, labelText, hintText string, editor *nucular.TextEditor, browseFn func()) {
+// hint text. When sheetEditor is non-nil a narrow worksheet selector is drawn
+// between the path and the Browse button.
+func (s *AppState) renderPathField(w *nucular.Window, labelText, hintText string, editor, sheetEditor *nucular.TextEditor, browseFn func()) {
 	editor.Flags = s.pathEditorFlags()
 
-	w.Row(20).Dynamic(1)
-	w.Label(labelText, "LC")
+	if sheetEditor == nil {
+		w.Row(20).Dynamic(1)
+		w.Label(labelText, "LC")
+
+		w.Row(28).Static(0, 90)
+		editor.Edit(w)
+	} else {
+		sheetEditor.Flags = s.pathEditorFlags()
+
+		w.Row(20).Static(0, 110, 90)
+		w.Label(labelText, "LC")
+		w.Label("Sheet (optional):", "LC")
+		w.Label("", "LC")
 
-	w.Row(28).Static(0, 90)
-	editor.Edit(w)
+		w.Row(28).Static(0, 110, 90)
+		editor.Edit(w)
+		sheetEditor.Edit(w)
+	}
 	if w.ButtonText("Browse") && !s.isBusy() {
 		browseFn()
 	}
@@ -50,6 +66,9 @@ func (s *AppState) renderPathField(w *nucular.Window, labelText, hintText string
 	// hint is rendered as a separate muted line below the field when it is empty.
 	w.Row(16).Dynamic(1)
 	if strings.TrimSpace(string(editor.Buffer)) == "" {
+		if sheetEditor != nil {
+			hintText += "; sheet is a name or number, blank uses Sheet1"
+		}
 		w.LabelColored(hintText, "LC", color.RGBA{R: 120, G: 120, B: 120, A: 255})
 	} else {
 		w.Label("", "LC")
@@ -89,8 +108,24 @@ func (s *AppState) renderStatusLine(w *nucular.Window) {
 // renderMainButtons draws the primary action row at the bottom of the form.
 //
 // The requested layout keeps Quit and Check for Updates grouped on the left and
-// Generate Hotsheets aligned on the right.
+// Generate Hotsheets aligned on the right. The configuration tools and the
+// consolidated workbook checkbox sit on their own row above.
 func (s *AppState) renderMainButtons(w *nucular.Window) {
+	w.Row(30).Static(120, 14, 220, 14, 0)
+	if w.ButtonText(buttonShortcutLabel("Settings", "S")) && !s.isBusy() {
+		s.openSettingsPopup()
+	}
+	w.Label("", "LC")
+	if w.ButtonText(buttonShortcutLabel("Test Class Rule", "T")) && !s.isBusy() {
+		s.openClassRulePopup()
+	}
+	w.Label("", "LC")
+	consolidated := s.consolidated
+	if w.CheckboxText(shortcutLabel("One workbook for all product lines", "W"), &consolidated) && !s.isBusy() {
+		s.consolidated = consolidated
+	}
+	s.renderSpacer(w, 4)
+
 	w.Row(34).Static(120, 14, 220, 0, 220)
 	if w.ButtonText(buttonShortcutLabel("Quit", "Q")) {
 		s.quit()
@@ -2,6 +2,7 @@ package gui
 
 import (
 	"fmt"
+	"image/color"
 	"strings"
 	"unicode/utf8"
 
@@ -58,7 +59,7 @@ func (s *AppState) openGenerateProgressPopup() {
 }
 
 // renderGenerateProgressPopup draws the content of the generation progress
-// popup.
+// popup. Cancel stops the run; Escape only hides the popup.
 func (s *AppState) renderGenerateProgressPopup(w *nucular.Window) {
 	if !s.generateInProgress {
 		s.closePopup(w)
@@ -67,6 +68,9 @@ func (s *AppState) renderGenerateProgressPopup(w *nucular.Window) {
 	if s.handlePopupEscape(w) {
 		return
 	}
+	if in := w.Input(); in != nil && hasShortcut(in.Keyboard.Keys, key.CodeC) {
+		s.cancelGenerate()
+	}
 
 	w.Row(28).Dynamic(1)
 	w.Label(fmt.Sprintf("Generating hotsheets... %d%%", s.generateProgress), "LC")
@@ -79,8 +83,8 @@ func (s *AppState) renderGenerateProgressPopup(w *nucular.Window) {
 	w.Label("", "LC")
 	w.Row(32).Static(0, 110, 0)
 	w.Label("", "LC")
-	if w.ButtonText("Cancel") {
-		s.closePopup(w)
+	if w.ButtonText(buttonShortcutLabel("Cancel", "C")) {
+		s.cancelGenerate()
 	}
 	w.Label("", "LC")
 }
@@ -173,7 +177,24 @@ func (s *AppState) renderOutputsPopup(w *nucular.Window) {
 		w.Label(fmt.Sprintf("Created files (%d):", len(s.outputs)), "LC")
 		w.Row(18).Dynamic(1)
 		w.Label("Double-click to open file or use Up/Down and Enter.", "LC")
-		w.Row(235).Dynamic(1)
+		listHeight := 235
+		if s.importIssueCount > 0 {
+			// The issues workbook is already in the list; this line tells the user why.
+			w.Row(18).Dynamic(1)
+			w.Label(fmt.Sprintf("%d import issue(s) found. See the Import Issues sheet or the import_issues workbook.", s.importIssueCount), "LC")
+			listHeight -= 22
+		}
+		if len(s.unmatchedOccasions) > 0 {
+			w.Row(18).Dynamic(1)
+			w.Label(fmt.Sprintf("Filed under Everyday (no occasion match): %s", strings.Join(s.unmatchedOccasions, ", ")), "LC")
+			listHeight -= 22
+		}
+		for _, warning := range s.generateWarnings {
+			w.Row(18).Dynamic(1)
+			w.Label("Warning: "+warning, "LC")
+			listHeight -= 22
+		}
+		w.Row(listHeight).Dynamic(1)
 		if gl, gw := nucular.GroupListStart(w, len(s.outputs), "created-hotsheets", nucular.WindowBorder|nucular.WindowNoHScrollbar); gw != nil {
 			// SkipToVisible keeps large result lists from rendering every row on
 			// every frame while still preserving the current scroll position.
@@ -205,6 +226,138 @@ func (s *AppState) renderOutputsPopup(w *nucular.Window) {
 	w.Label("", "LC")
 }
 
+// renderSettingsPopup draws the Settings popup: the product line being edited, one field per
+// setting, and the Load, Save, and Close buttons.
+func (s *AppState) renderSettingsPopup(w *nucular.Window) {
+	if s.handleSettingsPopupKeyboard(w) {
+		return
+	}
+
+	w.Row(20).Dynamic(1)
+	w.LabelColored("Blank product line edits the defaults used by every product line.", "LC", color.RGBA{R: 95, G: 95, B: 95, A: 255})
+	w.Row(28).Static(250, 0)
+	w.Label("Product line (optional):", "LC")
+	s.settingsLineEditor.Edit(w)
+	for field := range s.settingsEditors {
+		w.Row(26).Static(250, 0)
+		w.Label(settingsFieldLabels[field], "LC")
+		s.settingsEditors[field].Edit(w)
+	}
+	w.Row(26).Static(250, 0)
+	w.Label(settingsWorkersLabel, "LC")
+	s.settingsWorkersEditor.Edit(w)
+	w.Row(26).Dynamic(1)
+	w.CheckboxText("Write derived columns as Excel formulas (all product lines)", &s.settingsFormulas)
+
+	statusColor := color.RGBA{R: 70, G: 110, B: 170, A: 255}
+	if s.settingsError {
+		statusColor = color.RGBA{R: 190, G: 40, B: 40, A: 255}
+	}
+	for _, line := range wrapPopupText(s.settingsStatus, 80) {
+		w.Row(18).Dynamic(1)
+		w.LabelColored(line, "LC", statusColor)
+	}
+	w.Row(12).Dynamic(1)
+	w.Label("", "LC")
+
+	w.Row(32).Static(0, 110, 16, 110, 16, 110, 0)
+	w.Label("", "LC")
+	if w.ButtonText(buttonShortcutLabel("Load", "L")) {
+		s.loadSettingsForm()
+	}
+	w.Label("", "LC")
+	if w.ButtonText(buttonShortcutLabel("Save", "S")) {
+		s.saveSettingsForm()
+	}
+	w.Label("", "LC")
+	if w.ButtonText(buttonShortcutLabel("Close", "C")) {
+		s.closePopup(w)
+	}
+	w.Label("", "LC")
+}
+
+// handleSettingsPopupKeyboard applies the Settings popup shortcuts and returns true when one of
+// them closes the popup. Shortcuts wait while a field is being edited.
+func (s *AppState) handleSettingsPopupKeyboard(w *nucular.Window) bool {
+	if s.handlePopupEscape(w) {
+		return true
+	}
+	if s.anySettingsEditorActive() {
+		return false
+	}
+
+	in := w.Input()
+	if in == nil {
+		return false
+	}
+
+	switch {
+	case hasShortcut(in.Keyboard.Keys, key.CodeL):
+		s.loadSettingsForm()
+	case hasShortcut(in.Keyboard.Keys, key.CodeS):
+		s.saveSettingsForm()
+	case hasShortcut(in.Keyboard.Keys, key.CodeC):
+		s.closePopup(w)
+		return true
+	}
+	return false
+}
+
+// renderClassRulePopup draws the Test Class Rule popup: the SKU and product line to check, the
+// matching rule, and the Test and Close buttons.
+func (s *AppState) renderClassRulePopup(w *nucular.Window) {
+	if s.handleClassRulePopupKeyboard(w) {
+		return
+	}
+
+	w.Row(28).Static(200, 0)
+	w.Label("Item code:", "LC")
+	s.classRuleSKUEditor.Edit(w)
+	w.Row(28).Static(200, 0)
+	w.Label("Product line (optional):", "LC")
+	s.classRuleLineEditor.Edit(w)
+	s.renderPopupMessage(w, s.classRuleResult, 64)
+	w.Row(12).Dynamic(1)
+	w.Label("", "LC")
+
+	w.Row(32).Static(0, 110, 24, 110, 0)
+	w.Label("", "LC")
+	if w.ButtonText(buttonShortcutLabel("Test", "T")) {
+		s.testClassRule()
+	}
+	w.Label("", "LC")
+	if w.ButtonText(buttonShortcutLabel("Close", "C")) {
+		s.closePopup(w)
+	}
+	w.Label("", "LC")
+}
+
+// handleClassRulePopupKeyboard applies the Test Class Rule shortcuts and returns true when one of
+// them closes the popup. Enter runs the test from either field.
+func (s *AppState) handleClassRulePopupKeyboard(w *nucular.Window) bool {
+	if s.handlePopupEscape(w) {
+		return true
+	}
+
+	in := w.Input()
+	if in == nil {
+		return false
+	}
+
+	switch {
+	case in.Keyboard.Pressed(key.CodeReturnEnter), in.Keyboard.Pressed(key.CodeKeypadEnter):
+		s.testClassRule()
+	case s.anyClassRuleEditorActive():
+		return false
+	case hasShortcut(in.Keyboard.Keys, key.CodeT):
+		s.testClassRule()
+	case hasShortcut(in.Keyboard.Keys, key.CodeC):
+		s.closePopup(w)
+		return true
+	}
+	return false
+}
+
 // handleOutputsPopupKeyboard applies keyboard navigation and activation for the
 // generated output list while the popup is open.
 func (s *AppState) handleOutputsPopupKeyboard(w *nucular.Window) {
@@ -0,0 +1,206 @@
+package gui
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+	"github.com/aarzilli/nucular"
+)
+
+// settingsField identifies one editable value in the Settings popup.
+type settingsField int
+
+const (
+	settingsRedMonths settingsField = iota
+	settingsYellowMonths
+	settingsEverydayMonths
+	settingsWinterMonths
+	settingsSpringMonths
+	settingsYTDRedFill
+	settingsYTDYellowFill
+	settingsYTDGreenFill
+	settingsPYRedFill
+	settingsPYYellowFill
+	settingsPYGreenFill
+	settingsFieldCount
+)
+
+// settingsFillStart is the first color field; the fields before it are month counts.
+const settingsFillStart = settingsYTDRedFill
+
+// settingsFieldLabels are the Settings popup labels, in settingsField order.
+var settingsFieldLabels = [settingsFieldCount]string{
+	"MTO red at or below (months):",
+	"MTO yellow at or below (months):",
+	"Everyday season (months):",
+	"Winter season (months):",
+	"Spring season (months):",
+	"MTO YTD red fill:",
+	"MTO YTD yellow fill:",
+	"MTO YTD green fill:",
+	"MTO PY red fill:",
+	"MTO PY yellow fill:",
+	"MTO PY green fill:",
+}
+
+// settingsFormValues formats line settings as the Settings popup's field text.
+func settingsFormValues(line hotsheet.LineSettings) [settingsFieldCount]string {
+	months := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return [settingsFieldCount]string{
+		settingsRedMonths:      months(line.RedMonths),
+		settingsYellowMonths:   months(line.YellowMonths),
+		settingsEverydayMonths: months(line.SeasonMonths.Everyday),
+		settingsWinterMonths:   months(line.SeasonMonths.Winter),
+		settingsSpringMonths:   months(line.SeasonMonths.Spring),
+		settingsYTDRedFill:     line.YTDFills.Red,
+		settingsYTDYellowFill:  line.YTDFills.Yellow,
+		settingsYTDGreenFill:   line.YTDFills.Green,
+		settingsPYRedFill:      line.PYFills.Red,
+		settingsPYYellowFill:   line.PYFills.Yellow,
+		settingsPYGreenFill:    line.PYFills.Green,
+	}
+}
+
+// parseSettingsForm converts the Settings popup's field text back into line settings. Range and
+// color checks are left to hotsheet.Settings.Validate.
+func parseSettingsForm(values [settingsFieldCount]string) (hotsheet.LineSettings, error) {
+	var months [settingsFillStart]float64
+	for field := settingsField(0); field < settingsFillStart; field++ {
+		v, err := strconv.ParseFloat(strings.TrimSpace(values[field]), 64)
+		if err != nil {
+			return hotsheet.LineSettings{}, fmt.Errorf("%s %q is not a number", strings.TrimSuffix(settingsFieldLabels[field], ":"), values[field])
+		}
+		months[field] = v
+	}
+	fill := func(field settingsField) string {
+		return strings.ToUpper(strings.TrimSpace(values[field]))
+	}
+	return hotsheet.LineSettings{
+		RedMonths:    months[settingsRedMonths],
+		YellowMonths: months[settingsYellowMonths],
+		YTDFills:     hotsheet.MTOFills{Red: fill(settingsYTDRedFill), Yellow: fill(settingsYTDYellowFill), Green: fill(settingsYTDGreenFill)},
+		PYFills:      hotsheet.MTOFills{Red: fill(settingsPYRedFill), Yellow: fill(settingsPYYellowFill), Green: fill(settingsPYGreenFill)},
+		SeasonMonths: hotsheet.SeasonMonths{
+			Everyday: months[settingsEverydayMonths],
+			Winter:   months[settingsWinterMonths],
+			Spring:   months[settingsSpringMonths],
+		},
+	}, nil
+}
+
+// settingsWorkersLabel labels the Settings popup's workbooks-at-once field.
+const settingsWorkersLabel = "Workbooks built at once:"
+
+// settingsWorkersText formats the worker count for the Settings popup; the default is left blank.
+func settingsWorkersText(workers int) string {
+	if workers == 0 {
+		return ""
+	}
+	return strconv.Itoa(workers)
+}
+
+// parseSettingsWorkers reads the Settings popup's worker count. Blank keeps the default of one
+// workbook per CPU.
+func parseSettingsWorkers(text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	workers, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a whole number", strings.TrimSuffix(settingsWorkersLabel, ":"), text)
+	}
+	return workers, nil
+}
+
+// openSettingsPopup loads the settings file and opens the Settings popup on the defaults.
+func (s *AppState) openSettingsPopup() {
+	path, err := hotsheet.SettingsPath()
+	if err != nil {
+		s.openErrorPopup("Settings Error", err.Error())
+		return
+	}
+	settings, err := hotsheet.LoadSettings(path)
+	if err != nil {
+		s.openErrorPopup("Settings Error", err.Error())
+		return
+	}
+
+	s.settings = settings
+	s.settingsPath = path
+	s.settingsFormulas = settings.Formulas
+	setEditorText(&s.settingsWorkersEditor, settingsWorkersText(settings.Workers))
+	setEditorText(&s.settingsLineEditor, "")
+	s.loadSettingsForm()
+	s.currentPopup = popupSettings
+	s.mw.PopupOpen("Settings", nucular.WindowMovable|nucular.WindowTitle|nucular.WindowDynamic|nucular.WindowNoScrollbar, s.centeredPopupRect(620, 620), true, s.renderSettingsPopup)
+}
+
+// loadSettingsForm fills the Settings popup fields with the effective settings of the product
+// line typed in the popup, or the defaults when it is blank.
+func (s *AppState) loadSettingsForm() {
+	line := editorText(&s.settingsLineEditor)
+	for field, value := range settingsFormValues(s.settings.ForProductLine(line)) {
+		setEditorText(&s.settingsEditors[field], value)
+	}
+	if line == "" {
+		s.setSettingsStatus("Showing the default settings.", false)
+		return
+	}
+	s.setSettingsStatus(fmt.Sprintf("Showing the settings for product line %s.", line), false)
+}
+
+// saveSettingsForm stores the Settings popup fields for the chosen product line and writes the
+// settings file. The file and the loaded settings are left unchanged when a value is invalid.
+func (s *AppState) saveSettingsForm() {
+	var values [settingsFieldCount]string
+	for field := range values {
+		values[field] = editorText(&s.settingsEditors[field])
+	}
+	line, err := parseSettingsForm(values)
+	if err != nil {
+		s.setSettingsStatus(err.Error(), true)
+		return
+	}
+	workers, err := parseSettingsWorkers(editorText(&s.settingsWorkersEditor))
+	if err != nil {
+		s.setSettingsStatus(err.Error(), true)
+		return
+	}
+	updated := s.settings
+	updated.Formulas = s.settingsFormulas
+	updated.Workers = workers
+	updated.ProductLines = maps.Clone(s.settings.ProductLines)
+	updated.SetProductLine(editorText(&s.settingsLineEditor), line)
+	if err := hotsheet.SaveSettings(s.settingsPath, updated); err != nil {
+		s.setSettingsStatus(err.Error(), true)
+		return
+	}
+	s.settings = updated
+	s.setSettingsStatus(fmt.Sprintf("Saved to %s.", s.settingsPath), false)
+}
+
+// setSettingsStatus replaces the Settings popup's status message.
+func (s *AppState) setSettingsStatus(message string, isError bool) {
+	s.settingsStatus = message
+	s.settingsError = isError
+	s.requestRedraw()
+}
+
+// anySettingsEditorActive reports whether one of the Settings popup fields owns keyboard focus.
+func (s *AppState) anySettingsEditorActive() bool {
+	if s.settingsLineEditor.Active || s.settingsWorkersEditor.Active {
+		return true
+	}
+	for field := range s.settingsEditors {
+		if s.settingsEditors[field].Active {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package gui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+)
+
+// TestSettingsFormRoundTrips verifies that the Settings popup fields show and read back the same
+// settings, with fill colors normalized to upper case.
+func TestSettingsFormRoundTrips(t *testing.T) {
+	want := hotsheet.DefaultSettings().ForProductLine("")
+	values := settingsFormValues(want)
+	if values[settingsWinterMonths] != "6.5" || values[settingsPYRedFill] != "#FF6666" {
+		t.Fatalf("unexpected form values %q", values)
+	}
+
+	values[settingsYTDGreenFill] = " #00ff00 "
+	want.YTDFills.Green = "#00FF00"
+	got, err := parseSettingsForm(values)
+	if err != nil {
+		t.Fatalf("parseSettingsForm returned error: %v", err)
+	}
+	if got != want {
+		t.Fatalf("parseSettingsForm() = %+v, want %+v", got, want)
+	}
+}
+
+// TestParseSettingsFormRejectsNonNumbers verifies that a month field that is not a number is
+// reported by its label.
+func TestParseSettingsFormRejectsNonNumbers(t *testing.T) {
+	values := settingsFormValues(hotsheet.DefaultSettings().ForProductLine(""))
+	values[settingsSpringMonths] = "five"
+	_, err := parseSettingsForm(values)
+	if err == nil || !strings.Contains(err.Error(), "Spring season (months)") {
+		t.Fatalf("parseSettingsForm() error = %v, want one naming the Spring season field", err)
+	}
+}
+
+// TestParseSettingsWorkers verifies that a blank worker count keeps the default and that other
+// text must be a whole number.
+func TestParseSettingsWorkers(t *testing.T) {
+	if got, err := parseSettingsWorkers("  "); got != 0 || err != nil {
+		t.Fatalf("parseSettingsWorkers(blank) = %d, %v; want 0, nil", got, err)
+	}
+	if got, err := parseSettingsWorkers(" 3 "); got != 3 || err != nil {
+		t.Fatalf("parseSettingsWorkers(3) = %d, %v; want 3, nil", got, err)
+	}
+	if _, err := parseSettingsWorkers("two"); err == nil || !strings.Contains(err.Error(), "Workbooks built at once") {
+		t.Fatalf("parseSettingsWorkers(two) error = %v, want one naming the field", err)
+	}
+	if settingsWorkersText(0) != "" || settingsWorkersText(2) != "2" {
+		t.Fatalf("unexpected worker text %q / %q", settingsWorkersText(0), settingsWorkersText(2))
+	}
+}
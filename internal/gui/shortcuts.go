@@ -27,12 +27,20 @@ func (s *AppState) handleMainKeyboard(w *nucular.Window) {
 		s.browsePO()
 	case hasShortcut(in.Keyboard.Keys, key.CodeO) && !s.isBusy():
 		s.browseOutputDir()
+	case hasShortcut(in.Keyboard.Keys, key.CodeH) && !s.isBusy():
+		s.browseCompare()
 	case hasShortcut(in.Keyboard.Keys, key.CodeQ):
 		s.quit()
 	case hasShortcut(in.Keyboard.Keys, key.CodeU) && !s.isBusy() && !s.updateCheckInProgress:
 		s.startUpdateCheck(true)
 	case hasShortcut(in.Keyboard.Keys, key.CodeG) && !s.isBusy() && !s.updateCheckInProgress:
 		s.startGenerate()
+	case hasShortcut(in.Keyboard.Keys, key.CodeS) && !s.isBusy():
+		s.openSettingsPopup()
+	case hasShortcut(in.Keyboard.Keys, key.CodeT) && !s.isBusy():
+		s.openClassRulePopup()
+	case hasShortcut(in.Keyboard.Keys, key.CodeW) && !s.isBusy():
+		s.consolidated = !s.consolidated
 	}
 }
 
@@ -1,8 +1,10 @@
 package gui
 
 import (
+	"context"
 	"time"
 
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
 	"github.com/aarzilli/nucular"
 	"github.com/aarzilli/nucular/rect"
 	"golang.org/x/mobile/event/key"
@@ -22,6 +24,8 @@ const (
 	popupUpdateAvailable
 	popupUpdateProgress
 	popupOutputs
+	popupSettings
+	popupClassRule
 )
 
 // AppState contains all mutable state owned by the GUI layer.
@@ -37,10 +41,18 @@ type AppState struct {
 	windowBounds          rect.Rect
 	currentPopup          popupKind
 
-	// Text editors back the three path fields in the main form.
+	// Text editors back the path fields in the main form.
 	inventoryEditor nucular.TextEditor
 	poEditor        nucular.TextEditor
 	outputEditor    nucular.TextEditor
+	// compareEditor names the previous hotsheet the Changes sheet compares with; blank searches
+	// the output directory.
+	compareEditor nucular.TextEditor
+	// Sheet editors choose the worksheet read from each report workbook.
+	inventorySheetEditor nucular.TextEditor
+	poSheetEditor        nucular.TextEditor
+	// consolidated writes every product line into one workbook instead of one per line.
+	consolidated bool
 
 	// Output selection state is tracked separately from the rendered list because
 	// the immediate-mode UI is rebuilt each frame.
@@ -49,6 +61,34 @@ type AppState struct {
 	selectedOutputNeedsScroll bool
 	lastClickedOutput         int
 	lastClickAt               time.Time
+	// importIssueCount is the number of import issues reported by the last run.
+	importIssueCount int
+	// unmatchedOccasions lists the occasions from the last run that matched no season token and
+	// were filed under Everyday.
+	unmatchedOccasions []string
+	// generateWarnings are the problems the last run worked around, such as an unreadable PO report.
+	generateWarnings []string
+
+	// settings is the settings file being edited in the Settings popup, read from settingsPath.
+	settings     hotsheet.Settings
+	settingsPath string
+	// settingsLineEditor picks the product line being edited; blank edits the defaults.
+	settingsLineEditor nucular.TextEditor
+	// settingsEditors back the Settings popup fields in settingsField order.
+	settingsEditors [settingsFieldCount]nucular.TextEditor
+	// settingsFormulas is the popup's formulas checkbox, which applies to every product line.
+	settingsFormulas bool
+	// settingsWorkersEditor backs the popup's workbooks-at-once field; blank means one per CPU.
+	settingsWorkersEditor nucular.TextEditor
+	// settingsStatus is the popup's last load or save message; settingsError marks a failed save.
+	settingsStatus string
+	settingsError  bool
+
+	// classRuleSKUEditor and classRuleLineEditor hold the SKU and product line checked in the
+	// Test Class Rule popup, and classRuleResult describes the last check.
+	classRuleSKUEditor  nucular.TextEditor
+	classRuleLineEditor nucular.TextEditor
+	classRuleResult     string
 
 	generateInProgress bool
 	// generateProgress and generateProgressMessage are written only from the UI
@@ -62,17 +102,32 @@ type AppState struct {
 	updateStatusMessage     string
 	latestVersion           string
 	latestAssetURL          string
+
+	// generateCancel cancels the running generation; generateCancelling is set once it has been
+	// called so the popup can say the run is stopping.
+	generateCancel     context.CancelFunc
+	generateCancelling bool
 }
 
 // NewAppState constructs the initial GUI state.
 func NewAppState() *AppState {
 	state := &AppState{
-		events:            make(chan UIEvent, 16),
-		selectedOutput:    -1,
-		lastClickedOutput: -1,
-		inventoryEditor:   newPathEditor(),
-		poEditor:          newPathEditor(),
-		outputEditor:      newPathEditor(),
+		events:                make(chan UIEvent, 16),
+		selectedOutput:        -1,
+		lastClickedOutput:     -1,
+		inventoryEditor:       newPathEditor(),
+		poEditor:              newPathEditor(),
+		outputEditor:          newPathEditor(),
+		compareEditor:         newPathEditor(),
+		inventorySheetEditor:  newPathEditor(),
+		poSheetEditor:         newPathEditor(),
+		settingsLineEditor:    newPathEditor(),
+		settingsWorkersEditor: newPathEditor(),
+		classRuleSKUEditor:    newPathEditor(),
+		classRuleLineEditor:   newPathEditor(),
+	}
+	for field := range state.settingsEditors {
+		state.settingsEditors[field] = newPathEditor()
 	}
 	return state
 }
@@ -114,7 +169,7 @@ func (s *AppState) drainEvents() {
 			case generateProgressEvent:
 				s.handleGenerateProgress(e.Progress)
 			case generateCompletedEvent:
-				s.handleGenerateResult(e.Outputs, e.Err)
+				s.handleGenerateResult(e.Result, e.Err)
 			case updateCheckCompletedEvent:
 				s.handleUpdateCheckResult(e.Result, e.Err, e.ShowNoUpdates)
 			case selfUpdateCompletedEvent:
@@ -147,5 +202,6 @@ func shortcutModifier() key.Modifiers {
 // anyEditorActive reports whether one of the main form text inputs currently
 // owns keyboard focus.
 func (s *AppState) anyEditorActive() bool {
-	return s.inventoryEditor.Active || s.poEditor.Active || s.outputEditor.Active
+	return s.inventoryEditor.Active || s.poEditor.Active || s.outputEditor.Active || s.compareEditor.Active ||
+		s.inventorySheetEditor.Active || s.poSheetEditor.Active
 }
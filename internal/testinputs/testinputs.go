@@ -0,0 +1,105 @@
+// Package testinputs provides the inventory reports and the isolated config folder that the
+// generation tests of the hotsheet, cli, and watch packages share.
+//
+// Tests never point the run at config files that do not exist to get the built-in values.
+// Instead Main gives the test binary a config folder of its own, holding only a settings file
+// that builds one workbook at a time, so every other config file falls back to the built-in
+// values and the real app config is never read or written.
+package testinputs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// appConfigDirName matches the app's folder inside the user config directory.
+const appConfigDirName = "bsc-hotsheet"
+
+// testSettings builds one workbook at a time, so progress and cancellation are deterministic.
+const testSettings = `{"workers": 1, "default": {}}`
+
+// InventoryHeader is a Sage inventory header row with the columns shuffled relative to the
+// historical B/D/F/... layout.
+var InventoryHeader = []string{
+	"", "Item Code", "Occasion", "Class", "Status", "Qty On Hand", "Qty On PO", "Qty On SO", "Qty On BO",
+	"Qty Sold YTD", "Qty Issued YTD", "Qty Sold PY", "Qty Issued PY", "Dollars Sold YTD", "Dollars Sold PY",
+	"Description",
+}
+
+// InventoryValueRow builds the value row that follows an item-code row under InventoryHeader.
+func InventoryValueRow(productLine, occasion, onHand string) []string {
+	return []string{"", productLine, occasion, "Counter Cards", "Active", onHand, "10", "2", "1", "30", "0", "40", "0", "$120.00", "$90.00", "Birthday card"}
+}
+
+// InventoryCSV returns an inventory report with one Birthday item per product line, numbered
+// SKU0, SKU1, and so on.
+func InventoryCSV(productLines ...string) string {
+	lines := []string{strings.Join(InventoryHeader, ",")}
+	for i, productLine := range productLines {
+		lines = append(lines, fmt.Sprintf(",SKU%d", i), strings.Join(InventoryValueRow(productLine, "Birthday", "25"), ","))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Inputs are the files a test run reads besides the shared config folder.
+type Inputs struct {
+	// InventoryPath is the InventoryCSV report of the requested product lines.
+	InventoryPath string
+	// HistoryDir is an empty snapshot history folder of the test's own, so parallel runs do not
+	// measure trends from each other's snapshots.
+	HistoryDir string
+}
+
+// Write writes the InventoryCSV report of productLines to a temporary folder of t and returns
+// the test's inputs.
+func Write(t testing.TB, productLines ...string) Inputs {
+	t.Helper()
+
+	dir := t.TempDir()
+	inputs := Inputs{
+		InventoryPath: filepath.Join(dir, "inventory.csv"),
+		HistoryDir:    filepath.Join(dir, "history"),
+	}
+	if err := os.WriteFile(inputs.InventoryPath, []byte(InventoryCSV(productLines...)), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	return inputs
+}
+
+// Main runs a package's tests with the user config directory pointed at a new temporary folder
+// and returns the exit code for os.Exit. It is called from TestMain because t.Setenv cannot be
+// used by parallel tests.
+func Main(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "hotsheet-config-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create test config folder: %v\n", err)
+		return 1
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// os.UserConfigDir reads AppData on Windows, HOME on macOS, and XDG_CONFIG_HOME elsewhere.
+	for _, name := range []string{"AppData", "HOME", "XDG_CONFIG_HOME"} {
+		if err := os.Setenv(name, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to set %s: %v\n", name, err)
+			return 1
+		}
+	}
+	configDir, err := os.UserConfigDir()
+	if err == nil {
+		configDir = filepath.Join(configDir, appConfigDirName)
+		err = os.MkdirAll(configDir, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(configDir, "settings.json"), []byte(testSettings), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write test settings: %v\n", err)
+		return 1
+	}
+	return m.Run()
+}
@@ -0,0 +1,440 @@
+// Package watch regenerates hotsheets whenever new Sage exports land in a folder.
+//
+// The watcher polls rather than subscribing to file system events, because the exports usually
+// land on a network share where change notifications are unreliable. A file is only used once
+// its size and modification time have stopped changing, so a report that is still being copied
+// is never read half-written.
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+)
+
+// Defaults used for zero Config fields.
+const (
+	DefaultInterval         = 30 * time.Second
+	DefaultStableFor        = 10 * time.Second
+	DefaultInventoryPattern = "*inventory*"
+	DefaultPOPattern        = "po*"
+	// DefaultRunLogName is the run log written in the output folder.
+	DefaultRunLogName = "hotsheet_runs.jsonl"
+)
+
+// reportExtensions are the report formats the generator can read.
+var reportExtensions = []string{".xlsx", ".xls", ".csv", ".tsv"}
+
+// Config describes a watched folder.
+type Config struct {
+	// Dir is the folder the Sage exports land in.
+	Dir string
+	// OutputRoot is the folder that holds one dated subfolder (YYYY-MM-DD) per day of runs.
+	OutputRoot string
+	// RunLog is the JSON Lines file each run is appended to. Empty uses hotsheet_runs.jsonl in
+	// OutputRoot.
+	RunLog string
+	// InventoryPattern and POPattern are file name globs, matched ignoring case, that tell the
+	// inventory and PO exports apart. A file matching both is treated as an inventory report.
+	// Empty uses "*inventory*" and "po*".
+	InventoryPattern string
+	POPattern        string
+	// Interval is how often the folder is scanned. Zero uses 30 seconds.
+	Interval time.Duration
+	// StableFor is how long a file's size and modification time must stay unchanged before it is
+	// used. Zero uses 10 seconds.
+	StableFor time.Duration
+	// Options are the generation options for every run. The report paths, output folder, and
+	// clock are filled in by the watcher.
+	Options hotsheet.GenerateOptions
+	// Now is the watcher's clock. Nil uses time.Now.
+	Now func() time.Time
+	// Logger receives the watcher's own log. Nil discards it.
+	Logger *slog.Logger
+}
+
+// FileState identifies one version of a report file.
+type FileState struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// same reports whether s and other are the same version of the same file.
+func (s *FileState) same(other *FileState) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return s.Path == other.Path && s.Size == other.Size && s.ModTime.Equal(other.ModTime)
+}
+
+// RunRecord is one line of the run log. A scan of the watched folder that failed is recorded
+// with only the times and the error.
+type RunRecord struct {
+	Started   time.Time  `json:"started"`
+	Finished  time.Time  `json:"finished"`
+	Inventory FileState  `json:"inventory"`
+	PO        *FileState `json:"po,omitempty"`
+	OutputDir string     `json:"outputDir"`
+	Files     []string   `json:"files"`
+	Issues    int        `json:"issues"`
+	Warnings  []string   `json:"warnings,omitempty"`
+	// Error is the run's error message, when it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// observation tracks how long a file has looked unchanged.
+type observation struct {
+	state FileState
+	since time.Time
+}
+
+// Watcher polls a folder and runs the generator for each new pair of stable reports.
+type Watcher struct {
+	cfg  Config
+	seen map[string]observation
+	// lastInventory and lastPO are the reports of the last run, so unchanged files are not
+	// generated again.
+	lastInventory *FileState
+	lastPO        *FileState
+	// lastOutputDir is the folder of the last successful run, whose hotsheets the next day's are
+	// compared with.
+	lastOutputDir string
+	// lastScanErr is the error of the previous scan, or "" when it succeeded, so a folder that
+	// stays unreadable is recorded once rather than on every tick.
+	lastScanErr string
+}
+
+// New checks cfg, fills in its defaults, and picks up the last run from the run log so a restarted
+// watcher does not regenerate reports it already handled.
+func New(cfg Config) (*Watcher, error) {
+	if strings.TrimSpace(cfg.Dir) == "" {
+		return nil, errors.New("a folder to watch is required")
+	}
+	if info, err := os.Stat(cfg.Dir); err != nil {
+		return nil, fmt.Errorf("failed to open watched folder: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("watched path %s is not a folder", cfg.Dir)
+	}
+	if strings.TrimSpace(cfg.OutputRoot) == "" {
+		return nil, errors.New("an output folder is required")
+	}
+	if cfg.Interval < 0 || cfg.StableFor < 0 {
+		return nil, errors.New("the interval and stable time must not be negative")
+	}
+	if cfg.RunLog == "" {
+		cfg.RunLog = filepath.Join(cfg.OutputRoot, DefaultRunLogName)
+	}
+	if cfg.InventoryPattern == "" {
+		cfg.InventoryPattern = DefaultInventoryPattern
+	}
+	if cfg.POPattern == "" {
+		cfg.POPattern = DefaultPOPattern
+	}
+	for _, pattern := range []string{cfg.InventoryPattern, cfg.POPattern} {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.StableFor == 0 {
+		cfg.StableFor = DefaultStableFor
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.DiscardHandler)
+	}
+
+	w := &Watcher{cfg: cfg, seen: make(map[string]observation)}
+	last, err := lastGenerationRecord(cfg.RunLog)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		w.lastInventory = &last.Inventory
+		w.lastPO = last.PO
+		if last.Error == "" {
+			w.lastOutputDir = last.OutputDir
+		}
+	}
+	return w, nil
+}
+
+// Run polls the folder every Interval until ctx is cancelled, calling onRun after each
+// generation and each new scan error. A folder that cannot be scanned is tried again on the next
+// tick. It returns nil once ctx is cancelled and an error only when the run log cannot be
+// written.
+func (w *Watcher) Run(ctx context.Context, onRun func(RunRecord)) error {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		record, err := w.Poll(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record != nil && onRun != nil {
+			onRun(*record)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll scans the folder once. When the newest inventory report and the newest PO report are both
+// stable and differ from the last run's, it generates the hotsheets, appends the run to the run
+// log, and returns the record; otherwise it returns nil. A failed generation is recorded, not
+// returned, and is not retried until one of the reports changes. A failed scan is logged and
+// recorded the same way, once until the error changes, and the next Poll scans again. Poll
+// only returns an error when the run log cannot be written.
+func (w *Watcher) Poll(ctx context.Context) (*RunRecord, error) {
+	now := w.cfg.Now()
+	inventory, po, stable, err := w.scan(now)
+	if err != nil {
+		return w.recordScanError(now, err)
+	}
+	w.lastScanErr = ""
+	if inventory == nil || !stable {
+		return nil, nil
+	}
+	if inventory.same(w.lastInventory) && po.same(w.lastPO) {
+		return nil, nil
+	}
+
+	record, err := w.generate(ctx, now, *inventory, po)
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.lastInventory, w.lastPO = inventory, po
+	return record, nil
+}
+
+// recordScanError logs a failed scan and, unless the previous scan failed the same way, appends it
+// to the run log and returns its record.
+func (w *Watcher) recordScanError(now time.Time, scanErr error) (*RunRecord, error) {
+	w.cfg.Logger.Error("failed to scan watched folder", "dir", w.cfg.Dir, "err", scanErr)
+	if scanErr.Error() == w.lastScanErr {
+		return nil, nil
+	}
+	w.lastScanErr = scanErr.Error()
+	record := &RunRecord{Started: now, Finished: now, Error: scanErr.Error()}
+	if err := appendRunRecord(w.cfg.RunLog, *record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// scan lists the report files, updates how long each has been unchanged, and returns the newest
+// inventory and PO reports. stable is false while either of them is still changing.
+func (w *Watcher) scan(now time.Time) (inventory, po *FileState, stable bool, err error) {
+	dirEntries, err := os.ReadDir(w.cfg.Dir)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to scan watched folder: %w", err)
+	}
+
+	present := make(map[string]bool)
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !isReportFile(name) {
+			continue
+		}
+		isInventory := matchesPattern(w.cfg.InventoryPattern, name)
+		if !isInventory && !matchesPattern(w.cfg.POPattern, name) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		state := FileState{Path: filepath.Join(w.cfg.Dir, name), Size: info.Size(), ModTime: info.ModTime()}
+		present[state.Path] = true
+		if prev, ok := w.seen[state.Path]; !ok || !prev.state.same(&state) {
+			w.seen[state.Path] = observation{state: state, since: now}
+		}
+		if isInventory {
+			inventory = newer(inventory, state)
+		} else {
+			po = newer(po, state)
+		}
+	}
+	for path := range w.seen {
+		if !present[path] {
+			delete(w.seen, path)
+		}
+	}
+
+	stable = w.isStable(inventory, now) && w.isStable(po, now)
+	return inventory, po, stable, nil
+}
+
+// isStable reports whether file has been unchanged for StableFor. A missing file is stable.
+func (w *Watcher) isStable(file *FileState, now time.Time) bool {
+	if file == nil {
+		return true
+	}
+	return now.Sub(w.seen[file.Path].since) >= w.cfg.StableFor
+}
+
+// generate runs the generator for one pair of reports into the day's output folder and appends
+// the run to the run log. Unless the options name a hotsheet to compare with, the first run of a
+// day compares with the hotsheets of the last successful run, in an earlier day's folder.
+func (w *Watcher) generate(ctx context.Context, now time.Time, inventory FileState, po *FileState) (*RunRecord, error) {
+	outputDir := filepath.Join(w.cfg.OutputRoot, now.Format("2006-01-02"))
+	opts := w.cfg.Options
+	opts.InventoryPath = inventory.Path
+	opts.POPath = ""
+	if po != nil {
+		opts.POPath = po.Path
+	}
+	opts.OutputDir = outputDir
+	opts.Now = w.cfg.Now
+	if opts.CompareWith == "" && w.lastOutputDir != "" && w.lastOutputDir != outputDir {
+		if _, err := os.Stat(w.lastOutputDir); err == nil {
+			opts.CompareWith = w.lastOutputDir
+		}
+	}
+
+	w.cfg.Logger.Info("generating hotsheets", "inventory", opts.InventoryPath, "po", opts.POPath, "outputDir", outputDir)
+	result := &hotsheet.GenerateResult{}
+	genErr := os.MkdirAll(outputDir, 0o755)
+	if genErr != nil {
+		genErr = fmt.Errorf("failed to create output folder: %w", genErr)
+	} else {
+		result, genErr = hotsheet.GenerateWithOptions(ctx, opts)
+	}
+	if ctx.Err() != nil {
+		w.cfg.Logger.Info("generation cancelled", "inventory", opts.InventoryPath)
+		return nil, nil
+	}
+	record := &RunRecord{
+		Started:   now,
+		Finished:  w.cfg.Now(),
+		Inventory: inventory,
+		PO:        po,
+		OutputDir: outputDir,
+		Files:     result.Paths(),
+		Issues:    len(result.Issues),
+		Warnings:  result.Warnings,
+	}
+	if genErr != nil {
+		record.Error = genErr.Error()
+		w.cfg.Logger.Error("generation failed", "inventory", opts.InventoryPath, "err", genErr)
+	} else {
+		w.lastOutputDir = outputDir
+		w.cfg.Logger.Info("generation completed", "inventory", opts.InventoryPath, "files", len(record.Files))
+	}
+	if err := appendRunRecord(w.cfg.RunLog, *record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// isReportFile reports whether name is a report format the generator reads. Office lock files
+// and hidden files are ignored.
+func isReportFile(name string) bool {
+	if strings.HasPrefix(name, "~$") || strings.HasPrefix(name, ".") {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, reportExt := range reportExtensions {
+		if ext == reportExt {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern matches a file name glob ignoring case.
+func matchesPattern(pattern, name string) bool {
+	ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+// newer returns whichever of current and candidate was modified last, preferring the later name
+// when both were modified at the same time.
+func newer(current *FileState, candidate FileState) *FileState {
+	if current == nil || candidate.ModTime.After(current.ModTime) ||
+		(candidate.ModTime.Equal(current.ModTime) && candidate.Path > current.Path) {
+		return &candidate
+	}
+	return current
+}
+
+// appendRunRecord adds record to the run log as one JSON line.
+func appendRunRecord(path string, record RunRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create run log folder: %w", err)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode run record: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open run log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write run log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write run log: %w", err)
+	}
+	return nil
+}
+
+// lastGenerationRecord returns the last record in the run log that ran the generator, or nil when
+// there is none yet. Scan errors and lines that cannot be read, such as one cut short by a
+// crash, are skipped.
+func lastGenerationRecord(path string) (*RunRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run log: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var last *RunRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err == nil && record.Inventory.Path != "" {
+			last = &record
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run log: %w", err)
+	}
+	return last, nil
+}
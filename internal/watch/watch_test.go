@@ -0,0 +1,254 @@
+package watch
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepozopo/bsc-hotsheet-update/hotsheet"
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/testinputs"
+	"github.com/xuri/excelize/v2"
+)
+
+// testInventoryCSV is an inventory report with one BAS item.
+var testInventoryCSV = testinputs.InventoryCSV("BAS")
+
+// TestMain runs the package's tests with a config folder of their own.
+func TestMain(m *testing.M) {
+	os.Exit(testinputs.Main(m))
+}
+
+// TestPollWaitsForStableFilesAndRunsOnce verifies that the watcher waits until a new report stops
+// changing, generates once per new pair of reports into a dated folder, records each run, and
+// does not repeat the last run after a restart.
+func TestPollWaitsForStableFilesAndRunsOnce(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	outputRoot := t.TempDir()
+	now := time.Date(2026, time.March, 2, 7, 0, 0, 0, time.Local)
+	cfg := Config{
+		Dir:        dir,
+		OutputRoot: outputRoot,
+		StableFor:  10 * time.Second,
+		Options:    testGenerateOptions(t),
+		Now:        func() time.Time { return now },
+	}
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	poll := func() *RunRecord {
+		t.Helper()
+		record, err := w.Poll(context.Background())
+		if err != nil {
+			t.Fatalf("Poll returned error: %v", err)
+		}
+		return record
+	}
+
+	if record := poll(); record != nil {
+		t.Fatalf("Poll ran %+v with no reports in the folder", record)
+	}
+	writeFile(t, filepath.Join(dir, "Inventory_0302.csv"), testInventoryCSV)
+	writeFile(t, filepath.Join(dir, "~$Inventory_0302.csv"), "lock")
+	if record := poll(); record != nil {
+		t.Fatal("Poll ran before the inventory report was stable")
+	}
+	now = now.Add(11 * time.Second)
+	record := poll()
+	if record == nil {
+		t.Fatal("Poll did not run once the inventory report was stable")
+	}
+	wantDir := filepath.Join(outputRoot, "2026-03-02")
+	if record.OutputDir != wantDir || record.PO != nil || record.Error != "" ||
+		len(record.Files) != 1 || filepath.Base(record.Files[0]) != "BAS_hotsheet_20260302.xlsx" {
+		t.Fatalf("record = %+v, want one BAS hotsheet in %s", record, wantDir)
+	}
+	now = now.Add(time.Minute)
+	if record := poll(); record != nil {
+		t.Fatal("Poll ran again without a new report")
+	}
+
+	writeFile(t, filepath.Join(dir, "PO_0302.csv"), "not a PO report")
+	now = now.Add(time.Second)
+	if record := poll(); record != nil {
+		t.Fatal("Poll ran before the PO report was stable")
+	}
+	now = now.Add(11 * time.Second)
+	record = poll()
+	if record == nil || record.PO == nil || filepath.Base(record.PO.Path) != "PO_0302.csv" {
+		t.Fatalf("record = %+v, want a run paired with the PO report", record)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputRoot, DefaultRunLogName))
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Fatalf("run log has %d line(s), want 2", lines)
+	}
+
+	restarted, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	for range 2 {
+		now = now.Add(11 * time.Second)
+		if record, err := restarted.Poll(context.Background()); err != nil || record != nil {
+			t.Fatalf("restarted Poll() = %+v, %v; want the last run to be remembered", record, err)
+		}
+	}
+}
+
+// TestPollComparesWithPreviousDay verifies that the first run of a new day compares its hotsheets
+// with the last run's, which sit in the previous day's folder.
+func TestPollComparesWithPreviousDay(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	outputRoot := t.TempDir()
+	now := time.Date(2026, time.March, 2, 7, 0, 0, 0, time.Local)
+	w, err := New(Config{
+		Dir:        dir,
+		OutputRoot: outputRoot,
+		StableFor:  time.Second,
+		Options:    testGenerateOptions(t),
+		Now:        func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	for _, name := range []string{"Inventory_0302.csv", "Inventory_0303.csv"} {
+		writeFile(t, filepath.Join(dir, name), testInventoryCSV)
+		if record, err := w.Poll(context.Background()); err != nil || record != nil {
+			t.Fatalf("Poll() = %+v, %v; want no run before %s is stable", record, err, name)
+		}
+		now = now.Add(2 * time.Second)
+		record, err := w.Poll(context.Background())
+		if err != nil || record == nil || record.Error != "" {
+			t.Fatalf("Poll() = %+v, %v; want a run for %s", record, err, name)
+		}
+		now = now.Add(24 * time.Hour)
+	}
+
+	for day, wantChanges := range map[string]bool{"20260302": false, "20260303": true} {
+		folder := day[:4] + "-" + day[4:6] + "-" + day[6:]
+		f, err := excelize.OpenFile(filepath.Join(outputRoot, folder, "BAS_hotsheet_"+day+".xlsx"))
+		if err != nil {
+			t.Fatalf("OpenFile returned error: %v", err)
+		}
+		index, err := f.GetSheetIndex("Changes")
+		_ = f.Close()
+		if err != nil || (index >= 0) != wantChanges {
+			t.Fatalf("%s hotsheet Changes sheet index = %d, %v; want a Changes sheet: %v", day, index, err, wantChanges)
+		}
+	}
+}
+
+// TestPollRecordsScanErrorsAndRetries verifies that a watched folder that cannot be scanned is
+// logged and recorded once, does not stop the watcher, and is scanned again on the next Poll,
+// and that a restarted watcher still remembers the last run rather than the scan error.
+func TestPollRecordsScanErrorsAndRetries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	outputRoot := t.TempDir()
+	now := time.Date(2026, time.March, 2, 7, 0, 0, 0, time.Local)
+	cfg := Config{
+		Dir:        dir,
+		OutputRoot: outputRoot,
+		StableFor:  time.Second,
+		Options:    testGenerateOptions(t),
+		Now:        func() time.Time { return now },
+	}
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "Inventory_0302.csv"), testInventoryCSV)
+	if _, err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll returned error: %v", err)
+	}
+	now = now.Add(2 * time.Second)
+	if record, err := w.Poll(context.Background()); err != nil || record == nil || record.Error != "" {
+		t.Fatalf("Poll() = %+v, %v; want a run", record, err)
+	}
+
+	moved := dir + "-moved"
+	if err := os.Rename(dir, moved); err != nil {
+		t.Fatalf("Rename returned error: %v", err)
+	}
+	record, err := w.Poll(context.Background())
+	if err != nil || record == nil || !strings.Contains(record.Error, "failed to scan watched folder") || record.Inventory.Path != "" {
+		t.Fatalf("Poll() = %+v, %v; want a recorded scan error", record, err)
+	}
+	if record, err := w.Poll(context.Background()); err != nil || record != nil {
+		t.Fatalf("Poll() = %+v, %v; want the same scan error recorded only once", record, err)
+	}
+
+	if err := os.Rename(moved, dir); err != nil {
+		t.Fatalf("Rename returned error: %v", err)
+	}
+	restarted, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	for _, watcher := range []*Watcher{w, restarted} {
+		now = now.Add(2 * time.Second)
+		if record, err := watcher.Poll(context.Background()); err != nil || record != nil {
+			t.Fatalf("Poll() = %+v, %v; want the folder scanned again and the last run remembered", record, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputRoot, DefaultRunLogName))
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Fatalf("run log has %d line(s), want the run and one scan error", lines)
+	}
+}
+
+// TestNewRejectsBadConfig verifies the configuration checks.
+func TestNewRejectsBadConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	writeFile(t, file, "x")
+	tests := []Config{
+		{OutputRoot: dir},
+		{Dir: dir},
+		{Dir: file, OutputRoot: dir},
+		{Dir: dir, OutputRoot: dir, InventoryPattern: "["},
+		{Dir: dir, OutputRoot: dir, Interval: -time.Second},
+	}
+	for _, cfg := range tests {
+		if _, err := New(cfg); err == nil {
+			t.Fatalf("New(%+v) returned nil, want an error", cfg)
+		}
+	}
+}
+
+// testGenerateOptions gives the run a snapshot history of the test's own and discards the
+// generation log. The config files come from the test binary's config folder set up by TestMain.
+func testGenerateOptions(t *testing.T) hotsheet.GenerateOptions {
+	t.Helper()
+	return hotsheet.GenerateOptions{
+		Input:  hotsheet.InputOptions{HistoryDir: filepath.Join(t.TempDir(), "history")},
+		Logger: slog.New(slog.DiscardHandler),
+	}
+}
+
+// writeFile writes content to path, failing the test on error.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// BIFF8 record types read by this package.
+const (
+	recFormula    = 0x0006
+	recEOF        = 0x000A
+	recDateMode   = 0x0022
+	recFilePass   = 0x002F
+	recContinue   = 0x003C
+	recBoundSheet = 0x0085
+	recMulRK      = 0x00BD
+	recRString    = 0x00D6
+	recXF         = 0x00E0
+	recSST        = 0x00FC
+	recLabelSST   = 0x00FD
+	recNumber     = 0x0203
+	recLabel      = 0x0204
+	recBoolErr    = 0x0205
+	recString     = 0x0207
+	recArray      = 0x0221
+	recTable      = 0x0236
+	recRK         = 0x027E
+	recFormat     = 0x041E
+	recShrFmla    = 0x04BC
+	recBOF        = 0x0809
+)
+
+// biff8Version is the BOF version number written by Excel 97 and later.
+const biff8Version = 0x0600
+
+// record is one BIFF record. Data includes the payloads of any CONTINUE records that follow it,
+// and Segments holds the length of each piece so string readers can find the boundaries.
+type record struct {
+	Type     uint16
+	Offset   int
+	Data     []byte
+	Segments []int
+}
+
+// recordReader walks the records of a Workbook stream.
+type recordReader struct {
+	data []byte
+	pos  int
+}
+
+// next returns the record at the current position with its CONTINUE records merged in.
+func (r *recordReader) next() (record, bool, error) {
+	if r.pos+4 > len(r.data) {
+		return record{}, false, nil
+	}
+	rec := record{Offset: r.pos}
+	typ, data, err := r.raw()
+	if err != nil {
+		return record{}, false, err
+	}
+	rec.Type = typ
+	rec.Data = append(rec.Data, data...)
+	rec.Segments = append(rec.Segments, len(data))
+
+	for r.pos+4 <= len(r.data) && binary.LittleEndian.Uint16(r.data[r.pos:]) == recContinue {
+		_, data, err := r.raw()
+		if err != nil {
+			return record{}, false, err
+		}
+		rec.Data = append(rec.Data, data...)
+		rec.Segments = append(rec.Segments, len(data))
+	}
+	return rec, true, nil
+}
+
+// raw reads a single physical record.
+func (r *recordReader) raw() (uint16, []byte, error) {
+	typ := binary.LittleEndian.Uint16(r.data[r.pos:])
+	size := int(binary.LittleEndian.Uint16(r.data[r.pos+2:]))
+	start := r.pos + 4
+	if start+size > len(r.data) {
+		return 0, nil, fmt.Errorf("record %#04x at offset %d runs past the end of the stream", typ, r.pos)
+	}
+	r.pos = start + size
+	return typ, r.data[start : start+size], nil
+}
+
+// segmentedReader reads a record payload while tracking CONTINUE boundaries. BIFF8 repeats the
+// string option byte at the start of a CONTINUE record when a string's characters are split, so
+// character reads must know where each boundary falls.
+type segmentedReader struct {
+	data []byte
+	pos  int
+	ends []int
+}
+
+// newSegmentedReader wraps rec for boundary-aware reads.
+func newSegmentedReader(rec record) *segmentedReader {
+	ends := make([]int, 0, len(rec.Segments))
+	total := 0
+	for _, n := range rec.Segments {
+		total += n
+		ends = append(ends, total)
+	}
+	return &segmentedReader{data: rec.Data, ends: ends}
+}
+
+// remaining reports how many unread bytes are left.
+func (s *segmentedReader) remaining() int {
+	return len(s.data) - s.pos
+}
+
+// bytes reads n raw bytes, crossing CONTINUE boundaries transparently.
+func (s *segmentedReader) bytes(n int) ([]byte, error) {
+	if n < 0 || s.pos+n > len(s.data) {
+		return nil, errors.New("unexpected end of record")
+	}
+	b := s.data[s.pos : s.pos+n]
+	s.pos += n
+	return b, nil
+}
+
+// uint8 reads one byte.
+func (s *segmentedReader) uint8() (byte, error) {
+	b, err := s.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// uint16 reads a little-endian uint16.
+func (s *segmentedReader) uint16() (uint16, error) {
+	b, err := s.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// uint32 reads a little-endian uint32.
+func (s *segmentedReader) uint32() (uint32, error) {
+	b, err := s.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// segmentEnd returns the end offset of the segment containing the current position.
+func (s *segmentedReader) segmentEnd() int {
+	for _, end := range s.ends {
+		if s.pos < end {
+			return end
+		}
+	}
+	return len(s.data)
+}
+
+// unicodeString reads an XLUnicodeString (or a ShortXLUnicodeString when shortLen is set),
+// including the rich-text runs and phonetic data of SST strings.
+func (s *segmentedReader) unicodeString(shortLen bool) (string, error) {
+	var count int
+	if shortLen {
+		n, err := s.uint8()
+		if err != nil {
+			return "", err
+		}
+		count = int(n)
+	} else {
+		n, err := s.uint16()
+		if err != nil {
+			return "", err
+		}
+		count = int(n)
+	}
+	flags, err := s.uint8()
+	if err != nil {
+		return "", err
+	}
+
+	runs, extLen := 0, 0
+	if flags&0x08 != 0 {
+		n, err := s.uint16()
+		if err != nil {
+			return "", err
+		}
+		runs = int(n)
+	}
+	if flags&0x04 != 0 {
+		n, err := s.uint32()
+		if err != nil {
+			return "", err
+		}
+		extLen = int(n)
+	}
+
+	units := make([]uint16, 0, count)
+	wide := flags&0x01 != 0
+	for len(units) < count {
+		if s.pos >= len(s.data) {
+			return "", errors.New("unexpected end of string")
+		}
+		end := s.segmentEnd()
+		for s.pos < end && len(units) < count {
+			if wide {
+				if s.pos+2 > end {
+					return "", errors.New("string character split across records")
+				}
+				units = append(units, binary.LittleEndian.Uint16(s.data[s.pos:]))
+				s.pos += 2
+			} else {
+				units = append(units, uint16(s.data[s.pos]))
+				s.pos++
+			}
+		}
+		if len(units) < count {
+			// The characters continue in the next CONTINUE record, which starts with a fresh
+			// option byte saying whether the rest is compressed.
+			next, err := s.uint8()
+			if err != nil {
+				return "", err
+			}
+			wide = next&0x01 != 0
+		}
+	}
+
+	if _, err := s.bytes(runs*4 + extLen); err != nil {
+		return "", err
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// decodeRK converts an RK number to a float64.
+func decodeRK(rk uint32) float64 {
+	var v float64
+	if rk&0x02 != 0 {
+		v = float64(int32(rk) >> 2)
+	} else {
+		v = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		v /= 100
+	}
+	return v
+}
+
+// boolErrText renders a BOOLERR value the way Excel displays it.
+func boolErrText(value, isError byte) string {
+	if isError == 0 {
+		if value != 0 {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	switch value {
+	case 0x00:
+		return "#NULL!"
+	case 0x07:
+		return "#DIV/0!"
+	case 0x0F:
+		return "#VALUE!"
+	case 0x17:
+		return "#REF!"
+	case 0x1D:
+		return "#NAME?"
+	case 0x24:
+		return "#NUM!"
+	case 0x2A:
+		return "#N/A"
+	default:
+		return "#ERROR!"
+	}
+}
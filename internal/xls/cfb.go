@@ -0,0 +1,275 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// compoundFileSignature is the magic number at the start of every OLE Compound File, the
+// container format used by legacy .xls workbooks.
+var compoundFileSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// Special sector numbers used in the FAT and directory.
+const (
+	maxRegularSector = 0xFFFFFFFA
+	endOfChain       = 0xFFFFFFFE
+)
+
+// Directory entry object types.
+const (
+	objectStream = 2
+	objectRoot   = 5
+)
+
+const (
+	cfbHeaderSize      = 512
+	cfbDirEntrySize    = 128
+	cfbHeaderDIFATSize = 109
+)
+
+// compoundFile is a parsed OLE Compound File held in memory.
+type compoundFile struct {
+	data            []byte
+	sectorSize      int
+	miniSectorSize  int
+	miniStreamLimit uint64
+	fat             []uint32
+	miniFAT         []uint32
+	entries         []dirEntry
+	miniStream      []byte
+}
+
+// dirEntry is one directory entry of a compound file.
+type dirEntry struct {
+	Name        string
+	Type        byte
+	StartSector uint32
+	Size        uint64
+}
+
+// isCompoundFile reports whether data starts with the compound file signature.
+func isCompoundFile(data []byte) bool {
+	return len(data) >= len(compoundFileSignature) && bytes.Equal(data[:len(compoundFileSignature)], compoundFileSignature)
+}
+
+// parseCompoundFile reads the header, FAT, mini FAT, and directory of a compound file.
+func parseCompoundFile(data []byte) (*compoundFile, error) {
+	if len(data) < cfbHeaderSize || !isCompoundFile(data) {
+		return nil, errors.New("not an OLE compound file")
+	}
+
+	sectorShift := binary.LittleEndian.Uint16(data[0x1E:])
+	miniSectorShift := binary.LittleEndian.Uint16(data[0x20:])
+	if sectorShift != 9 && sectorShift != 12 {
+		return nil, fmt.Errorf("unsupported compound file sector size 2^%d", sectorShift)
+	}
+	if miniSectorShift == 0 || miniSectorShift >= sectorShift {
+		return nil, fmt.Errorf("invalid compound file mini sector size 2^%d", miniSectorShift)
+	}
+
+	cf := &compoundFile{
+		data:            data,
+		sectorSize:      1 << sectorShift,
+		miniSectorSize:  1 << miniSectorShift,
+		miniStreamLimit: uint64(binary.LittleEndian.Uint32(data[0x38:])),
+	}
+
+	if err := cf.loadFAT(); err != nil {
+		return nil, err
+	}
+
+	dirData, err := cf.readChain(binary.LittleEndian.Uint32(data[0x30:]), cf.fat, cf.sector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	cf.entries = parseDirEntries(dirData, binary.LittleEndian.Uint16(data[0x1A:]) >= 4)
+	if len(cf.entries) == 0 || cf.entries[0].Type != objectRoot {
+		return nil, errors.New("compound file has no root directory entry")
+	}
+
+	if firstMiniFAT := binary.LittleEndian.Uint32(data[0x3C:]); firstMiniFAT <= maxRegularSector {
+		miniFATData, err := cf.readChain(firstMiniFAT, cf.fat, cf.sector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mini FAT: %w", err)
+		}
+		cf.miniFAT = bytesToUint32s(miniFATData)
+	}
+
+	root := cf.entries[0]
+	if root.StartSector <= maxRegularSector {
+		miniStream, err := cf.readChain(root.StartSector, cf.fat, cf.sector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mini stream: %w", err)
+		}
+		if uint64(len(miniStream)) > root.Size {
+			miniStream = miniStream[:root.Size]
+		}
+		cf.miniStream = miniStream
+	}
+
+	return cf, nil
+}
+
+// loadFAT collects the FAT sector list from the header and DIFAT chain and reads the FAT.
+func (cf *compoundFile) loadFAT() error {
+	// The header count is untrusted; a file cannot hold more FAT sectors than it has sectors.
+	numFATSectors := min(int(binary.LittleEndian.Uint32(cf.data[0x2C:])), len(cf.data)/cf.sectorSize)
+	fatSectors := make([]uint32, 0, numFATSectors)
+	for i := 0; i < cfbHeaderDIFATSize && len(fatSectors) < numFATSectors; i++ {
+		fatSectors = append(fatSectors, binary.LittleEndian.Uint32(cf.data[0x4C+i*4:]))
+	}
+
+	next := binary.LittleEndian.Uint32(cf.data[0x44:])
+	perDIFAT := cf.sectorSize/4 - 1
+	for visited := 0; next <= maxRegularSector && len(fatSectors) < numFATSectors; visited++ {
+		if visited > cf.sectorCount() {
+			return errors.New("compound file DIFAT chain loops")
+		}
+		sector, err := cf.sector(next)
+		if err != nil {
+			return fmt.Errorf("failed to read DIFAT: %w", err)
+		}
+		for i := 0; i < perDIFAT && len(fatSectors) < numFATSectors; i++ {
+			fatSectors = append(fatSectors, binary.LittleEndian.Uint32(sector[i*4:]))
+		}
+		next = binary.LittleEndian.Uint32(sector[perDIFAT*4:])
+	}
+
+	fat := make([]uint32, 0, len(fatSectors)*cf.sectorSize/4)
+	for _, sectorID := range fatSectors {
+		sector, err := cf.sector(sectorID)
+		if err != nil {
+			return fmt.Errorf("failed to read FAT: %w", err)
+		}
+		fat = append(fat, bytesToUint32s(sector)...)
+	}
+	cf.fat = fat
+	return nil
+}
+
+// sectorCount returns how many full sectors follow the header.
+func (cf *compoundFile) sectorCount() int {
+	return (len(cf.data) - cfbHeaderSize) / cf.sectorSize
+}
+
+// sector returns the bytes of a regular sector.
+func (cf *compoundFile) sector(id uint32) ([]byte, error) {
+	if id > maxRegularSector {
+		return nil, fmt.Errorf("invalid sector %#x", id)
+	}
+	start := (int64(id) + 1) * int64(cf.sectorSize)
+	end := start + int64(cf.sectorSize)
+	if end > int64(len(cf.data)) {
+		// The last sector of a file may be truncated; pad it so chain reads stay simple.
+		if start >= int64(len(cf.data)) {
+			return nil, fmt.Errorf("sector %d is past the end of the file", id)
+		}
+		padded := make([]byte, cf.sectorSize)
+		copy(padded, cf.data[start:])
+		return padded, nil
+	}
+	return cf.data[start:end], nil
+}
+
+// miniSector returns the bytes of a mini stream sector.
+func (cf *compoundFile) miniSector(id uint32) ([]byte, error) {
+	start := int(id) * cf.miniSectorSize
+	end := start + cf.miniSectorSize
+	if id > maxRegularSector || end > len(cf.miniStream) {
+		return nil, fmt.Errorf("invalid mini sector %d", id)
+	}
+	return cf.miniStream[start:end], nil
+}
+
+// readChain concatenates the sectors of the chain starting at start. A chain that comes back to
+// a sector it already read is rejected as soon as it does.
+func (cf *compoundFile) readChain(start uint32, table []uint32, read func(uint32) ([]byte, error)) ([]byte, error) {
+	var out []byte
+	visited := make([]bool, len(table))
+	for id := start; id != endOfChain; {
+		if id > maxRegularSector || int(id) >= len(table) {
+			return nil, fmt.Errorf("broken sector chain at %#x", id)
+		}
+		if visited[id] {
+			return nil, fmt.Errorf("sector chain loops at %#x", id)
+		}
+		visited[id] = true
+		sector, err := read(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sector...)
+		id = table[id]
+	}
+	return out, nil
+}
+
+// stream returns the contents of the stream named name, ignoring case.
+func (cf *compoundFile) stream(name string) ([]byte, bool, error) {
+	for _, entry := range cf.entries {
+		if entry.Type != objectStream || !strings.EqualFold(entry.Name, name) {
+			continue
+		}
+		var data []byte
+		var err error
+		if entry.Size < cf.miniStreamLimit {
+			data, err = cf.readChain(entry.StartSector, cf.miniFAT, cf.miniSector)
+		} else {
+			data, err = cf.readChain(entry.StartSector, cf.fat, cf.sector)
+		}
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to read %s stream: %w", name, err)
+		}
+		if uint64(len(data)) < entry.Size {
+			return nil, true, fmt.Errorf("%s stream is truncated", name)
+		}
+		return data[:entry.Size], true, nil
+	}
+	return nil, false, nil
+}
+
+// parseDirEntries decodes the directory stream. Unused entries are kept so indexes match the
+// on-disk entry numbers. wideSizes is set for version 4 files, which use 64-bit stream sizes.
+func parseDirEntries(data []byte, wideSizes bool) []dirEntry {
+	entries := make([]dirEntry, 0, len(data)/cfbDirEntrySize)
+	for off := 0; off+cfbDirEntrySize <= len(data); off += cfbDirEntrySize {
+		raw := data[off : off+cfbDirEntrySize]
+		nameLen := int(binary.LittleEndian.Uint16(raw[0x40:]))
+		if nameLen > 64 {
+			nameLen = 64
+		}
+		units := make([]uint16, 0, nameLen/2)
+		for i := 0; i+1 < nameLen; i += 2 {
+			u := binary.LittleEndian.Uint16(raw[i:])
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		entry := dirEntry{
+			Name:        string(utf16.Decode(units)),
+			Type:        raw[0x42],
+			StartSector: binary.LittleEndian.Uint32(raw[0x74:]),
+			// Version 3 files only define the low 32 bits of the size.
+			Size: uint64(binary.LittleEndian.Uint32(raw[0x78:])),
+		}
+		if wideSizes {
+			entry.Size = binary.LittleEndian.Uint64(raw[0x78:])
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// bytesToUint32s decodes little-endian uint32 values.
+func bytesToUint32s(data []byte) []uint32 {
+	out := make([]uint32, len(data)/4)
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return out
+}
@@ -0,0 +1,431 @@
+// Package xls reads cell text from legacy Excel 97-2003 (.xls) workbooks.
+//
+// The reader understands the OLE Compound File container and the BIFF8 records that hold cell
+// values. It renders each cell as the text a report parser needs: strings as-is, numbers in
+// plain decimal form, booleans and errors the way Excel displays them, and date-formatted
+// numbers as MM/DD/YYYY dates. Formatting, formulas, and charts are otherwise ignored.
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sheetTypeWorksheet is the BOUNDSHEET sheet type of a regular worksheet.
+const sheetTypeWorksheet = 0
+
+// Workbook is a legacy workbook loaded into memory.
+type Workbook struct {
+	stream    []byte
+	sheets    []sheetInfo
+	sst       []string
+	xfFormats []uint16
+	formats   map[uint16]string
+	date1904  bool
+}
+
+// sheetInfo locates one worksheet substream.
+type sheetInfo struct {
+	name   string
+	offset int
+}
+
+// IsLegacyFile reports whether the file at path is an OLE Compound File, the container used by
+// .xls workbooks, regardless of its extension.
+func IsLegacyFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	header := make([]byte, len(compoundFileSignature))
+	if _, err := io.ReadFull(file, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return isCompoundFile(header), nil
+}
+
+// Open reads the workbook at path.
+func Open(path string) (*Workbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBytes(data)
+}
+
+// OpenBytes reads a workbook from the bytes of an .xls file.
+func OpenBytes(data []byte) (*Workbook, error) {
+	cf, err := parseCompoundFile(data)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok, err := cf.stream("Workbook")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if _, isBIFF5, _ := cf.stream("Book"); isBIFF5 {
+			return nil, errors.New("Excel 5.0/95 workbooks are not supported; save the file as Excel 97-2003 or .xlsx")
+		}
+		return nil, errors.New("file does not contain an Excel workbook")
+	}
+
+	wb := &Workbook{stream: stream, formats: make(map[uint16]string)}
+	if err := wb.readGlobals(); err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// SheetNames returns the worksheet names in workbook order. Chart and macro sheets are omitted.
+func (wb *Workbook) SheetNames() []string {
+	names := make([]string, 0, len(wb.sheets))
+	for _, sheet := range wb.sheets {
+		names = append(names, sheet.name)
+	}
+	return names
+}
+
+// readGlobals reads the workbook globals substream: sheet locations, shared strings, number
+// formats, cell formats, and the date system.
+func (wb *Workbook) readGlobals() error {
+	rr := &recordReader{data: wb.stream}
+	first := true
+	for {
+		rec, ok, err := rr.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("workbook globals are missing their EOF record")
+		}
+		if first {
+			if rec.Type != recBOF || len(rec.Data) < 2 {
+				return errors.New("workbook stream does not start with a BOF record")
+			}
+			if version := binary.LittleEndian.Uint16(rec.Data); version != biff8Version {
+				return fmt.Errorf("unsupported BIFF version %#04x; only Excel 97-2003 workbooks are supported", version)
+			}
+			first = false
+			continue
+		}
+
+		switch rec.Type {
+		case recEOF:
+			return nil
+		case recFilePass:
+			return errors.New("workbook is password protected")
+		case recDateMode:
+			wb.date1904 = len(rec.Data) >= 2 && binary.LittleEndian.Uint16(rec.Data) == 1
+		case recBoundSheet:
+			if err := wb.readBoundSheet(rec); err != nil {
+				return err
+			}
+		case recSST:
+			if err := wb.readSST(rec); err != nil {
+				return err
+			}
+		case recFormat:
+			sr := newSegmentedReader(rec)
+			id, err := sr.uint16()
+			if err != nil {
+				return fmt.Errorf("invalid FORMAT record: %w", err)
+			}
+			code, err := sr.unicodeString(false)
+			if err != nil {
+				return fmt.Errorf("invalid FORMAT record: %w", err)
+			}
+			wb.formats[id] = code
+		case recXF:
+			if len(rec.Data) < 4 {
+				return errors.New("invalid XF record")
+			}
+			wb.xfFormats = append(wb.xfFormats, binary.LittleEndian.Uint16(rec.Data[2:]))
+		}
+	}
+}
+
+// readBoundSheet records the name and stream offset of one sheet.
+func (wb *Workbook) readBoundSheet(rec record) error {
+	if len(rec.Data) < 8 {
+		return errors.New("invalid BOUNDSHEET record")
+	}
+	offset := int(binary.LittleEndian.Uint32(rec.Data))
+	sheetType := rec.Data[5]
+	sr := newSegmentedReader(rec)
+	if _, err := sr.bytes(6); err != nil {
+		return err
+	}
+	name, err := sr.unicodeString(true)
+	if err != nil {
+		return fmt.Errorf("invalid BOUNDSHEET record: %w", err)
+	}
+	if sheetType == sheetTypeWorksheet {
+		wb.sheets = append(wb.sheets, sheetInfo{name: name, offset: offset})
+	}
+	return nil
+}
+
+// readSST reads the shared string table, including strings split across CONTINUE records.
+func (wb *Workbook) readSST(rec record) error {
+	sr := newSegmentedReader(rec)
+	if _, err := sr.uint32(); err != nil { // total string references
+		return fmt.Errorf("invalid SST record: %w", err)
+	}
+	unique, err := sr.uint32()
+	if err != nil {
+		return fmt.Errorf("invalid SST record: %w", err)
+	}
+	// Every string takes at least a two-byte length and a flag byte, so the record bounds the
+	// count no matter what the header claims.
+	wb.sst = make([]string, 0, min(int(unique), sr.remaining()/3))
+	for i := uint32(0); i < unique && sr.remaining() > 0; i++ {
+		s, err := sr.unicodeString(false)
+		if err != nil {
+			return fmt.Errorf("invalid SST string %d: %w", i, err)
+		}
+		wb.sst = append(wb.sst, s)
+	}
+	return nil
+}
+
+// Rows returns the cell text of the named worksheet as a dense grid, matching the shape of
+// excelize's GetRows: one slice per row up to the last row with a value, each trimmed after
+// its last non-empty cell. The name is matched exactly.
+func (wb *Workbook) Rows(sheet string) ([][]string, error) {
+	var info *sheetInfo
+	for i := range wb.sheets {
+		if wb.sheets[i].name == sheet {
+			info = &wb.sheets[i]
+			break
+		}
+	}
+	if info == nil {
+		return nil, fmt.Errorf("sheet %q does not exist", sheet)
+	}
+	if info.offset < 0 || info.offset >= len(wb.stream) {
+		return nil, fmt.Errorf("sheet %q has an invalid stream offset", sheet)
+	}
+
+	grid := &cellGrid{}
+	rr := &recordReader{data: wb.stream, pos: info.offset}
+	// pendingFormula holds the cell of a FORMULA record whose string result follows in a STRING
+	// record.
+	var pendingFormula *[2]int
+	for first := true; ; first = false {
+		rec, ok, err := rr.next()
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet, err)
+		}
+		if !ok || (rec.Type == recEOF && !first) {
+			break
+		}
+		if first {
+			if rec.Type != recBOF {
+				return nil, fmt.Errorf("sheet %q does not start with a BOF record", sheet)
+			}
+			continue
+		}
+		switch rec.Type {
+		case recString, recShrFmla, recArray, recTable:
+			// Shared and array formula definitions sit between a FORMULA record and its
+			// STRING result.
+		default:
+			pendingFormula = nil
+		}
+		if err := wb.readCell(rec, grid, &pendingFormula); err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet, err)
+		}
+	}
+	return grid.rows(), nil
+}
+
+// readCell applies one sheet record to grid.
+func (wb *Workbook) readCell(rec record, grid *cellGrid, pendingFormula **[2]int) error {
+	data := rec.Data
+	cellPos := func() (int, int, uint16, error) {
+		if len(data) < 6 {
+			return 0, 0, 0, fmt.Errorf("record %#04x is too short", rec.Type)
+		}
+		return int(binary.LittleEndian.Uint16(data)), int(binary.LittleEndian.Uint16(data[2:])), binary.LittleEndian.Uint16(data[4:]), nil
+	}
+
+	switch rec.Type {
+	case recLabelSST:
+		row, col, _, err := cellPos()
+		if err != nil || len(data) < 10 {
+			return errors.New("invalid LABELSST record")
+		}
+		idx := int(binary.LittleEndian.Uint32(data[6:]))
+		if idx < len(wb.sst) {
+			grid.set(row, col, wb.sst[idx])
+		}
+	case recLabel, recRString:
+		row, col, _, err := cellPos()
+		if err != nil {
+			return err
+		}
+		sr := newSegmentedReader(rec)
+		_, _ = sr.bytes(6)
+		text, err := sr.unicodeString(false)
+		if err != nil {
+			return fmt.Errorf("invalid LABEL record: %w", err)
+		}
+		grid.set(row, col, text)
+	case recNumber:
+		row, col, xf, err := cellPos()
+		if err != nil || len(data) < 14 {
+			return errors.New("invalid NUMBER record")
+		}
+		grid.set(row, col, wb.formatNumber(math.Float64frombits(binary.LittleEndian.Uint64(data[6:])), xf))
+	case recRK:
+		row, col, xf, err := cellPos()
+		if err != nil || len(data) < 10 {
+			return errors.New("invalid RK record")
+		}
+		grid.set(row, col, wb.formatNumber(decodeRK(binary.LittleEndian.Uint32(data[6:])), xf))
+	case recMulRK:
+		if len(data) < 6 {
+			return errors.New("invalid MULRK record")
+		}
+		row := int(binary.LittleEndian.Uint16(data))
+		col := int(binary.LittleEndian.Uint16(data[2:]))
+		for off := 4; off+6 <= len(data)-2; off += 6 {
+			xf := binary.LittleEndian.Uint16(data[off:])
+			grid.set(row, col, wb.formatNumber(decodeRK(binary.LittleEndian.Uint32(data[off+2:])), xf))
+			col++
+		}
+	case recBoolErr:
+		row, col, _, err := cellPos()
+		if err != nil || len(data) < 8 {
+			return errors.New("invalid BOOLERR record")
+		}
+		grid.set(row, col, boolErrText(data[6], data[7]))
+	case recFormula:
+		row, col, xf, err := cellPos()
+		if err != nil || len(data) < 14 {
+			return errors.New("invalid FORMULA record")
+		}
+		result := data[6:14]
+		if binary.LittleEndian.Uint16(result[6:]) != 0xFFFF {
+			grid.set(row, col, wb.formatNumber(math.Float64frombits(binary.LittleEndian.Uint64(result)), xf))
+			return nil
+		}
+		switch result[0] {
+		case 0: // string result in the following STRING record
+			*pendingFormula = &[2]int{row, col}
+		case 1:
+			grid.set(row, col, boolErrText(result[2], 0))
+		case 2:
+			grid.set(row, col, boolErrText(result[2], 1))
+		}
+	case recString:
+		if *pendingFormula == nil {
+			return nil
+		}
+		sr := newSegmentedReader(rec)
+		text, err := sr.unicodeString(false)
+		if err != nil {
+			return fmt.Errorf("invalid STRING record: %w", err)
+		}
+		grid.set((*pendingFormula)[0], (*pendingFormula)[1], text)
+		*pendingFormula = nil
+	}
+	return nil
+}
+
+// formatNumber renders a numeric cell using the date/number distinction of its XF.
+func (wb *Workbook) formatNumber(v float64, xf uint16) string {
+	if int(xf) < len(wb.xfFormats) {
+		formatID := wb.xfFormats[xf]
+		if isDateFormat(formatID, wb.formats[formatID]) {
+			return formatSerialDate(v, wb.date1904)
+		}
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// isDateFormat reports whether a number format displays dates or times. Built-in formats are
+// recognized by id; custom formats by their date and time tokens outside quoted text.
+func isDateFormat(id uint16, code string) bool {
+	switch {
+	case id >= 14 && id <= 22, id >= 45 && id <= 47:
+		return true
+	case code == "":
+		return false
+	}
+
+	inQuote, inBracket := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++ // the next character is literal or padding
+		case strings.ContainsRune("dmyhsDMYHS", rune(c)):
+			return true
+		}
+	}
+	return false
+}
+
+// formatSerialDate converts an Excel serial date to MM/DD/YYYY text, adding the time of day
+// when the serial has a fractional part.
+func formatSerialDate(serial float64, date1904 bool) string {
+	base := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := base.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+	if seconds == 0 {
+		return t.Format("01/02/2006")
+	}
+	return t.Format("01/02/2006 15:04")
+}
+
+// cellGrid accumulates sparse cell values into rows.
+type cellGrid struct {
+	cells [][]string
+}
+
+// set stores text at the zero-based row and column. Empty text is ignored.
+func (g *cellGrid) set(row, col int, text string) {
+	if text == "" {
+		return
+	}
+	for len(g.cells) <= row {
+		g.cells = append(g.cells, nil)
+	}
+	for len(g.cells[row]) <= col {
+		g.cells[row] = append(g.cells[row], "")
+	}
+	g.cells[row][col] = text
+}
+
+// rows returns the accumulated grid.
+func (g *cellGrid) rows() [][]string {
+	return g.cells
+}
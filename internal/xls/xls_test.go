@@ -0,0 +1,276 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// biffRecord encodes one BIFF record.
+func biffRecord(typ uint16, data ...[]byte) []byte {
+	payload := bytes.Join(data, nil)
+	out := make([]byte, 4, 4+len(payload))
+	binary.LittleEndian.PutUint16(out, typ)
+	binary.LittleEndian.PutUint16(out[2:], uint16(len(payload)))
+	return append(out, payload...)
+}
+
+// le16 and le32 encode little-endian integers for record payloads.
+func le16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+
+// cellHeader encodes the row, column, and XF index that start every cell record.
+func cellHeader(row, col, xf uint16) []byte {
+	return bytes.Join([][]byte{le16(row), le16(col), le16(xf)}, nil)
+}
+
+// compressedString encodes an XLUnicodeString with 8-bit characters.
+func compressedString(s string) []byte {
+	return append(append(le16(uint16(len(s))), 0), s...)
+}
+
+// wideChars encodes s as UTF-16LE.
+func wideChars(s string) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+	return out
+}
+
+// buildTestWorkbookStream builds a BIFF8 Workbook stream with one worksheet named "Report"
+// covering each cell record the reader supports. The third shared string is split across a
+// CONTINUE record and switches from 8-bit to UTF-16 characters at the split.
+func buildTestWorkbookStream() []byte {
+	bof := func(dt uint16) []byte {
+		return biffRecord(recBOF, le16(biff8Version), le16(dt), make([]byte, 12))
+	}
+	xf := func(format uint16) []byte {
+		return biffRecord(recXF, le16(0), le16(format), make([]byte, 16))
+	}
+
+	sst := biffRecord(recSST, le32(3), le32(3), compressedString("Item Code"), compressedString("ABC123"),
+		le16(9), []byte{0}, []byte("Caf"))
+	sstContinue := biffRecord(recContinue, []byte{1}, wideChars("é Noël"))
+
+	globalsBeforeSheet := bytes.Join([][]byte{
+		bof(0x0005),
+		biffRecord(recFormat, le16(164), compressedString(`"Due "mm/dd/yy`)),
+		xf(0),   // 0: General
+		xf(14),  // 1: built-in short date
+		xf(164), // 2: custom date format
+		xf(4),   // 3: #,##0.00
+		sst, sstContinue,
+	}, nil)
+	sheetName := append([]byte{byte(len("Report")), 0}, "Report"...)
+	boundSheetLen := 4 + 6 + len(sheetName)
+	eof := biffRecord(recEOF)
+	sheetOffset := len(globalsBeforeSheet) + boundSheetLen + len(eof)
+	boundSheet := biffRecord(recBoundSheet, le32(uint32(sheetOffset)), []byte{0, 0}, sheetName)
+
+	rk := func(v int32) []byte { return le32(uint32(v<<2) | 0x02) }
+	number := func(v float64) []byte { return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)) }
+
+	sheet := bytes.Join([][]byte{
+		bof(0x0010),
+		biffRecord(recLabelSST, cellHeader(0, 0, 0), le32(0)),
+		biffRecord(recLabel, cellHeader(0, 2, 0), compressedString("Qty On Hand")),
+		biffRecord(recLabelSST, cellHeader(1, 0, 0), le32(1)),
+		biffRecord(recLabelSST, cellHeader(1, 1, 0), le32(2)),
+		biffRecord(recRK, cellHeader(1, 2, 0), rk(-25)),
+		// 123.45 is stored as the integer 12345 with the divide-by-100 flag.
+		biffRecord(recMulRK, le16(2), le16(1), le16(0), rk(7), le16(0), le32(uint32(12345<<2)|0x03), le16(2)),
+		biffRecord(recNumber, cellHeader(3, 0, 1), number(46157)),
+		biffRecord(recNumber, cellHeader(3, 1, 2), number(46157.5)),
+		biffRecord(recNumber, cellHeader(3, 2, 3), number(1234.5)),
+		biffRecord(recFormula, cellHeader(4, 0, 0), []byte{0, 0, 0, 0, 0, 0, 0xFF, 0xFF}, make([]byte, 6)),
+		biffRecord(recString, compressedString("formula text")),
+		biffRecord(recBoolErr, cellHeader(4, 1, 0), []byte{1, 0}),
+		biffRecord(recBoolErr, cellHeader(4, 2, 0), []byte{0x2A, 1}),
+		eof,
+	}, nil)
+
+	return bytes.Join([][]byte{globalsBeforeSheet, boundSheet, eof, sheet}, nil)
+}
+
+// buildTestCompoundFile wraps stream as the Workbook stream of a version 3 compound file with
+// 512-byte sectors. When mini is set the stream is stored in the mini stream, as small files
+// written by some exporters are.
+func buildTestCompoundFile(stream []byte, mini bool) []byte {
+	const sectorSize = 512
+	const miniSize = 64
+	const endChain, freeSect, fatSect = 0xFFFFFFFE, 0xFFFFFFFF, 0xFFFFFFFD
+
+	var sectors [][]byte
+	fat := []uint32{fatSect, endChain} // sector 0 holds the FAT, sector 1 the directory
+	sectors = append(sectors, nil, nil)
+	addChain := func(data []byte) uint32 {
+		first := uint32(len(sectors))
+		for off := 0; off < len(data); off += sectorSize {
+			chunk := make([]byte, sectorSize)
+			copy(chunk, data[off:])
+			sectors = append(sectors, chunk)
+			fat = append(fat, uint32(len(sectors)))
+		}
+		fat[len(fat)-1] = endChain
+		return first
+	}
+
+	rootStart, rootSize := uint32(endChain), uint32(0)
+	miniFATStart, miniFATCount := uint32(endChain), uint32(0)
+	var streamStart uint32
+	if mini {
+		var miniFAT []byte
+		count := (len(stream) + miniSize - 1) / miniSize
+		for i := 0; i < count; i++ {
+			next := uint32(i + 1)
+			if i == count-1 {
+				next = endChain
+			}
+			miniFAT = binary.LittleEndian.AppendUint32(miniFAT, next)
+		}
+		for len(miniFAT)%sectorSize != 0 {
+			miniFAT = binary.LittleEndian.AppendUint32(miniFAT, freeSect)
+		}
+		miniFATStart, miniFATCount = addChain(miniFAT), 1
+		padded := make([]byte, count*miniSize)
+		copy(padded, stream)
+		rootStart, rootSize = addChain(padded), uint32(len(padded))
+		streamStart = 0
+	} else {
+		padded := stream
+		if len(padded) < 4096 {
+			padded = append(append([]byte(nil), stream...), make([]byte, 4096-len(stream))...)
+		}
+		stream = padded
+		streamStart = addChain(stream)
+	}
+
+	dirEntry := func(name string, typ byte, start, size uint32) []byte {
+		entry := make([]byte, 128)
+		units := utf16.Encode([]rune(name))
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(entry[i*2:], u)
+		}
+		binary.LittleEndian.PutUint16(entry[0x40:], uint16((len(units)+1)*2))
+		entry[0x42] = typ
+		binary.LittleEndian.PutUint32(entry[0x44:], freeSect)
+		binary.LittleEndian.PutUint32(entry[0x48:], freeSect)
+		binary.LittleEndian.PutUint32(entry[0x4C:], freeSect)
+		binary.LittleEndian.PutUint32(entry[0x74:], start)
+		binary.LittleEndian.PutUint32(entry[0x78:], size)
+		return entry
+	}
+	dir := bytes.Join([][]byte{
+		dirEntry("Root Entry", objectRoot, rootStart, rootSize),
+		dirEntry("Workbook", objectStream, streamStart, uint32(len(stream))),
+		make([]byte, 256),
+	}, nil)
+	sectors[1] = dir
+
+	fatSector := make([]byte, sectorSize)
+	for i := range sectorSize / 4 {
+		v := uint32(freeSect)
+		if i < len(fat) {
+			v = fat[i]
+		}
+		binary.LittleEndian.PutUint32(fatSector[i*4:], v)
+	}
+	sectors[0] = fatSector
+
+	header := make([]byte, sectorSize)
+	copy(header, compoundFileSignature)
+	binary.LittleEndian.PutUint16(header[0x18:], 0x3E)
+	binary.LittleEndian.PutUint16(header[0x1A:], 3)
+	binary.LittleEndian.PutUint16(header[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[0x1E:], 9)
+	binary.LittleEndian.PutUint16(header[0x20:], 6)
+	binary.LittleEndian.PutUint32(header[0x2C:], 1)
+	binary.LittleEndian.PutUint32(header[0x30:], 1)
+	binary.LittleEndian.PutUint32(header[0x38:], 4096)
+	binary.LittleEndian.PutUint32(header[0x3C:], miniFATStart)
+	binary.LittleEndian.PutUint32(header[0x40:], miniFATCount)
+	binary.LittleEndian.PutUint32(header[0x44:], endChain)
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(header[0x4C+i*4:], freeSect)
+	}
+	binary.LittleEndian.PutUint32(header[0x4C:], 0)
+
+	return bytes.Join(append([][]byte{header}, sectors...), nil)
+}
+
+// TestRowsReadsBIFF8Cells verifies each supported cell record, shared strings split across a
+// CONTINUE record, date formats, and both regular and mini stream storage.
+func TestRowsReadsBIFF8Cells(t *testing.T) {
+	t.Parallel()
+
+	want := [][]string{
+		{"Item Code", "", "Qty On Hand"},
+		{"ABC123", "Café Noël", "-25"},
+		{"", "7", "123.45"},
+		{"05/15/2026", "05/15/2026 12:00", "1234.5"},
+		{"formula text", "TRUE", "#N/A"},
+	}
+
+	for _, mini := range []bool{false, true} {
+		wb, err := OpenBytes(buildTestCompoundFile(buildTestWorkbookStream(), mini))
+		if err != nil {
+			t.Fatalf("OpenBytes(mini=%v) returned error: %v", mini, err)
+		}
+		if names := wb.SheetNames(); !reflect.DeepEqual(names, []string{"Report"}) {
+			t.Fatalf("unexpected sheet names %v", names)
+		}
+		rows, err := wb.Rows("Report")
+		if err != nil {
+			t.Fatalf("Rows(mini=%v) returned error: %v", mini, err)
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Fatalf("Rows(mini=%v) = %q, want %q", mini, rows, want)
+		}
+	}
+}
+
+// TestOpenBytesIgnoresOversizedCounts verifies that FAT sector and shared string counts far
+// larger than the file are bounded by the data instead of being used to size allocations.
+func TestOpenBytesIgnoresOversizedCounts(t *testing.T) {
+	t.Parallel()
+
+	stream := buildTestWorkbookStream()
+	sstCounts := bytes.Join([][]byte{le32(3), le32(3), compressedString("Item Code")}, nil)
+	at := bytes.Index(stream, sstCounts)
+	if at < 0 {
+		t.Fatal("test stream has no SST record")
+	}
+	binary.LittleEndian.PutUint32(stream[at+4:], math.MaxUint32)
+	wb, err := OpenBytes(buildTestCompoundFile(stream, false))
+	if err != nil {
+		t.Fatalf("OpenBytes with an oversized SST count returned error: %v", err)
+	}
+	if rows, err := wb.Rows("Report"); err != nil || len(rows) < 2 || rows[1][1] != "Café Noël" {
+		t.Fatalf("Rows with an oversized SST count = %q, %v; want the three shared strings", rows, err)
+	}
+
+	data := buildTestCompoundFile(buildTestWorkbookStream(), false)
+	binary.LittleEndian.PutUint32(data[0x2C:], math.MaxUint32)
+	if _, err := OpenBytes(data); err == nil {
+		t.Fatal("OpenBytes with an oversized FAT sector count returned no error")
+	}
+}
+
+// TestOpenBytesRejectsLoopingChain verifies that a stream whose sector chain points back to its
+// first sector is reported as a loop instead of being read again and again.
+func TestOpenBytesRejectsLoopingChain(t *testing.T) {
+	t.Parallel()
+
+	data := buildTestCompoundFile(buildTestWorkbookStream(), false)
+	// The FAT is sector 0, right after the header; the Workbook stream fills sectors 2 through 9.
+	binary.LittleEndian.PutUint32(data[cfbHeaderSize+9*4:], 2)
+	_, err := OpenBytes(data)
+	if err == nil || !strings.Contains(err.Error(), "sector chain loops") {
+		t.Fatalf("OpenBytes with a looping chain = %v, want a sector chain loop error", err)
+	}
+}
@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+
 	helpers "github.com/Fepozopo/bsc-hotsheet-update/helpers"
+	"github.com/Fepozopo/bsc-hotsheet-update/internal/cli"
 	"github.com/Fepozopo/bsc-hotsheet-update/internal/gui"
 )
 
-// main wires up the application logger and launches the GUI flow.
+// main runs the command-line mode when a command is given and otherwise wires up the application
+// logger and launches the GUI flow.
 func main() {
+	if len(os.Args) > 1 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		code := cli.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+
 	logger, logCloser, err := helpers.CreateSlogLogger("main", "DEBUG")
 	if err != nil {
 		// If we cannot create the logger, we cannot proceed reliably.